@@ -1,15 +1,33 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"syscall"
 
+	"github.com/mikerourke/forensic-files-api/internal/aboutface"
+	"github.com/mikerourke/forensic-files-api/internal/breakingnews"
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/handdelivered"
 	"github.com/mikerourke/forensic-files-api/internal/hearnoevil"
+	"github.com/mikerourke/forensic-files-api/internal/housecall"
 	"github.com/mikerourke/forensic-files-api/internal/killigraphy"
+	"github.com/mikerourke/forensic-files-api/internal/measureofguilt"
+	"github.com/mikerourke/forensic-files-api/internal/printedproof"
 	"github.com/mikerourke/forensic-files-api/internal/tagasuspect"
+	"github.com/mikerourke/forensic-files-api/internal/timewilltell"
+	"github.com/mikerourke/forensic-files-api/internal/truelies"
 	"github.com/mikerourke/forensic-files-api/internal/videodiary"
 	"github.com/mikerourke/forensic-files-api/internal/visibilityzero"
+	"github.com/mikerourke/forensic-files-api/internal/wastemismanagement"
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/olekukonko/tablewriter"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -21,6 +39,38 @@ func main() {
 		"overwrite",
 		"Overwrite existing file").Short('x').Bool()
 
+	concurrencyFlag := app.Flag(
+		"concurrency",
+		"Number of episodes to process at the same time.",
+	).Short('j').Default("1").Int()
+
+	workspaceFlag := app.Flag(
+		"workspace",
+		"Root of the repo with the assets directory, alibi.json, and .env.",
+	).Short('W').Default(".").String()
+
+	configFlag := app.Flag(
+		"config",
+		"Path to the JSON config file (defaults to ALIBI_CONFIG or alibi.json in the workspace).",
+	).String()
+
+	setFlag := app.Flag(
+		"set",
+		"Override a setting (e.g. --set callback.port=9001).",
+	).PlaceHolder("KEY=VALUE").Strings()
+
+	// The flag overrides the gitAutoCommit setting, but only if it was
+	// specified.
+	var commitFlag bool
+	commitOverride := ""
+	app.Flag(
+		"commit",
+		"Commit recognitions, transcripts, and analyses to the investigations repo.",
+	).Action(func(*kingpin.ParseContext) error {
+		commitOverride = "gitAutoCommit=" + strconv.FormatBool(commitFlag)
+		return nil
+	}).BoolVar(&commitFlag)
+
 	registerCommand := app.Command(
 		"registercb",
 		"Register a callback URL.").Alias("rcb")
@@ -38,7 +88,7 @@ func main() {
 		"recognize",
 		"Send recognition job requests to the speech to text service.",
 	).Alias("rec")
-	recogSeason, recogEpisode := addSeasonEpisodeFlags(recognizeCommand)
+	recogSelection := addStaleSelectionFlags(recognizeCommand)
 
 	investigateCommand := app.Command(
 		"investigate",
@@ -47,36 +97,66 @@ func main() {
 	investigateAssetFlag := investigateCommand.Flag(
 		"asset",
 		"Asset to log.",
-	).Short('a').Required().Enum("analysis", "audio", "video", "recog", "trans")
+	).Short('a').Required().Enum(append(whodunit.AssetTypeKeys(true), "analysis")...)
 
 	investigateServiceFlag := investigateCommand.Flag(
 		"service",
-		"Service to use for the analysis.",
+		"Service to use for the analysis (with --asset analysis).",
 	).Default("gcp").Short('u').Enum("gcp", "ibm")
 
 	investigateFilterFlag := investigateCommand.Flag(
 		"filter",
 		"Type to filter by.",
-	).Short('f').Enum("pending", "complete", "in-process", "missing")
+	).Short('f').Enum("pending", "complete", "in-process", "missing", "failed", "stale")
+
+	matrixCommand := app.Command(
+		"matrix",
+		"Log status of every asset for each episode.").Alias("mx")
+	matrixSelection := addSelectionFlags(matrixCommand)
+
+	matrixAssetFlag := matrixCommand.Flag(
+		"asset",
+		"Asset to include as a column (all assets if not specified).",
+	).Short('a').Enums(whodunit.AssetTypeKeys(true)...)
+
+	matrixWhereFlag := matrixCommand.Flag(
+		"where",
+		"Only include episodes where the asset has the status "+
+			"(e.g. audio=complete, recognition!=complete).",
+	).Short('w').Strings()
+
+	matrixFormatFlag := matrixCommand.Flag(
+		"format",
+		"Output format.",
+	).Default(string(whodunit.MatrixFormatTable)).Short('o').Enum(whodunit.MatrixFormats()...)
+
+	statsCommand := app.Command(
+		"stats",
+		"Report completion, audio, storage, and analysis statistics.")
+	statsSelection := addSelectionFlags(statsCommand)
+
+	statsJSONFlag := statsCommand.Flag(
+		"json",
+		"Output the statistics as JSON.").Bool()
 
 	downloadCommand := app.Command(
 		"download",
 		"Download episodes from YouTube.").Alias("dl")
-	dlSeason, dlEpisode := addSeasonEpisodeFlags(downloadCommand)
+	dlSelection := addStaleSelectionFlags(downloadCommand)
 
 	extractCommand := app.Command(
 		"extract",
 		"Extract audio from downloaded episodes for recognition.").Alias("ext")
-	exSeason, exEpisode := addSeasonEpisodeFlags(extractCommand)
+	exSelection := addStaleSelectionFlags(extractCommand)
 
 	transcribeCommand := app.Command(
 		"transcribe",
 		"Transcribes episode from recognition.").Alias("tr")
-	transSeason, transEpisode := addSeasonEpisodeFlags(transcribeCommand)
+	transSelection := addStaleSelectionFlags(transcribeCommand)
 
 	analyzeCommand := app.Command("analyze",
 		"Create a new entity analysis.").Alias("an")
-	analyzeSeason, analyzeEpisode := addSeasonEpisodeFlags(analyzeCommand)
+	analyzeSelection := addStaleSelectionFlags(analyzeCommand)
 
 	analyzeServiceFlag := analyzeCommand.Flag(
 		"service",
@@ -87,72 +167,648 @@ func main() {
 		"csv",
 		"Output a CSV file to the specified directory.").Short('c').ExistingDir()
 
+	verifyCommand := app.Command(
+		"verify",
+		"Verify asset files against the season manifests.").Alias("ver")
+	verifySelection := addSelectionFlags(verifyCommand)
+
+	verifyAssetFlag := verifyCommand.Flag(
+		"asset",
+		"Asset to verify (all assets if not specified).",
+	).Short('a').Enums(whodunit.AssetTypeKeys(true)...)
+
+	verifyDeepFlag := verifyCommand.Flag(
+		"deep",
+		"Read every packet of video and audio files to catch truncation.",
+	).Short('d').Bool()
+
+	verifyResetFlag := verifyCommand.Flag(
+		"reset",
+		"Move corrupt files aside and reset them to pending.",
+	).Short('r').Bool()
+
+	probeCommand := app.Command(
+		"probe",
+		"Record the duration, codecs, and bitrate of video and audio files.",
+	).Alias("pr")
+	probeSelection := addSelectionFlags(probeCommand)
+
+	probeAssetFlag := probeCommand.Flag(
+		"asset",
+		"Asset to probe (video and audio if not specified).",
+	).Short('a').Enums(mediaAssetTypeKeys()...)
+
+	exportCommand := app.Command(
+		"export",
+		"Export the assets of episodes to portable bundles.")
+	exportSelection := addSelectionFlags(exportCommand)
+
+	exportAssetFlag := exportCommand.Flag(
+		"asset",
+		"Asset to include in the bundles (all assets if not specified).",
+	).Short('a').Enums(whodunit.AssetTypeKeys(true)...)
+
+	exportOutputFlag := exportCommand.Flag(
+		"output",
+		"Directory to write the bundles to.",
+	).Default(".").Short('o').ExistingDir()
+
+	exportPerFlag := exportCommand.Flag(
+		"per",
+		"Write a bundle for each season or each episode.",
+	).Default(string(handdelivered.GroupBySeason)).Enum(handdelivered.Groupings()...)
+
+	importCommand := app.Command(
+		"import",
+		"Import the assets in bundles created with export.")
+
+	importBundleArg := importCommand.Arg(
+		"bundle",
+		"Path to the bundle file(s).",
+	).Required().ExistingFiles()
+
+	gcCommand := app.Command(
+		"gc",
+		"Find orphan assets and partial downloads that can be removed.")
+
+	gcKindFlag := gcCommand.Flag(
+		"kind",
+		"Kind of file to collect (all kinds if not specified).",
+	).Short('k').Enums(wastemismanagement.Kinds()...)
+
+	gcMinAgeFlag := gcCommand.Flag(
+		"min-age",
+		"Skip files modified more recently than this (e.g. 30m, 24h).",
+	).Default("1h").Duration()
+
+	gcApplyFlag := gcCommand.Flag(
+		"apply",
+		"Remove the files instead of only listing them.").Bool()
+
+	catalogCommand := app.Command(
+		"catalog",
+		"Manage the episode catalog.").Alias("cat")
+
+	catalogSyncCommand := catalogCommand.Command(
+		"sync",
+		"Sync episode metadata from the OMDb API into the catalog.")
+	catalogSyncSelection := addSelectionFlags(catalogSyncCommand)
+
+	catalogSyncURLFlag := catalogSyncCommand.Flag(
+		"omdb-url",
+		"Base URL of the OMDb API (defaults to OMDB_URL or the public API).",
+	).String()
+
+	catalogLintCommand := catalogCommand.Command(
+		"lint",
+		"Check the catalog for gaps, duplicates, bad URLs, and orphan assets.")
+
+	catalogLintPathArg := catalogLintCommand.Arg(
+		"path",
+		"Path to the episodes JSON file (defaults to the one in /assets).",
+	).ExistingFile()
+
+	catalogLintAssetsFlag := catalogLintCommand.Flag(
+		"assets",
+		"Check the asset storage for files that aren't owned by an episode.",
+	).Default("true").Bool()
+
+	catalogRenameCommand := catalogCommand.Command(
+		"rename",
+		"Move the assets of episodes that are still named after an old title.")
+	catalogRenameSelection := addSelectionFlags(catalogRenameCommand)
+
+	catalogRenameTitleFlag := catalogRenameCommand.Flag(
+		"new-title",
+		"New title for the selected episode, which is updated in the catalog too.",
+	).String()
+
+	catalogRenameDryRunFlag := catalogRenameCommand.Flag(
+		"dry-run",
+		"List the files that would be moved without moving them.").Short('n').Bool()
+
+	configCommand := app.Command(
+		"config",
+		"Manage the configuration.")
+
+	configShowCommand := configCommand.Command(
+		"show",
+		"Show the effective settings and where they came from.")
+
+	doctorCommand := app.Command(
+		"doctor",
+		"Check the executables, settings, and directories the commands need.")
+
 	parsedCmd := kingpin.MustParse(app.Parse(os.Args[1:]))
 
-	ew := hearnoevil.NewEyewitness("")
-	d := tagasuspect.NewDetective()
+	overrides := *setFlag
+	if commitOverride != "" {
+		overrides = append(overrides, commitOverride)
+	}
+	config, err := crimeseen.LoadConfig(crimeseen.ConfigOptions{
+		Path:      *configFlag,
+		Dir:       *workspaceFlag,
+		Overrides: overrides,
+	})
+	app.FatalIfError(err, "config")
+
+	// Invalid settings are shown rather than stopping the show and doctor
+	// commands, so they can be tracked down. Those commands don't log
+	// anything worth keeping, so the log files are only set up for the rest.
+	isValidated := parsedCmd != configShowCommand.FullCommand() &&
+		parsedCmd != doctorCommand.FullCommand()
+	if isValidated {
+		app.FatalIfError(config.Validate(), "config")
+		app.FatalIfError(waterlogged.Configure(logSettings(config, *workspaceFlag)), "log")
+	}
+
+	ws, err := whodunit.NewWorkspace(*workspaceFlag, config)
+	app.FatalIfError(err, "workspace")
+
+	// An asset is treated as missing if the storage can't be configured, so
+	// the storage is checked up front instead of when the first file is read.
+	if isValidated {
+		_, err = ws.Storage()
+		app.FatalIfError(err, "storage")
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	concurrency := *concurrencyFlag
+
+	// The speech-to-text service needs credentials, so the eyewitness is only
+	// created for the commands that use it.
+	eyewitness := func() *hearnoevil.Eyewitness {
+		return hearnoevil.NewEyewitness(ws, "")
+	}
+	d := tagasuspect.NewDetective(ws)
 	switch parsedCmd {
 	case registerCommand.FullCommand():
-		ew.RegisterCallbackURL(*registerCommandURLFlag)
+		eyewitness().RegisterCallbackURL(*registerCommandURLFlag)
 
 	case serverCommand.FullCommand():
-		ew.StartCallbackServer()
+		eyewitness().StartCallbackServer()
 
 	case recognizeCommand.FullCommand():
-		ew.Recognize(*recogSeason, *recogEpisode)
+		sel := recogSelection.parseFor(app, ws, whodunit.AssetTypeRecognition)
+		err := eyewitness().Recognize(ctx, sel, concurrency)
+		app.FatalIfError(err, "recognize")
 
 	case investigateCommand.FullCommand():
 		status := whodunit.AssetStatusAny
 		if investigateFilterFlag != nil {
 			status = flagToAssetStatus(*investigateFilterFlag)
 		}
-		switch *investigateAssetFlag {
-		case "analysis":
-			cloudService := flagToCloudService(*investigateServiceFlag)
-			tagasuspect.Investigate(cloudService, status)
-		case "audio":
-			visibilityzero.Investigate(status)
-		case "recog":
-			ew.Investigate(status)
-		case "trans":
-			killigraphy.Investigate(status)
-		case "video":
-			videodiary.Investigate(status)
+		assetKey := *investigateAssetFlag
+		if assetKey == "analysis" {
+			assetKey = *investigateServiceFlag + "-analysis"
+		}
+
+		// The enum only allows registered keys, so the error is ignored.
+		assetType, _ := whodunit.ParseAssetType(assetKey)
+		if assetType == whodunit.AssetTypeRecognition {
+			// Jobs are checked with the speech-to-text service, so jobs that
+			// are still running show up as in process.
+			err := eyewitness().Investigate(status)
+			app.FatalIfError(err, "investigate")
+		} else {
+			c, err := ws.Catalog()
+			app.FatalIfError(err, "investigate")
+			whodunit.NewStatusTable(assetType, status).LogCatalog(c)
+		}
+
+	case matrixCommand.FullCommand():
+		sel := matrixSelection.parse(app, ws)
+		err := logMatrix(ws, sel, flagsToAssetTypes(*matrixAssetFlag),
+			*matrixWhereFlag, whodunit.MatrixFormat(*matrixFormatFlag))
+		app.FatalIfError(err, "matrix")
+
+	case statsCommand.FullCommand():
+		sel := statsSelection.parse(app, ws)
+		stats, err := timewilltell.Gather(ws, sel)
+		app.FatalIfError(err, "stats")
+		if *statsJSONFlag {
+			app.FatalIfError(stats.WriteJSON(os.Stdout), "stats")
+		} else {
+			stats.Report(os.Stdout)
 		}
 
 	case downloadCommand.FullCommand():
-		videodiary.Download(*dlSeason, *dlEpisode)
+		sel := dlSelection.parseFor(app, ws, whodunit.AssetTypeVideo)
+		err := videodiary.Download(ctx, ws, sel, concurrency)
+		app.FatalIfError(err, "download")
 
 	case extractCommand.FullCommand():
-		visibilityzero.ExtractAudio(*exSeason, *exEpisode)
+		sel := exSelection.parseFor(app, ws, whodunit.AssetTypeAudio)
+		err := visibilityzero.ExtractAudio(ctx, ws, sel, concurrency)
+		app.FatalIfError(err, "extract")
 
 	case transcribeCommand.FullCommand():
-		killigraphy.Transcribe(*transSeason, *transEpisode)
+		sel := transSelection.parseFor(app, ws, whodunit.AssetTypeTranscript)
+		err := killigraphy.Transcribe(ctx, ws, sel, concurrency)
+		app.FatalIfError(err, "transcribe")
+
+	case verifyCommand.FullCommand():
+		opts := printedproof.Options{
+			AssetTypes: flagsToAssetTypes(*verifyAssetFlag),
+			Deep:       *verifyDeepFlag,
+			Reset:      *verifyResetFlag,
+		}
+		sel := verifySelection.parse(app, ws)
+		err := printedproof.Verify(ctx, ws, sel, concurrency, opts)
+		app.FatalIfError(err, "verify")
+
+	case probeCommand.FullCommand():
+		assetTypes := flagsToAssetTypes(*probeAssetFlag)
+		if len(*probeAssetFlag) == 0 {
+			assetTypes = mediaAssetTypes()
+		}
+		sel := probeSelection.parse(app, ws)
+		err := measureofguilt.Measure(ctx, ws, sel, concurrency, assetTypes)
+		app.FatalIfError(err, "probe")
+
+	case exportCommand.FullCommand():
+		opts := handdelivered.ExportOptions{
+			AssetTypes: flagsToAssetTypes(*exportAssetFlag),
+			OutputDir:  *exportOutputFlag,
+			Grouping:   handdelivered.Grouping(*exportPerFlag),
+			Overwrite:  *overwriteFlag,
+		}
+		sel := exportSelection.parse(app, ws)
+		_, err := handdelivered.Export(ctx, ws, sel, opts)
+		app.FatalIfError(err, "export")
+
+	case importCommand.FullCommand():
+		for _, bundlePath := range *importBundleArg {
+			err := handdelivered.Import(ctx, ws, bundlePath, *overwriteFlag)
+			app.FatalIfError(err, "import")
+		}
+
+	case gcCommand.FullCommand():
+		opts := wastemismanagement.Options{
+			Kinds:  flagsToGarbageKinds(*gcKindFlag),
+			MinAge: *gcMinAgeFlag,
+		}
+		err := collectGarbage(ws, opts, *gcApplyFlag)
+		app.FatalIfError(err, "gc")
+
+	case catalogSyncCommand.FullCommand():
+		sel := catalogSyncSelection.parse(app, ws)
+		r := breakingnews.NewReporter(ws, *catalogSyncURLFlag)
+		err := r.Sync(ctx, sel, concurrency)
+		app.FatalIfError(err, "catalog sync")
+
+	case catalogLintCommand.FullCommand():
+		err := lintCatalog(ws, *catalogLintPathArg, *catalogLintAssetsFlag)
+		app.FatalIfError(err, "catalog lint")
+
+	case catalogRenameCommand.FullCommand():
+		sel := catalogRenameSelection.parse(app, ws)
+		err := renameEpisodes(ws, sel, *catalogRenameTitleFlag, *catalogRenameDryRunFlag)
+		app.FatalIfError(err, "catalog rename")
+
+	case configShowCommand.FullCommand():
+		err := showConfig(config)
+		app.FatalIfError(err, "config show")
+
+	case doctorCommand.FullCommand():
+		results := housecall.Examine(ws)
+		housecall.Report(os.Stdout, results)
+		if housecall.Failed(results) {
+			app.Fatalf("doctor: some checks failed, fix them before running the other commands")
+		}
 
 	case analyzeCommand.FullCommand():
-		season := *analyzeSeason
-		episode := *analyzeEpisode
+		cloudService := flagToCloudService(*analyzeServiceFlag)
+		assetType := tagasuspect.AssetTypeForCloudService(cloudService)
+		sel := analyzeSelection.parseFor(app, ws, assetType)
 		if *analyzeCSVFlag != "" {
-			d.FileReport(season, episode, *analyzeCSVFlag)
+			err := d.FileReport(ctx, sel, *analyzeCSVFlag)
+			app.FatalIfError(err, "analyze")
 		} else {
-			cloudService := flagToCloudService(*analyzeServiceFlag)
 			d.OpenCase(cloudService)
-			defer d.CloseCase()
-			d.Analyze(season, episode, *overwriteFlag)
+			err := d.Analyze(ctx, sel, concurrency, *overwriteFlag)
+			d.CloseCase()
+			app.FatalIfError(err, "analyze")
+		}
+	}
+}
+
+// logMatrix writes the status of the specified asset types for each episode in
+// the selection that meets the specified conditions to stdout.
+func logMatrix(
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	assetTypes []whodunit.AssetType,
+	where []string,
+	format whodunit.MatrixFormat,
+) error {
+	conditions := make([]*whodunit.StatusCondition, 0, len(where))
+	for _, value := range where {
+		condition, err := whodunit.ParseStatusCondition(value)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	c, err := ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	m := whodunit.NewStatusMatrix(c, sel, assetTypes).Where(conditions...)
+	return m.Write(os.Stdout, format)
+}
+
+// collectGarbage lists the files in the asset storage that are safe to remove
+// and removes them if apply is true.
+func collectGarbage(
+	ws *whodunit.Workspace,
+	opts wastemismanagement.Options,
+	apply bool,
+) error {
+	c, err := ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	storage, err := ws.Storage()
+	if err != nil {
+		return err
+	}
+
+	garbage, err := wastemismanagement.Collect(c, storage, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(garbage) == 0 {
+		fmt.Println("Nothing to collect")
+		return nil
+	}
+
+	wastemismanagement.Report(os.Stdout, garbage)
+	if !apply {
+		fmt.Println("Run again with --apply to remove these files")
+		return nil
+	}
+
+	return wastemismanagement.Sweep(storage, garbage)
+}
+
+// renameEpisodes moves the assets of the episodes in the selection that are
+// still named after an old title. If a title is specified, the selection must
+// be a single episode, which is given the new title first. If dryRun is true,
+// the files are listed without moving them.
+func renameEpisodes(
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	title string,
+	dryRun bool,
+) error {
+	c, err := ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	var renames []*aboutface.Rename
+	if title != "" {
+		episodes := c.Select(sel)
+		if len(episodes) != 1 {
+			return fmt.Errorf("--new-title requires a single episode, %d selected", len(episodes))
+		}
+
+		r, err := aboutface.Retitle(episodes[0], title)
+		if err != nil {
+			return err
+		}
+		renames = []*aboutface.Rename{r}
+	} else {
+		renames, err = aboutface.FindRenames(c, sel)
+		if err != nil {
+			return err
 		}
 	}
+
+	if len(renames) == 0 {
+		fmt.Println("Nothing to rename")
+		return nil
+	}
+
+	aboutface.Report(os.Stdout, renames)
+	if dryRun {
+		return nil
+	}
+
+	for _, r := range renames {
+		if err := r.Apply(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func addSeasonEpisodeFlags(
-	command *kingpin.CmdClause,
-) (seasonFlag *int, episodeFlag *int) {
-	seasonFlag = command.Flag(
-		"season",
-		"Season number to process.").Short('s').Int()
-	episodeFlag = command.Flag(
-		"episode",
-		"Episode number to process.").Short('e').Int()
-	return
+// logSettings returns the log file settings in the specified config with the
+// log directory resolved against the specified workspace.
+func logSettings(config *crimeseen.Config, workspace string) waterlogged.Settings {
+	dir := config.Log.Dir
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(workspace, dir)
+	}
+
+	return waterlogged.Settings{
+		Dir:      dir,
+		Level:    config.Log.Level,
+		Format:   config.Log.Format,
+		Files:    config.Log.Files,
+		MaxSize:  config.Log.MaxSize,
+		MaxAge:   config.Log.MaxAge,
+		Compress: config.Log.Compress,
+	}
+}
+
+// showConfig writes every setting in the specified config to stdout as a table
+// with secrets redacted and returns an error if any of the values are invalid.
+func showConfig(config *crimeseen.Config) error {
+	path := config.Path()
+	if path == "" {
+		path = "none"
+	}
+	fmt.Printf("Config file: %s\n", path)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Setting", "Value", "Source", "Environment Variable"})
+	for _, setting := range config.Settings() {
+		table.Append([]string{
+			setting.Key,
+			setting.DisplayValue(),
+			string(config.Source(setting.Key)),
+			setting.EnvVar,
+		})
+	}
+	table.Render()
+
+	return config.Validate()
+}
+
+// lintCatalog checks the catalog at the specified path (or the catalog of the
+// workspace if empty) and returns an error if any problems were found.
+func lintCatalog(ws *whodunit.Workspace, path string, withAssets bool) error {
+	if path == "" {
+		path = ws.CatalogPath()
+	}
+
+	episodes, err := whodunit.LoadEpisodes(path)
+	if err != nil {
+		return err
+	}
+
+	var storage coldstorage.Storage
+	if withAssets {
+		storage, err = ws.Storage()
+		if err != nil {
+			return err
+		}
+	}
+
+	problems, err := truelies.Lint(episodes, storage)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	truelies.Report(problems)
+	return fmt.Errorf("%d problem(s) found", len(problems))
+}
+
+// selectionFlags contains the flags used to specify which episodes a command
+// should process.
+type selectionFlags struct {
+	series  *string
+	season  *int
+	episode *int
+	expr    *string
+	title   *string
+	stale   *bool
+}
+
+func addSelectionFlags(command *kingpin.CmdClause) *selectionFlags {
+	return &selectionFlags{
+		series: command.Flag(
+			"series",
+			"Series to process (e.g. forensic-files-ii). Seasons and episodes "+
+				"refer to the original series if not specified.").String(),
+		season: command.Flag(
+			"season",
+			"Season number to process.").Short('s').Int(),
+		episode: command.Flag(
+			"episode",
+			"Episode number to process.").Short('e').Int(),
+		expr: command.Flag(
+			"select",
+			"Episodes to process (e.g. s3, s3e2-s4e10, s1,s5e7,!s14, forensic-files-ii:s1).",
+		).Short('S').String(),
+		title: command.Flag(
+			"title",
+			"Title of the episode to process, which doesn't need to be exact "+
+				"(e.g. \"knot for everyone\" or helle).").Short('t').String(),
+	}
+}
+
+// addStaleSelectionFlags adds the selection flags along with a flag to only
+// process episodes where the asset produced by the command is stale.
+func addStaleSelectionFlags(command *kingpin.CmdClause) *selectionFlags {
+	sf := addSelectionFlags(command)
+	sf.stale = command.Flag(
+		"stale",
+		"Only process episodes where the asset is stale.").Bool()
+	return sf
+}
+
+// parse returns the selection represented by the flag values and exits if the
+// values are invalid. Titles are looked up in the catalog of the specified
+// workspace.
+func (sf *selectionFlags) parse(
+	app *kingpin.Application,
+	ws *whodunit.Workspace,
+) *whodunit.Selection {
+	var series whodunit.Series
+	if *sf.series != "" {
+		var err error
+		series, err = whodunit.ParseSeries(*sf.series)
+		app.FatalIfError(err, "")
+	}
+
+	var sel *whodunit.Selection
+	var err error
+	switch {
+	case *sf.title != "":
+		if *sf.expr != "" || *sf.season != 0 || *sf.episode != 0 {
+			app.Fatalf("--title can't be combined with --select, --season, or --episode")
+		}
+		ep, lookupErr := lookupTitle(ws, *sf.title, series)
+		app.FatalIfError(lookupErr, "")
+		sel, err = whodunit.EpisodeSelection(ep)
+
+	case *sf.expr != "":
+		if *sf.season != 0 || *sf.episode != 0 {
+			app.Fatalf("--select can't be combined with --season or --episode")
+		}
+		sel, err = whodunit.ParseSelection(*sf.expr)
+
+	default:
+		sel, err = whodunit.NewSelection(*sf.season, *sf.episode)
+	}
+	app.FatalIfError(err, "")
+
+	if series != "" {
+		sel = sel.WithSeries(series)
+	}
+
+	return sel
+}
+
+// parseFor returns the selection represented by the flag values. If the stale
+// flag was specified, the selection is limited to episodes where the asset
+// associated with the specified asset type is stale.
+func (sf *selectionFlags) parseFor(
+	app *kingpin.Application,
+	ws *whodunit.Workspace,
+	assetType whodunit.AssetType,
+) *whodunit.Selection {
+	sel := sf.parse(app, ws)
+	if sf.stale != nil && *sf.stale {
+		sel = sel.WithStatus(assetType, whodunit.AssetStatusStale)
+	}
+	return sel
+}
+
+// interruptContext returns a context that is canceled when the process
+// receives an interrupt, so episodes in process can finish cleanly. A second
+// interrupt terminates the process immediately.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
 }
 
 func flagToAssetStatus(value string) whodunit.AssetStatus {
@@ -165,10 +821,65 @@ func flagToAssetStatus(value string) whodunit.AssetStatus {
 		return whodunit.AssetStatusComplete
 	case "missing":
 		return whodunit.AssetStatusMissing
+	case "failed":
+		return whodunit.AssetStatusFailed
+	case "stale":
+		return whodunit.AssetStatusStale
 	}
 	return whodunit.AssetStatusAny
 }
 
+// flagsToAssetTypes returns the asset types associated with the specified
+// keys or every asset type if no keys were specified.
+func flagsToAssetTypes(values []string) []whodunit.AssetType {
+	if len(values) == 0 {
+		return whodunit.AllAssetTypes()
+	}
+
+	assetTypes := make([]whodunit.AssetType, 0)
+	for _, value := range values {
+		// The values are validated by the flag enum, so the error is ignored.
+		assetType, _ := whodunit.ParseAssetType(value)
+		assetTypes = append(assetTypes, assetType)
+	}
+	return assetTypes
+}
+
+// flagsToGarbageKinds returns the kinds of garbage associated with the
+// specified keys or every kind if no keys were specified.
+func flagsToGarbageKinds(values []string) []wastemismanagement.Kind {
+	if len(values) == 0 {
+		values = wastemismanagement.Kinds()
+	}
+
+	kinds := make([]wastemismanagement.Kind, 0, len(values))
+	for _, value := range values {
+		kinds = append(kinds, wastemismanagement.Kind(value))
+	}
+	return kinds
+}
+
+// mediaAssetTypes returns the asset types for video and audio files.
+func mediaAssetTypes() []whodunit.AssetType {
+	assetTypes := make([]whodunit.AssetType, 0)
+	for _, assetType := range whodunit.AllAssetTypes() {
+		if assetType.IsMedia() {
+			assetTypes = append(assetTypes, assetType)
+		}
+	}
+	return assetTypes
+}
+
+// mediaAssetTypeKeys returns the keys of the video and audio asset types for
+// use in flag enums.
+func mediaAssetTypeKeys() []string {
+	keys := make([]string, 0)
+	for _, assetType := range mediaAssetTypes() {
+		keys = append(keys, assetType.String())
+	}
+	return keys
+}
+
 func flagToCloudService(value string) tagasuspect.CloudService {
 	if value == "gcp" {
 		return tagasuspect.CloudServiceGCP
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+)
+
+// maxTitleChoices is the maximum number of episodes listed when asking which
+// episode was meant by an ambiguous title.
+const maxTitleChoices = 10
+
+// lookupTitle returns the episode in the catalog of the specified workspace
+// with the title that best matches the specified query. If a series is
+// specified, only episodes in the series are searched. If the match is
+// ambiguous and the command is running in a terminal, the user is asked to
+// pick one of the closest matches.
+func lookupTitle(
+	ws *whodunit.Workspace,
+	query string,
+	series whodunit.Series,
+) (*whodunit.Episode, error) {
+	c, err := ws.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	displayTitles, err := whodunit.LoadDisplayTitles(c, ws.DisplayTitlesPath())
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*whodunit.TitleMatch, 0)
+	for _, match := range c.SearchTitle(query, displayTitles) {
+		if series == "" || match.Episode.Series.Key() == series.Key() {
+			matches = append(matches, match)
+		}
+	}
+
+	ep, err := whodunit.BestTitleMatch(query, matches)
+	var ambiguousErr *whodunit.AmbiguousTitleError
+	if !errors.As(err, &ambiguousErr) || !isTerminal(os.Stdin) {
+		return ep, err
+	}
+
+	return chooseTitleMatch(ambiguousErr)
+}
+
+// chooseTitleMatch lists the matches of the ambiguous title and asks the user
+// to pick one. If there's no answer, the ambiguous title error is returned.
+func chooseTitleMatch(
+	ambiguousErr *whodunit.AmbiguousTitleError,
+) (*whodunit.Episode, error) {
+	matches := ambiguousErr.Matches
+	if len(matches) > maxTitleChoices {
+		matches = matches[:maxTitleChoices]
+	}
+
+	fmt.Fprintln(os.Stderr, "More than one episode matches that title:")
+	for i, match := range matches {
+		fmt.Fprintf(os.Stderr, "  %2d) %s\n", i+1, match.Episode.Name())
+	}
+	fmt.Fprintf(os.Stderr, "Which episode did you mean? [1-%d] ", len(matches))
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && strings.TrimSpace(answer) == "" {
+		fmt.Fprintln(os.Stderr)
+		return nil, ambiguousErr
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || choice < 1 || choice > len(matches) {
+		return nil, fmt.Errorf("invalid choice %q", strings.TrimSpace(answer))
+	}
+
+	return matches[choice-1].Episode, nil
+}
+
+// isTerminal returns true if the specified file is an interactive terminal
+// rather than a pipe or a regular file.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,317 @@
+// Package aboutface moves the assets of an episode to its new name when the
+// title in the catalog changes, so correcting a typo in a title doesn't orphan
+// the recognitions, transcripts, and analyses that were already paid for.
+package aboutface
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+)
+
+// Move is a single asset file that gets moved to a new path in the asset
+// storage.
+type Move struct {
+	AssetType whodunit.AssetType
+	From      string
+	To        string
+}
+
+// Rename contains the asset files of an episode that are still named after
+// an old title and where they get moved to.
+type Rename struct {
+	Episode *whodunit.Episode
+	OldName string
+
+	// NewTitle is the title the episode gets in the catalog when the rename
+	// is applied. It's empty if the catalog already has the new title.
+	NewTitle string
+
+	Moves []*Move
+}
+
+var log = waterlogged.New("aboutface")
+
+// Retitle returns the rename that changes the title of the specified episode
+// in the catalog and moves its assets to the new name. The title is
+// normalized to the form used in the catalog (e.g. "Knot for Everyone"
+// becomes "knot-for-everyone").
+func Retitle(ep *whodunit.Episode, title string) (*Rename, error) {
+	newTitle := whodunit.NormalizeTitle(title)
+	if newTitle == "" {
+		return nil, fmt.Errorf("title %q is empty once normalized", title)
+	}
+
+	if newTitle == ep.Title {
+		return nil, fmt.Errorf("episode %s already has the title %s", ep.Name(), newTitle)
+	}
+
+	r := &Rename{
+		Episode:  ep,
+		OldName:  ep.Name(),
+		NewTitle: newTitle,
+		Moves:    make([]*Move, 0),
+	}
+
+	newName := strings.TrimSuffix(ep.Name(), ep.Title) + newTitle
+	for _, assetType := range whodunit.AllAssetTypes() {
+		files, err := assetFiles(ep, assetType, r.OldName+".")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			r.addMove(assetType, file.Path, newName)
+		}
+	}
+
+	return r, nil
+}
+
+// FindRenames returns the renames needed to move the asset files of the
+// episodes in the specified selection that are still named after an old title
+// (e.g. because the title was corrected in `episodes.json`). Files are matched
+// by season and episode number, so only files that aren't owned by any episode
+// in the catalog are moved.
+func FindRenames(c *whodunit.Catalog, sel *whodunit.Selection) ([]*Rename, error) {
+	renames := make([]*Rename, 0)
+	for _, ep := range c.Select(sel) {
+		byOldName := make(map[string]*Rename)
+		numberPrefix := strings.TrimSuffix(ep.Name(), ep.Title)
+		for _, assetType := range whodunit.AllAssetTypes() {
+			files, err := assetFiles(ep, assetType, numberPrefix)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, file := range files {
+				oldName := strings.SplitN(file.Name(), ".", 2)[0]
+				if oldName == ep.Name() || c.AssetOwner(assetType, file.Path) != nil {
+					continue
+				}
+
+				r, ok := byOldName[oldName]
+				if !ok {
+					r = &Rename{Episode: ep, OldName: oldName, Moves: make([]*Move, 0)}
+					byOldName[oldName] = r
+					renames = append(renames, r)
+				}
+				r.addMove(assetType, file.Path, ep.Name())
+			}
+		}
+	}
+
+	return renames, nil
+}
+
+// assetFiles returns the asset files in the season directory of the episode
+// with a name that starts with the specified prefix. Partial downloads and
+// quarantined files (e.g. `.mp4.part`) are left for `alibi gc`.
+func assetFiles(
+	ep *whodunit.Episode,
+	assetType whodunit.AssetType,
+	namePrefix string,
+) ([]*coldstorage.FileInfo, error) {
+	ws, err := ep.Workspace()
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := ws.Storage()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Join(assetType.DirName(), ep.Season().DirName())
+	files, err := storage.List(path.Join(dir, namePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s files: %w", assetType, err)
+	}
+
+	assets := make([]*coldstorage.FileInfo, 0, len(files))
+	for _, file := range files {
+		if path.Dir(file.Path) == dir && strings.Count(file.Name(), ".") == 1 {
+			assets = append(assets, file)
+		}
+	}
+
+	return assets, nil
+}
+
+// addMove adds a move of the file at the specified path to a file with the
+// specified name and the same extension.
+func (r *Rename) addMove(assetType whodunit.AssetType, from string, newName string) {
+	ext := path.Ext(from)
+	r.Moves = append(r.Moves, &Move{
+		AssetType: assetType,
+		From:      from,
+		To:        path.Join(path.Dir(from), newName+ext),
+	})
+}
+
+// Apply moves the asset files, updates the title (if it changed) and media
+// paths in the specified catalog, and moves the ledger and manifest entries to
+// the new name. If any file can't be moved or the catalog can't be saved, the
+// files that were already moved are moved back. Nothing is moved if any of the
+// new paths already exist.
+func (r *Rename) Apply(c *whodunit.Catalog) error {
+	ws, err := r.Episode.Workspace()
+	if err != nil {
+		return err
+	}
+
+	storage, err := ws.Storage()
+	if err != nil {
+		return err
+	}
+
+	// The assets are locked before the new paths are checked, so another
+	// process can't write one of them while the files are being moved.
+	unlock, err := r.lockAssets()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, move := range r.Moves {
+		if coldstorage.Exists(storage, move.To) {
+			return fmt.Errorf("can't move %s, %s already exists", move.From, move.To)
+		}
+	}
+
+	moved := make([]*Move, 0, len(r.Moves))
+	rollback := func() {
+		for i := len(moved) - 1; i >= 0; i-- {
+			move := moved[i]
+			if err := coldstorage.Rename(storage, move.To, move.From); err != nil {
+				log.WithFields(logrus.Fields{
+					"file":  move.To,
+					"error": err,
+				}).Errorln("Unable to move file back")
+			}
+		}
+	}
+
+	for _, move := range r.Moves {
+		if err := coldstorage.Rename(storage, move.From, move.To); err != nil {
+			rollback()
+			return fmt.Errorf("error moving %s: %w", move.From, err)
+		}
+		moved = append(moved, move)
+	}
+
+	ep := r.Episode
+	oldTitle := ep.Title
+	if r.NewTitle != "" {
+		ep.Title = r.NewTitle
+	}
+
+	mediaPaths := make(map[whodunit.AssetType]string)
+	for _, move := range r.Moves {
+		if info := ep.MediaInfo(move.AssetType); info != nil && info.Path == move.From {
+			mediaPaths[move.AssetType] = info.Path
+			info.Path = move.To
+		}
+	}
+
+	if r.NewTitle != "" || len(mediaPaths) != 0 {
+		if err := c.Save(c.Workspace().CatalogPath()); err != nil {
+			ep.Title = oldTitle
+			for assetType, mediaPath := range mediaPaths {
+				ep.MediaInfo(assetType).Path = mediaPath
+			}
+			rollback()
+			return err
+		}
+	}
+
+	// The files are already in place, so the entries are updated even if
+	// one of them fails.
+	ledgerErr := ep.RenameLedgerEntries(r.OldName)
+	manifestErr := ep.RenameManifestEntries(r.OldName)
+	if ledgerErr != nil {
+		return fmt.Errorf("files moved, but unable to update ledger: %w", ledgerErr)
+	}
+	if manifestErr != nil {
+		return fmt.Errorf("files moved, but unable to update manifest: %w", manifestErr)
+	}
+
+	log.WithFields(logrus.Fields{
+		"from":  r.OldName,
+		"to":    ep.Name(),
+		"files": len(r.Moves),
+	}).Infoln("Renamed episode")
+	return nil
+}
+
+// lockAssets locks each asset type of the episode that has a file to move and
+// returns the function that releases the locks. The asset types are always
+// locked in the same order, so two renames can't deadlock.
+func (r *Rename) lockAssets() (func(), error) {
+	unlocks := make([]func(), 0)
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+
+	for _, assetType := range whodunit.AllAssetTypes() {
+		if !r.hasMove(assetType) {
+			continue
+		}
+
+		unlock, err := r.Episode.LockAsset(assetType)
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+
+	return unlockAll, nil
+}
+
+// hasMove returns true if a file of the specified asset type gets moved.
+func (r *Rename) hasMove(assetType whodunit.AssetType) bool {
+	for _, move := range r.Moves {
+		if move.AssetType == assetType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Report writes the moves of the specified renames to the specified writer as
+// a table.
+func Report(w io.Writer, renames []*Rename) {
+	moveCount := 0
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Asset", "From", "To"})
+	for _, r := range renames {
+		moves := make([]*Move, len(r.Moves))
+		copy(moves, r.Moves)
+		sort.SliceStable(moves, func(i, j int) bool {
+			return moves[i].From < moves[j].From
+		})
+
+		for _, move := range moves {
+			table.Append([]string{move.AssetType.String(), move.From, move.To})
+			moveCount++
+		}
+	}
+	table.SetFooter([]string{"Total", strconv.Itoa(moveCount), ""})
+	table.Render()
+}
@@ -0,0 +1,316 @@
+// Package breakingnews fetches episode metadata (air date, plot, rating, etc.)
+// from the OMDb API and syncs it into the episode catalog.
+package breakingnews
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBaseURL is the base URL of the public OMDb API.
+const DefaultBaseURL = "https://www.omdbapi.com/"
+
+// Reporter fetches episode metadata from the OMDb API.
+type Reporter struct {
+	ws      *whodunit.Workspace
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// SeasonListing is a single episode in the response from a season request.
+type SeasonListing struct {
+	Title      string `json:"Title"`
+	Released   string `json:"Released"`
+	Episode    string `json:"Episode"`
+	IMDbRating string `json:"imdbRating"`
+	IMDbID     string `json:"imdbID"`
+}
+
+// EpisodeDetails is the response from an episode request.
+type EpisodeDetails struct {
+	Title      string `json:"Title"`
+	Released   string `json:"Released"`
+	Runtime    string `json:"Runtime"`
+	Plot       string `json:"Plot"`
+	IMDbRating string `json:"imdbRating"`
+	IMDbID     string `json:"imdbID"`
+}
+
+// omdbResponse contains the fields included in every OMDb response.
+type omdbResponse struct {
+	Response string `json:"Response"`
+	Error    string `json:"Error"`
+}
+
+// notAvailable is the value OMDb returns for fields without a value.
+const notAvailable = "N/A"
+
+var log = waterlogged.New("breakingnews")
+
+// NewReporter returns a new instance of a reporter that syncs the catalog of
+// the specified workspace and sends requests to the specified base URL. If the
+// base URL is empty, the `omdb.url` setting is used, followed by the public
+// OMDb API.
+func NewReporter(ws *whodunit.Workspace, baseURL string) *Reporter {
+	config := ws.Config()
+	if baseURL == "" {
+		baseURL = config.OMDb.URL
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Reporter{
+		ws:      ws,
+		baseURL: baseURL,
+		apiKey:  config.OMDb.APIKey,
+		client:  &http.Client{Timeout: config.OMDb.Timeout},
+	}
+}
+
+// Season returns the episodes in the specified season of the series with the
+// specified title.
+func (r *Reporter) Season(
+	ctx context.Context,
+	title string,
+	seasonNumber int,
+) ([]*SeasonListing, error) {
+	query := url.Values{}
+	query.Set("t", title)
+	query.Set("Season", strconv.Itoa(seasonNumber))
+
+	var result struct {
+		omdbResponse
+		Episodes []*SeasonListing `json:"Episodes"`
+	}
+	if err := r.get(ctx, query, &result, &result.omdbResponse); err != nil {
+		return nil, fmt.Errorf("error fetching season %d: %w", seasonNumber, err)
+	}
+
+	return result.Episodes, nil
+}
+
+// Episode returns the details of the episode with the specified IMDb ID.
+func (r *Reporter) Episode(
+	ctx context.Context,
+	imdbID string,
+) (*EpisodeDetails, error) {
+	query := url.Values{}
+	query.Set("i", imdbID)
+	query.Set("plot", "short")
+
+	var result struct {
+		omdbResponse
+		EpisodeDetails
+	}
+	if err := r.get(ctx, query, &result, &result.omdbResponse); err != nil {
+		return nil, fmt.Errorf("error fetching episode %s: %w", imdbID, err)
+	}
+
+	return &result.EpisodeDetails, nil
+}
+
+func (r *Reporter) get(
+	ctx context.Context,
+	query url.Values,
+	result interface{},
+	resp *omdbResponse,
+) error {
+	if r.apiKey != "" {
+		query.Set("apikey", r.apiKey)
+	}
+
+	reqURL := r.baseURL + "?" + query.Encode()
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+
+	if resp.Response == "False" {
+		return fmt.Errorf("omdb: %s", resp.Error)
+	}
+
+	return nil
+}
+
+// Sync fetches the metadata for each episode in the specified selection from
+// the OMDb API, fetching up to the specified number of episodes at the same
+// time, and saves the results to the episodes JSON file. Episodes that were
+// updated are saved even if others failed.
+func (r *Reporter) Sync(
+	ctx context.Context,
+	sel *whodunit.Selection,
+	concurrency int,
+) error {
+	c, err := r.ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	seasons := &seasonCache{
+		reporter: r,
+		listings: make(map[*whodunit.Season]map[int]*SeasonListing),
+	}
+
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
+		listing, err := seasons.listing(ctx, ep)
+		if err != nil {
+			return err
+		}
+
+		details, err := r.Episode(ctx, listing.IMDbID)
+		if err != nil {
+			return err
+		}
+
+		applyDetails(ep, listing, details)
+		log.WithFields(logrus.Fields{
+			"series":  ep.Series.String(),
+			"season":  ep.SeasonNumber,
+			"episode": ep.EpisodeNumber,
+			"imdbId":  ep.IMDbID,
+		}).Infoln("Synced episode metadata")
+		return nil
+	}
+
+	summary, err := c.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
+		log.WithError(err).Errorln("Error syncing episode(s)")
+		return err
+	}
+
+	summary.Log(log)
+
+	if len(summary.Succeeded()) != 0 {
+		if err := r.ws.SaveCatalog(); err != nil {
+			return fmt.Errorf("error saving catalog: %w", err)
+		}
+		log.WithField("file", r.ws.CatalogPath()).Infoln("Catalog saved")
+	}
+
+	return summary.Err()
+}
+
+// seasonCache fetches the episode listing for each season once, so episodes
+// in the same season don't each request it. The season is looked up on OMDb
+// by the title of the episode's series.
+type seasonCache struct {
+	reporter *Reporter
+	mutex    sync.Mutex
+	listings map[*whodunit.Season]map[int]*SeasonListing
+}
+
+func (sc *seasonCache) listing(
+	ctx context.Context,
+	ep *whodunit.Episode,
+) (*SeasonListing, error) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	seasonNumber, episodeNumber := ep.SeasonNumber, ep.EpisodeNumber
+	listings, ok := sc.listings[ep.Season()]
+	if !ok {
+		episodes, err := sc.reporter.Season(ctx, ep.Series.Title(), seasonNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		listings = make(map[int]*SeasonListing)
+		for _, listing := range episodes {
+			n, err := strconv.Atoi(listing.Episode)
+			if err == nil {
+				listings[n] = listing
+			}
+		}
+		sc.listings[ep.Season()] = listings
+	}
+
+	listing, ok := listings[episodeNumber]
+	if !ok || listing.IMDbID == "" {
+		return nil, fmt.Errorf("episode %d not found in OMDb season %d of %s",
+			episodeNumber, seasonNumber, ep.Series.Title())
+	}
+
+	return listing, nil
+}
+
+// applyDetails copies the metadata from the OMDb responses to the episode.
+// Fields that aren't available are left unchanged.
+func applyDetails(
+	ep *whodunit.Episode,
+	listing *SeasonListing,
+	details *EpisodeDetails,
+) {
+	ep.IMDbID = listing.IMDbID
+
+	if airDate := parseReleased(details.Released, listing.Released); airDate != "" {
+		ep.AirDate = airDate
+	}
+
+	if details.Plot != "" && details.Plot != notAvailable {
+		ep.Plot = details.Plot
+	}
+
+	if runtime := parseRuntime(details.Runtime); runtime != 0 {
+		ep.Runtime = runtime
+	}
+
+	rating := details.IMDbRating
+	if rating == "" || rating == notAvailable {
+		rating = listing.IMDbRating
+	}
+	if value, err := strconv.ParseFloat(rating, 64); err == nil {
+		ep.Rating = value
+	}
+}
+
+// parseReleased returns the first valid release date from the specified
+// values formatted as YYYY-MM-DD. The season listing uses that format already,
+// but the episode details use "02 Jan 2006".
+func parseReleased(values ...string) string {
+	for _, value := range values {
+		for _, layout := range []string{"02 Jan 2006", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.Format("2006-01-02")
+			}
+		}
+	}
+
+	return ""
+}
+
+// parseRuntime returns the runtime in minutes from a value like "30 min".
+func parseRuntime(value string) int {
+	minutes, err := strconv.Atoi(strings.TrimSuffix(value, " min"))
+	if err != nil {
+		return 0
+	}
+
+	return minutes
+}
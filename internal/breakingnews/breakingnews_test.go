@@ -0,0 +1,206 @@
+package breakingnews
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+)
+
+const testAPIKey = "test-key"
+
+const testCatalogJSON = `{
+  "01": [
+    {"season": 1, "episode": 1, "title": "the-disappearance-of-helle-crafts", "url": "https://example.com/1"},
+    {"season": 1, "episode": 2, "title": "the-magic-bullet", "url": "https://example.com/2", "plot": "Old plot."}
+  ],
+  "03": [
+    {"season": 3, "episode": 2, "title": "knot-for-everyone", "url": "https://example.com/3"}
+  ],
+  "forensic-files-ii/01": [
+    {"series": "forensic-files-ii", "season": 1, "episode": 1, "title": "the-cold-case", "url": ""}
+  ]
+}`
+
+// fakeOMDb serves the season listings and episode details of the episodes in
+// the test catalog, keyed the same way as the real OMDb API.
+type fakeOMDb struct {
+	mutex sync.Mutex
+
+	// seasonRequests is the number of requests for each season, keyed by
+	// series title and season number.
+	seasonRequests map[string]int
+}
+
+var testSeasons = map[string][]*SeasonListing{
+	"Forensic Files/1": {
+		{Title: "The Disappearance of Helle Crafts", Released: "1996-04-23", Episode: "1", IMDbRating: "8.0", IMDbID: "tt0000101"},
+		{Title: "The Magic Bullet", Released: "1996-04-30", Episode: "2", IMDbRating: "7.5", IMDbID: "tt0000102"},
+	},
+	"Forensic Files II/1": {
+		{Title: "The Cold Case", Released: "2020-02-23", Episode: "1", IMDbRating: "N/A", IMDbID: "tt0000201"},
+	},
+}
+
+var testEpisodes = map[string]*EpisodeDetails{
+	"tt0000101": {Released: "23 Apr 1996", Runtime: "30 min", Plot: "A wood chipper.", IMDbRating: "8.2"},
+	"tt0000102": {Released: "N/A", Runtime: "N/A", Plot: "N/A", IMDbRating: "N/A"},
+	"tt0000201": {Released: "23 Feb 2020", Runtime: "45 min", Plot: "A cold case.", IMDbRating: "7.9"},
+}
+
+// newTestWorkspace returns a workspace in a temporary directory with the test
+// catalog and the specified OMDb API key, along with the function that removes
+// the directory.
+func newTestWorkspace(t *testing.T, apiKey string) (*whodunit.Workspace, func()) {
+	root, err := ioutil.TempDir("", "breakingnews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(root) }
+
+	if err := os.MkdirAll(filepath.Join(root, "assets"), os.ModePerm); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	config := crimeseen.NewConfig()
+	config.InvestigationsPath = filepath.Join(root, "investigations")
+	config.OMDb.APIKey = apiKey
+
+	ws, err := whodunit.NewWorkspace(root, config)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(ws.CatalogPath(), []byte(testCatalogJSON), 0644); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return ws, cleanup
+}
+
+func (f *fakeOMDb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("apikey") != testAPIKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(omdbResponse{Response: "False", Error: "No API key provided."})
+		return
+	}
+
+	var result interface{} = omdbResponse{Response: "False", Error: "Series or season not found!"}
+	switch {
+	case query.Get("t") != "":
+		key := query.Get("t") + "/" + query.Get("Season")
+		f.mutex.Lock()
+		f.seasonRequests[key]++
+		f.mutex.Unlock()
+
+		if episodes, ok := testSeasons[key]; ok {
+			result = map[string]interface{}{"Response": "True", "Episodes": episodes}
+		}
+
+	case query.Get("i") != "":
+		if details, ok := testEpisodes[query.Get("i")]; ok {
+			result = struct {
+				omdbResponse
+				*EpisodeDetails
+			}{omdbResponse{Response: "True"}, details}
+		}
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+func TestSync(t *testing.T) {
+	fake := &fakeOMDb{seasonRequests: make(map[string]int)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ws, cleanup := newTestWorkspace(t, testAPIKey)
+	defer cleanup()
+
+	r := NewReporter(ws, server.URL+"/")
+	err := r.Sync(context.Background(), whodunit.AllEpisodes(), 2)
+	if err == nil {
+		t.Error("expected an error for the episode that isn't on OMDb")
+	}
+
+	// The catalog is saved even though one of the episodes failed, so it's
+	// read back from the file to check what was written.
+	c, err := whodunit.LoadCatalog(ws.CatalogPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		imdbID  string
+		airDate string
+		plot    string
+		runtime int
+		rating  float64
+	}{
+		{"01-01-the-disappearance-of-helle-crafts", "tt0000101", "1996-04-23", "A wood chipper.", 30, 8.2},
+		{"01-02-the-magic-bullet", "tt0000102", "1996-04-30", "Old plot.", 0, 7.5},
+		{"03-02-knot-for-everyone", "", "", "", 0, 0},
+		{"forensic-files-ii-01-01-the-cold-case", "tt0000201", "2020-02-23", "A cold case.", 45, 7.9},
+	}
+
+	for _, test := range tests {
+		ep := c.EpisodeByName(test.name)
+		if ep == nil {
+			t.Errorf("episode %s is missing from the saved catalog", test.name)
+			continue
+		}
+
+		if ep.IMDbID != test.imdbID || ep.AirDate != test.airDate || ep.Plot != test.plot ||
+			ep.Runtime != test.runtime || ep.Rating != test.rating {
+			t.Errorf("got %s with %q, %q, %q, %d, %.1f, want %q, %q, %q, %d, %.1f",
+				test.name, ep.IMDbID, ep.AirDate, ep.Plot, ep.Runtime, ep.Rating,
+				test.imdbID, test.airDate, test.plot, test.runtime, test.rating)
+		}
+	}
+
+	wantRequests := map[string]int{"Forensic Files/1": 1, "Forensic Files/3": 1, "Forensic Files II/1": 1}
+	for key, want := range wantRequests {
+		if got := fake.seasonRequests[key]; got != want {
+			t.Errorf("got %d request(s) for season %s, want %d", got, key, want)
+		}
+	}
+}
+
+func TestSyncInvalidAPIKey(t *testing.T) {
+	server := httptest.NewServer(&fakeOMDb{seasonRequests: make(map[string]int)})
+	defer server.Close()
+
+	ws, cleanup := newTestWorkspace(t, "")
+	defer cleanup()
+
+	sel, err := whodunit.ParseSelection("s1e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewReporter(ws, server.URL+"/").Sync(context.Background(), sel, 1); err == nil {
+		t.Error("expected an error without an API key")
+	}
+
+	contents, err := ioutil.ReadFile(ws.CatalogPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != testCatalogJSON {
+		t.Error("catalog was saved even though nothing was synced")
+	}
+}
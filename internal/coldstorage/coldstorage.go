@@ -0,0 +1,265 @@
+// Package coldstorage provides the storage backends for the asset files. The
+// files can either live in a local directory (the investigations repo) or in
+// an S3-compatible object store.
+package coldstorage
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// ErrNotExist is returned when a file doesn't exist in the storage. It's the
+// same error as os.ErrNotExist, so os.IsNotExist works as expected.
+var ErrNotExist = os.ErrNotExist
+
+// Storage is implemented by every storage backend. Paths are slash-separated
+// and relative to the root of the storage (e.g. "videos/season-1/file.mp4").
+type Storage interface {
+	// Open opens the file at the specified path for reading.
+	Open(path string) (io.ReadCloser, error)
+
+	// Create creates (or replaces) the file at the specified path. The file
+	// isn't stored until the writer is closed, and the writer can be passed
+	// to Abort to discard what was written instead.
+	Create(path string) (io.WriteCloser, error)
+
+	// Stat returns the details of the file at the specified path.
+	Stat(path string) (*FileInfo, error)
+
+	// List returns the details of every file with a path that starts with
+	// the specified prefix.
+	List(prefix string) ([]*FileInfo, error)
+
+	// Remove removes the file at the specified path.
+	Remove(path string) error
+}
+
+// LocalStorage is implemented by storage backends that keep files on the
+// local filesystem, so external tools (e.g. ffmpeg) can use them directly.
+type LocalStorage interface {
+	Storage
+
+	// LocalPath returns the absolute path to the file on the filesystem.
+	LocalPath(path string) string
+}
+
+// Aborter is implemented by the writers returned from Create that can discard
+// what was written without storing it.
+type Aborter interface {
+	Abort() error
+}
+
+// Abort discards the contents written to the specified writer, so a failed
+// write never replaces the existing file. Writers that can't discard their
+// contents are closed instead.
+func Abort(w io.WriteCloser) error {
+	if a, ok := w.(Aborter); ok {
+		return a.Abort()
+	}
+
+	return w.Close()
+}
+
+// FileInfo contains the details of a file in the storage.
+type FileInfo struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Name returns the base name of the file.
+func (fi *FileInfo) Name() string {
+	return path.Base(fi.Path)
+}
+
+// Exists returns true if the file at the specified path exists in the storage.
+func Exists(s Storage, path string) bool {
+	_, err := s.Stat(path)
+	return err == nil
+}
+
+// ReadFile returns the contents of the file at the specified path.
+func ReadFile(s Storage, path string) ([]byte, error) {
+	r, err := s.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// WriteFile writes the specified contents to the file at the specified path.
+func WriteFile(s Storage, path string, contents []byte) error {
+	w, err := s.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(contents); err != nil {
+		Abort(w)
+		return err
+	}
+
+	return w.Close()
+}
+
+// Fetch returns a path on the local filesystem that contains the file at the
+// specified path, so it can be passed to external tools. If the storage isn't
+// local, the file is downloaded to a temporary file, which is removed when the
+// returned release function is called.
+func Fetch(s Storage, filePath string) (string, func(), error) {
+	if ls, ok := s.(LocalStorage); ok {
+		return ls.LocalPath(filePath), func() {}, nil
+	}
+
+	r, err := s.Open(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	temp, err := ioutil.TempFile("", "*-"+path.Base(filePath))
+	if err != nil {
+		return "", nil, err
+	}
+
+	release := func() {
+		os.Remove(temp.Name())
+	}
+
+	if _, err := io.Copy(temp, r); err != nil {
+		temp.Close()
+		release()
+		return "", nil, err
+	}
+
+	if err := temp.Close(); err != nil {
+		release()
+		return "", nil, err
+	}
+
+	return temp.Name(), release, nil
+}
+
+// Produce calls the specified function with a local path that an external tool
+// (e.g. youtube-dl) can write the file to. If the storage is local, the
+// function gets a temporary path next to the file, which is renamed into place
+// once the function returns successfully. Otherwise, the function gets a path
+// in a temporary directory and the file is uploaded to the specified path in
+// the storage once the function returns successfully.
+func Produce(s Storage, filePath string, produce func(localPath string) error) error {
+	if ls, ok := s.(LocalStorage); ok {
+		return produceLocal(ls.LocalPath(filePath), produce)
+	}
+
+	tempDir, err := ioutil.TempDir("", "coldstorage")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	localPath := filepath.Join(tempDir, path.Base(filePath))
+	if err := produce(localPath); err != nil {
+		return err
+	}
+
+	return upload(s, localPath, filePath)
+}
+
+// produceLocal calls the specified function with a temporary path in the
+// same directory as the local path and renames the file it wrote into place.
+// The temporary file is removed if the function fails.
+func produceLocal(localPath string, produce func(localPath string) error) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	tempPath, err := crimeseen.TempPath(localPath)
+	if err != nil {
+		return err
+	}
+
+	if err := produce(tempPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, localPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
+
+func upload(s Storage, localPath string, filePath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := s.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, file); err != nil {
+		Abort(w)
+		return err
+	}
+
+	return w.Close()
+}
+
+// IsNotExist returns true if the specified error indicates that a file
+// doesn't exist in the storage.
+func IsNotExist(err error) bool {
+	return errors.Is(err, ErrNotExist)
+}
+
+// cleanPath returns the specified path without any leading slashes or dot
+// segments, so it can't escape the root of the storage.
+func cleanPath(filePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+filePath), "/")
+}
+
+// Rename moves the file at the specified path to a new path. Storage backends
+// that aren't local don't support renaming, so the file is copied to the new
+// path and the original file is removed.
+func Rename(s Storage, oldPath string, newPath string) error {
+	if ls, ok := s.(LocalStorage); ok {
+		return os.Rename(ls.LocalPath(oldPath), ls.LocalPath(newPath))
+	}
+
+	r, err := s.Open(oldPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := s.Create(newPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		Abort(w)
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return s.Remove(oldPath)
+}
@@ -0,0 +1,125 @@
+package coldstorage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// Local is a storage backend that keeps files in a directory on the local
+// filesystem.
+type Local struct {
+	root string
+}
+
+// NewLocal returns a local storage backend rooted at the specified directory.
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// Root returns the absolute path to the root directory of the storage.
+func (l *Local) Root() string {
+	return l.root
+}
+
+// LocalPath returns the absolute path to the file on the filesystem.
+func (l *Local) LocalPath(path string) string {
+	return filepath.Join(l.root, filepath.FromSlash(cleanPath(path)))
+}
+
+// Open opens the file at the specified path for reading.
+func (l *Local) Open(path string) (io.ReadCloser, error) {
+	return os.Open(l.LocalPath(path))
+}
+
+// Create creates (or replaces) the file at the specified path, creating any
+// parent directories that don't exist. The contents are written to a
+// temporary file in the same directory, which is renamed into place when the
+// writer is closed.
+func (l *Local) Create(path string) (io.WriteCloser, error) {
+	localPath := l.LocalPath(path)
+	if err := os.MkdirAll(filepath.Dir(localPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	return crimeseen.CreateAtomic(localPath)
+}
+
+// Stat returns the details of the file at the specified path.
+func (l *Local) Stat(path string) (*FileInfo, error) {
+	info, err := os.Stat(l.LocalPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: ErrNotExist}
+	}
+
+	return &FileInfo{
+		Path:    cleanPath(path),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// List returns the details of every file with a path that starts with the
+// specified prefix, sorted by path.
+func (l *Local) List(prefix string) ([]*FileInfo, error) {
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	// Only walk the deepest directory that contains the prefix, so listing
+	// a single season doesn't walk the entire investigations directory.
+	walkDir := l.root
+	if index := strings.LastIndex(prefix, "/"); index != -1 {
+		walkDir = l.LocalPath(prefix[:index])
+	}
+
+	files := make([]*FileInfo, 0)
+	err := filepath.Walk(walkDir, func(localPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(l.root, localPath)
+		if err != nil {
+			return err
+		}
+
+		relPath = filepath.ToSlash(relPath)
+		if strings.HasPrefix(relPath, prefix) {
+			files = append(files, &FileInfo{
+				Path:    relPath,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// Remove removes the file at the specified path.
+func (l *Local) Remove(path string) error {
+	return os.Remove(l.LocalPath(path))
+}
@@ -0,0 +1,471 @@
+package coldstorage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config contains the settings used to connect to an S3-compatible object
+// store.
+type S3Config struct {
+	// Endpoint is the URL of the object store (e.g. "http://localhost:9000"
+	// for MinIO). If empty, AWS S3 is used for the region.
+	Endpoint string
+
+	// Region is the region of the bucket. Defaults to "us-east-1".
+	Region string
+
+	// Bucket is the name of the bucket that contains the files.
+	Bucket string
+
+	// Prefix is prepended to the path of every file, so the files can live
+	// in a "directory" of a shared bucket.
+	Prefix string
+
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Timeout is how long a single request can take, including the transfer
+	// of the file. Defaults to 30 minutes.
+	Timeout time.Duration
+}
+
+// S3 is a storage backend that keeps files in an S3-compatible object store.
+// Requests are signed with AWS Signature Version 4, so it works with AWS S3,
+// MinIO, DigitalOcean Spaces, Backblaze B2, etc.
+type S3 struct {
+	config   S3Config
+	baseURL  *url.URL
+	basePath string
+	client   *http.Client
+}
+
+const (
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3UnsignedBody  = "UNSIGNED-PAYLOAD"
+	s3DateFormat    = "20060102"
+	s3DateTimeFmt   = "20060102T150405Z"
+	s3DefaultRegion = "us-east-1"
+
+	s3DefaultTimeout = 30 * time.Minute
+
+	// s3ResponseTimeout is how long to wait for the response headers, so a
+	// server that stops responding fails long before the request timeout.
+	s3ResponseTimeout = time.Minute
+)
+
+// NewS3 returns an S3 storage backend for the specified config. Buckets on a
+// custom endpoint are addressed with path-style URLs, which every
+// S3-compatible store supports.
+func NewS3(config S3Config) (*S3, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("s3: access key ID and secret access key are required")
+	}
+
+	if config.Region == "" {
+		config.Region = s3DefaultRegion
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = s3DefaultTimeout
+	}
+
+	config.Prefix = strings.Trim(config.Prefix, "/")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = s3ResponseTimeout
+	s := &S3{
+		config: config,
+		client: &http.Client{Transport: transport, Timeout: config.Timeout},
+	}
+
+	if config.Endpoint == "" {
+		s.baseURL = &url.URL{
+			Scheme: "https",
+			Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", config.Bucket, config.Region),
+		}
+		s.basePath = "/"
+	} else {
+		endpoint, err := url.Parse(config.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("s3: invalid endpoint: %w", err)
+		}
+
+		if endpoint.Scheme == "" || endpoint.Host == "" {
+			return nil, fmt.Errorf("s3: invalid endpoint %q", config.Endpoint)
+		}
+
+		s.baseURL = &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host}
+		s.basePath = strings.TrimSuffix(endpoint.Path, "/") + "/" + config.Bucket + "/"
+	}
+
+	return s, nil
+}
+
+// Open opens the file at the specified path for reading.
+func (s *S3) Open(path string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.key(path), nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "open", path); err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Create returns a writer for the file at the specified path. The contents
+// are buffered to a temporary file and uploaded when the writer is closed.
+func (s *S3) Create(path string) (io.WriteCloser, error) {
+	temp, err := ioutil.TempFile("", "coldstorage-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Writer{s: s, path: path, temp: temp}, nil
+}
+
+// Stat returns the details of the file at the specified path.
+func (s *S3) Stat(path string) (*FileInfo, error) {
+	resp, err := s.do(http.MethodHead, s.key(path), nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, "stat", path); err != nil {
+		return nil, err
+	}
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &FileInfo{
+		Path:    cleanPath(path),
+		Size:    resp.ContentLength,
+		ModTime: modTime,
+	}, nil
+}
+
+// listBucketResult is the response body of a ListObjectsV2 request.
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the details of every file with a path that starts with the
+// specified prefix, sorted by path.
+func (s *S3) List(prefix string) ([]*FileInfo, error) {
+	files := make([]*FileInfo, 0)
+	keyPrefix := s.listPrefix(prefix)
+
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", keyPrefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(http.MethodGet, "", query, nil, -1)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkResponse(resp, "list", prefix); err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: error parsing list response: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			files = append(files, &FileInfo{
+				Path:    s.path(object.Key),
+				Size:    object.Size,
+				ModTime: object.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// Remove removes the file at the specified path. Unlike the local storage,
+// removing a file that doesn't exist isn't an error.
+func (s *S3) Remove(path string) error {
+	resp, err := s.do(http.MethodDelete, s.key(path), nil, nil, -1)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp, "remove", path)
+}
+
+// key returns the object key for the specified storage path.
+func (s *S3) key(path string) string {
+	path = cleanPath(path)
+	if s.config.Prefix == "" {
+		return path
+	}
+	if path == "" {
+		return s.config.Prefix
+	}
+	return s.config.Prefix + "/" + path
+}
+
+// listPrefix returns the object key prefix used to list the files with a path
+// that starts with the specified prefix. The configured prefix always ends
+// with a slash, so listing "p" doesn't include the files under "p2/". A
+// trailing slash on the specified prefix is kept, so only files in that
+// directory are listed.
+func (s *S3) listPrefix(prefix string) string {
+	keyPrefix := cleanPath(prefix)
+	if keyPrefix != "" && strings.HasSuffix(prefix, "/") {
+		keyPrefix += "/"
+	}
+
+	if s.config.Prefix == "" {
+		return keyPrefix
+	}
+	return s.config.Prefix + "/" + keyPrefix
+}
+
+// path returns the storage path for the specified object key.
+func (s *S3) path(key string) string {
+	if s.config.Prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, s.config.Prefix+"/")
+}
+
+// do sends a signed request for the specified object key. If contentLength is
+// negative, the request has no body.
+func (s *S3) do(
+	method string,
+	key string,
+	query url.Values,
+	body io.Reader,
+	contentLength int64,
+) (*http.Response, error) {
+	reqURL := *s.baseURL
+	reqURL.Path = s.basePath + key
+	reqURL.RawPath = uriEncode(s.basePath+key, false)
+	reqURL.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, reqURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentLength >= 0 {
+		req.ContentLength = contentLength
+	}
+
+	s.sign(req, time.Now().UTC(), s3UnsignedBody)
+
+	return s.client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 authorization headers to the request.
+// Requests are sent with UNSIGNED-PAYLOAD as the payload hash, so large files
+// can be streamed without reading them twice.
+func (s *S3) sign(req *http.Request, now time.Time, payloadHash string) {
+	dateTime := now.Format(s3DateTimeFmt)
+	date := now.Format(s3DateFormat)
+
+	req.Header.Set("X-Amz-Date", dateTime)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + dateTime + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.config.Region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		dateTime,
+		scope,
+		hashHex(canonicalRequest),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// s3Writer buffers the contents of a file to a temporary file and uploads it
+// when closed.
+type s3Writer struct {
+	s    *S3
+	path string
+	temp *os.File
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.temp.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	defer os.Remove(w.temp.Name())
+	defer w.temp.Close()
+
+	size, err := w.temp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.temp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	resp, err := w.s.do(http.MethodPut, w.s.key(w.path), nil, w.temp, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp, "create", w.path)
+}
+
+// Abort removes the temporary file without uploading it.
+func (w *s3Writer) Abort() error {
+	w.temp.Close()
+	return os.Remove(w.temp.Name())
+}
+
+// s3Error is the response body of a failed S3 request.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// checkResponse returns an error if the response status isn't successful.
+// Missing files return an error that matches ErrNotExist.
+func checkResponse(resp *http.Response, op string, path string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &os.PathError{Op: op, Path: path, Err: ErrNotExist}
+	}
+
+	var body s3Error
+	contents, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := xml.Unmarshal(contents, &body); err != nil || body.Code == "" {
+		body.Code = strconv.Itoa(resp.StatusCode)
+		body.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return &os.PathError{
+		Op:   op,
+		Path: path,
+		Err:  fmt.Errorf("s3: %s: %s", body.Code, body.Message),
+	}
+}
+
+// canonicalQuery returns the query string with the keys sorted and the keys
+// and values encoded as required by Signature Version 4.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode encodes every byte except the unreserved characters. Slashes are
+// only encoded if encodeSlash is true (i.e. for query values).
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func hashHex(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,133 @@
+package crimeseen
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// TempFileSuffix is appended to the names of the temporary files that are
+// written before being renamed into place. Files with this suffix that are
+// left behind (e.g. by a crash) are picked up by `alibi gc`.
+const TempFileSuffix = ".part"
+
+// AtomicFile is a file that's written to a temporary file in the same
+// directory and renamed to its final path when it's closed, so the file at
+// the final path is either the previous version or complete, never
+// half-written.
+type AtomicFile struct {
+	temp *os.File
+	path string
+	done bool
+}
+
+// CreateAtomic returns a new atomic file that replaces the file at the
+// specified path when it's closed. The parent directory needs to exist.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	temp, err := ioutil.TempFile(filepath.Dir(path),
+		filepath.Base(path)+".*"+TempFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Temporary files are only readable by the owner, but the files they
+	// replace are regular assets.
+	if err := temp.Chmod(0644); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, err
+	}
+
+	return &AtomicFile{temp: temp, path: path}, nil
+}
+
+// Write writes the specified bytes to the temporary file.
+func (af *AtomicFile) Write(p []byte) (int, error) {
+	if af.done {
+		return 0, os.ErrClosed
+	}
+
+	return af.temp.Write(p)
+}
+
+// Close flushes the contents of the temporary file to disk and renames it to
+// the final path. If anything fails, the temporary file is removed and the
+// file at the final path is left untouched.
+func (af *AtomicFile) Close() error {
+	if af.done {
+		return os.ErrClosed
+	}
+	af.done = true
+
+	err := af.temp.Sync()
+	if closeErr := af.temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(af.temp.Name(), af.path)
+	}
+	if err != nil {
+		os.Remove(af.temp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Abort closes and removes the temporary file without touching the file at
+// the final path. It does nothing if the file was already closed, so it can
+// be deferred.
+func (af *AtomicFile) Abort() error {
+	if af.done {
+		return nil
+	}
+	af.done = true
+
+	closeErr := af.temp.Close()
+	if err := os.Remove(af.temp.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return closeErr
+}
+
+// WriteFileAtomic writes the specified contents to a temporary file and
+// renames it to the specified path.
+func WriteFileAtomic(path string, contents []byte) error {
+	af, err := CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer af.Abort()
+
+	if _, err := af.Write(contents); err != nil {
+		return err
+	}
+
+	return af.Close()
+}
+
+// TempPath returns a path in the same directory as the specified path that
+// doesn't exist yet and keeps its extension, so external tools that pick the
+// output format from the extension (e.g. ffmpeg) can write to it before it's
+// renamed into place.
+func TempPath(path string) (string, error) {
+	ext := filepath.Ext(path)
+	name := filepath.Base(path)
+	name = name[:len(name)-len(ext)]
+
+	temp, err := ioutil.TempFile(filepath.Dir(path), name+TempFileSuffix+"-*"+ext)
+	if err != nil {
+		return "", err
+	}
+
+	// The tool gets a path that doesn't exist yet, because some of them
+	// (e.g. youtube-dl) skip files that are already there.
+	temp.Close()
+	if err := os.Remove(temp.Name()); err != nil {
+		return "", err
+	}
+
+	return temp.Name(), nil
+}
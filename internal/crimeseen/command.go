@@ -0,0 +1,282 @@
+package crimeseen
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDiskFull is the reason a command failed when it ran out of disk space.
+var ErrDiskFull = errors.New("no space left on device")
+
+// errStderrOutput is the underlying error of a command with FailOnStderr set
+// that exited normally but wrote to stderr.
+var errStderrOutput = errors.New("command wrote to stderr")
+
+// maxStderrSize is the number of bytes at the end of the stderr output of a
+// command that are kept for the error. Commands like ffmpeg can write a lot
+// to stderr, and the useful part is almost always at the end.
+const maxStderrSize = 16 * 1024
+
+// Command is an external command (e.g. ffmpeg) run with Run.
+type Command struct {
+	Name string
+	Args []string
+
+	// Timeout is how long the command can run before it's killed. If it's
+	// zero, the command runs until it exits or the context is canceled.
+	Timeout time.Duration
+
+	// Progress is called with every line the command writes to stdout, so
+	// commands that report their progress on stdout can be followed. The
+	// output is discarded if it's nil.
+	Progress func(line string)
+
+	// Stdout receives everything the command writes to stdout (in addition
+	// to Progress), for commands whose output is the result.
+	Stdout io.Writer
+
+	// FailOnStderr makes the command fail if it writes anything to stderr,
+	// even if it exits with status 0. This is for commands like ffprobe,
+	// which report problems with the input without failing.
+	FailOnStderr bool
+
+	// Signatures are checked against the stderr output if the command
+	// fails, so known failures can be returned as a specific error.
+	Signatures []Signature
+}
+
+// Signature maps a message that a command writes to stderr to the error that
+// is returned when the command fails with that message.
+type Signature struct {
+	// Pattern is matched against stderr without regard to case.
+	Pattern string
+
+	// Err is the error that CommandError unwraps to if the pattern matches.
+	Err error
+}
+
+// CommandError is returned from Run when a command fails.
+type CommandError struct {
+	Name string
+
+	// ExitCode is the exit code of the command or -1 if the command didn't
+	// exit on its own (e.g. it couldn't be started or was killed).
+	ExitCode int
+
+	// Stderr is the end of what the command wrote to stderr.
+	Stderr string
+
+	// TimedOut is true if the command was killed because it ran longer than
+	// the timeout.
+	TimedOut bool
+
+	// Reason is the error from the signature that matched stderr or nil if
+	// none did.
+	Reason error
+
+	// Err is the underlying error from running the command.
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	var msg string
+	switch {
+	case e.TimedOut:
+		msg = fmt.Sprintf("%s timed out", e.Name)
+	case e.ExitCode == 0:
+		msg = fmt.Sprintf("%s reported problems", e.Name)
+	case e.ExitCode >= 0:
+		msg = fmt.Sprintf("%s exited with status %d", e.Name, e.ExitCode)
+	default:
+		msg = fmt.Sprintf("%s failed: %v", e.Name, e.Err)
+	}
+
+	if e.Reason != nil {
+		msg += ": " + e.Reason.Error()
+	}
+
+	if line := lastLine(e.Stderr); line != "" {
+		msg += fmt.Sprintf(" (%s)", line)
+	}
+
+	return msg
+}
+
+// Unwrap returns the reason if a signature matched, so errors.Is can be used
+// to check for specific failures, otherwise the underlying error.
+func (e *CommandError) Unwrap() error {
+	if e.Reason != nil {
+		return e.Reason
+	}
+
+	return e.Err
+}
+
+// Run runs the command and waits for it to exit. The command is killed if the
+// specified context is canceled or the timeout elapses. If the command fails,
+// the returned error is a CommandError, unless the context was canceled, in
+// which case the context's error is returned. The command is started in its
+// own process group (where supported), so anything it starts (e.g. the ffmpeg
+// process youtube-dl uses to merge formats) is killed along with it.
+func (c *Command) Run(ctx context.Context) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	stderr := &tailBuffer{limit: maxStderrSize}
+	cmd := exec.Command(c.Name, c.Args...)
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+
+	var wg sync.WaitGroup
+	if c.Progress != nil {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return &CommandError{Name: c.Name, ExitCode: -1, Err: err}
+		}
+
+		var r io.Reader = stdout
+		if c.Stdout != nil {
+			r = io.TeeReader(stdout, c.Stdout)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scanLines(r, c.Progress)
+		}()
+	} else if c.Stdout != nil {
+		cmd.Stdout = c.Stdout
+	}
+
+	if err := cmd.Start(); err != nil {
+		return &CommandError{Name: c.Name, ExitCode: -1, Err: err}
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcess(cmd)
+		case <-exited:
+		}
+	}()
+
+	// The pipe has to be drained before Wait is called, since Wait closes it.
+	wg.Wait()
+	err := cmd.Wait()
+	close(exited)
+	if err == nil && c.FailOnStderr && strings.TrimSpace(stderr.String()) != "" {
+		err = errStderrOutput
+	}
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(ctxErr, context.DeadlineExceeded) {
+		return ctxErr
+	}
+
+	cmdErr := &CommandError{
+		Name:     c.Name,
+		ExitCode: -1,
+		Stderr:   stderr.String(),
+		TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Err:      err,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		cmdErr.ExitCode = exitErr.ExitCode()
+	} else if err == errStderrOutput {
+		cmdErr.ExitCode = 0
+	}
+
+	cmdErr.Reason = c.match(cmdErr.Stderr)
+	return cmdErr
+}
+
+// match returns the error of the first signature found in the specified
+// stderr output or nil if none match.
+func (c *Command) match(stderr string) error {
+	stderr = strings.ToLower(stderr)
+	for _, sig := range c.Signatures {
+		if strings.Contains(stderr, strings.ToLower(sig.Pattern)) {
+			return sig.Err
+		}
+	}
+
+	return nil
+}
+
+// scanLines calls the specified function with every line read from the
+// specified reader. Carriage returns are treated as line breaks, since tools
+// use them to redraw progress bars.
+func scanLines(r io.Reader, onLine func(line string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) != 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			onLine(line)
+		}
+	}
+
+	// Keep draining if a line was too long for the scanner, so the command
+	// doesn't block on a full pipe.
+	io.Copy(ioutil.Discard, r)
+}
+
+// tailBuffer is a writer that only keeps the last bytes written to it.
+type tailBuffer struct {
+	limit int
+	mutex sync.Mutex
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.limit {
+		b.buf = b.buf[len(b.buf)-b.limit:]
+	}
+
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return string(b.buf)
+}
+
+func lastLine(value string) string {
+	value = strings.TrimSpace(value)
+	if index := strings.LastIndexAny(value, "\r\n"); index != -1 {
+		return strings.TrimSpace(value[index+1:])
+	}
+
+	return value
+}
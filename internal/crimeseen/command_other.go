@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package crimeseen
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcess kills the command. Processes it started aren't killed, since
+// process groups aren't supported on this platform.
+func killProcess(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
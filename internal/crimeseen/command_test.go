@@ -0,0 +1,203 @@
+package crimeseen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helperCommand returns a command that runs the test binary as a helper
+// process, which behaves as specified by the mode and arguments (see
+// TestHelperProcess).
+func helperCommand(mode string, args ...string) *Command {
+	return &Command{
+		Name: os.Args[0],
+		Args: append([]string{"-test.run=TestHelperProcess", "--", mode}, args...),
+	}
+}
+
+// TestHelperProcess isn't a real test, it's the process started by
+// helperCommand. It only does something if it's started with the arguments
+// after "--".
+func TestHelperProcess(t *testing.T) {
+	args := os.Args
+	for len(args) != 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return
+	}
+
+	switch mode, args := args[1], args[2:]; mode {
+	case "exit":
+		// exit CODE MESSAGE writes the message to stderr and exits.
+		code, _ := strconv.Atoi(args[0])
+		fmt.Fprintln(os.Stderr, args[1])
+		os.Exit(code)
+
+	case "stdout":
+		// stdout writes a progress bar and a line to stdout.
+		fmt.Print("10%\r50%\r100%\ndone\n")
+		os.Exit(0)
+
+	case "stderr":
+		// stderr SIZE writes SIZE bytes to stderr followed by a last line.
+		size, _ := strconv.Atoi(args[0])
+		fmt.Fprint(os.Stderr, strings.Repeat("x", size))
+		fmt.Fprint(os.Stderr, "\nlast line\n")
+		os.Exit(1)
+
+	case "sleep":
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+
+	os.Exit(2)
+}
+
+func TestCommandRun(t *testing.T) {
+	var lines []string
+	cmd := helperCommand("stdout")
+	cmd.Progress = func(line string) {
+		lines = append(lines, line)
+	}
+
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if want := []string{"10%", "50%", "100%", "done"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got progress %q, want %q", lines, want)
+	}
+}
+
+func TestCommandRunStdout(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := helperCommand("stdout")
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if want := "10%\r50%\r100%\ndone\n"; stdout.String() != want {
+		t.Errorf("got stdout %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestCommandRunFailOnStderr(t *testing.T) {
+	if err := helperCommand("exit", "0", "warning").Run(context.Background()); err != nil {
+		t.Errorf("got error %q without FailOnStderr", err)
+	}
+
+	cmd := helperCommand("exit", "0", "Invalid data found when processing input")
+	cmd.FailOnStderr = true
+
+	var cmdErr *CommandError
+	if err := cmd.Run(context.Background()); !errors.As(err, &cmdErr) || cmdErr.ExitCode != 0 {
+		t.Fatalf("got error %v, want a CommandError with exit code 0", err)
+	}
+
+	want := "reported problems (Invalid data found when processing input)"
+	if !strings.HasSuffix(cmdErr.Error(), want) {
+		t.Errorf("got error %q, want it to end with %q", cmdErr, want)
+	}
+}
+
+func TestCommandRunExitCode(t *testing.T) {
+	errCorrupt := errors.New("corrupt")
+
+	cmd := helperCommand("exit", "3", "Error: The File Is Corrupt")
+	cmd.Signatures = []Signature{
+		{Pattern: "no space left", Err: ErrDiskFull},
+		{Pattern: "file is corrupt", Err: errCorrupt},
+	}
+
+	err := fmt.Errorf("error processing: %w", cmd.Run(context.Background()))
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("got error %v, want a CommandError", err)
+	}
+
+	if cmdErr.ExitCode != 3 || cmdErr.TimedOut {
+		t.Errorf("got exit code %d and timed out %t, want 3 and false",
+			cmdErr.ExitCode, cmdErr.TimedOut)
+	}
+
+	if !errors.Is(err, errCorrupt) || errors.Is(err, ErrDiskFull) {
+		t.Errorf("got reason %v, want %v", cmdErr.Reason, errCorrupt)
+	}
+
+	want := "exited with status 3: corrupt (Error: The File Is Corrupt)"
+	if !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("got error %q, want it to end with %q", err, want)
+	}
+
+	cmd = helperCommand("exit", "1", "something else")
+	cmd.Signatures = []Signature{{Pattern: "file is corrupt", Err: errCorrupt}}
+	if err := cmd.Run(context.Background()); !errors.As(err, &cmdErr) || cmdErr.Reason != nil {
+		t.Errorf("got error %v, want a CommandError without a reason", err)
+	}
+}
+
+func TestCommandRunTimeout(t *testing.T) {
+	cmd := helperCommand("sleep")
+	cmd.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := cmd.Run(context.Background())
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || !cmdErr.TimedOut || cmdErr.ExitCode != -1 {
+		t.Errorf("got error %v, want a timed out CommandError", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("command ran for %s after it timed out", elapsed)
+	}
+}
+
+func TestCommandRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	cmd := helperCommand("sleep")
+	cmd.Timeout = time.Minute
+	if err := cmd.Run(ctx); err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCommandRunStderrLimit(t *testing.T) {
+	err := helperCommand("stderr", strconv.Itoa(2*maxStderrSize)).Run(context.Background())
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("got error %v, want a CommandError", err)
+	}
+
+	if len(cmdErr.Stderr) != maxStderrSize {
+		t.Errorf("got %d bytes of stderr, want %d", len(cmdErr.Stderr), maxStderrSize)
+	}
+
+	if !strings.HasSuffix(cmdErr.Error(), "(last line)") {
+		t.Errorf("got error %q, want the last line of stderr", cmdErr)
+	}
+}
+
+func TestCommandRunNotFound(t *testing.T) {
+	cmd := &Command{Name: "alibi-command-that-does-not-exist"}
+
+	var cmdErr *CommandError
+	if err := cmd.Run(context.Background()); !errors.As(err, &cmdErr) || cmdErr.ExitCode != -1 {
+		t.Errorf("got error %v, want a CommandError without an exit code", err)
+	}
+}
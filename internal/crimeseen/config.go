@@ -0,0 +1,742 @@
+package crimeseen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexsasharegan/dotenv"
+)
+
+// DefaultConfigPath is the config file that is loaded from the config
+// directory if no other path is specified and ALIBI_CONFIG isn't set.
+const DefaultConfigPath = "alibi.json"
+
+// DotEnvPath is the path to the `.env` file that is loaded from the config
+// directory. Values that are set in the environment take precedence.
+const DotEnvPath = ".env"
+
+// Config contains the settings used by the tools. The settings are loaded in
+// layers: the defaults, then a JSON config file, then environment variables
+// (including the `.env` file), then the overrides specified as CLI flags.
+type Config struct {
+	InvestigationsPath string
+	AssetStorage       string
+	GitAutoCommit      bool
+	Callback           CallbackConfig
+	SpeechToText       SpeechToTextConfig
+	NaturalLanguage    NaturalLanguageConfig
+	GCP                GCPConfig
+	OMDb               OMDbConfig
+	S3                 S3Config
+	Transcript         TranscriptConfig
+	FFmpeg             FFmpegConfig
+	YouTubeDL          YouTubeDLConfig
+	Log                LogConfig
+
+	path    string
+	dir     string
+	sources map[string]Source
+}
+
+// CallbackConfig contains the settings for the callback server that receives
+// the recognition results.
+type CallbackConfig struct {
+	URL  string
+	Port int
+}
+
+// SpeechToTextConfig contains the settings for the IBM speech-to-text service.
+type SpeechToTextConfig struct {
+	APIKey  string
+	URL     string
+	Timeout time.Duration
+}
+
+// NaturalLanguageConfig contains the settings for the IBM natural language
+// understanding service.
+type NaturalLanguageConfig struct {
+	APIKey        string
+	URL           string
+	EntityLimit   int
+	CategoryLimit int
+}
+
+// GCPConfig contains the settings for the GCP Natural Language API.
+type GCPConfig struct {
+	CredentialsPath string
+}
+
+// OMDbConfig contains the settings for the OMDb API.
+type OMDbConfig struct {
+	APIKey  string
+	URL     string
+	Timeout time.Duration
+}
+
+// S3Config contains the settings for the S3-compatible object store used when
+// the asset storage is "s3".
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Timeout         time.Duration
+}
+
+// TranscriptConfig contains the settings used to build transcripts from the
+// recognitions.
+type TranscriptConfig struct {
+	MinConfidence float64
+}
+
+// FFmpegConfig contains the settings used to run ffmpeg and ffprobe.
+type FFmpegConfig struct {
+	Timeout time.Duration
+}
+
+// YouTubeDLConfig contains the settings used to run youtube-dl.
+type YouTubeDLConfig struct {
+	Timeout time.Duration
+}
+
+// LogConfig contains the settings for the log files, which are written in
+// addition to the terminal output.
+type LogConfig struct {
+	// Dir is the directory the log files are written to. A relative path is
+	// relative to the workspace.
+	Dir string
+
+	// Level is the minimum level of the entries written to the log files
+	// (e.g. "debug").
+	Level string
+
+	// Format is the format of the entries in the log files, either "text" or
+	// "json".
+	Format string
+
+	// Files is "per-service" to write a file for each package, "combined" to
+	// write every package to the same file, or "off" to not write any.
+	Files string
+
+	// MaxSize is the size in megabytes a log file can reach before it's
+	// rotated.
+	MaxSize int
+
+	// MaxAge is the number of days rotated log files are kept. If it's 0,
+	// they're kept forever.
+	MaxAge int
+
+	// Compress gzips the rotated log files.
+	Compress bool
+}
+
+// logLevels are the valid values of the `log.level` setting.
+var logLevels = []string{"panic", "fatal", "error", "warn", "warning", "info", "debug", "trace"}
+
+// Source identifies the layer a setting was loaded from.
+type Source string
+
+const (
+	// SourceDefault is the built-in default value.
+	SourceDefault Source = "default"
+
+	// SourceFile is the JSON config file.
+	SourceFile Source = "file"
+
+	// SourceDotEnv is the `.env` file.
+	SourceDotEnv Source = ".env"
+
+	// SourceEnv is an environment variable.
+	SourceEnv Source = "env"
+
+	// SourceFlag is a CLI flag.
+	SourceFlag Source = "flag"
+)
+
+// Setting is a single setting in the config, which can be set by key in the
+// config file or CLI flags and by name in the environment.
+type Setting struct {
+	// Key is the key of the setting in the config file, where the part before
+	// a dot is the name of a nested object (e.g. "callback.port").
+	Key string
+
+	// EnvVar is the name of the environment variable for the setting.
+	EnvVar string
+
+	// Secret indicates that the value is redacted when it's shown.
+	Secret bool
+
+	value interface{}
+}
+
+// ConfigOptions contains the options used to load the config.
+type ConfigOptions struct {
+	// Path is the path to the config file. If empty, ALIBI_CONFIG is used
+	// (from the environment, then the `.env` file), followed by
+	// DefaultConfigPath if it exists.
+	Path string
+
+	// Dir is the directory that DefaultConfigPath and DotEnvPath are loaded
+	// from. If empty, the working directory is used.
+	Dir string
+
+	// Overrides are settings in the form "key=value" (e.g.
+	// "callback.port=9001") that take precedence over every other layer.
+	Overrides []string
+}
+
+// ConfigError is returned when the config has invalid values.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config: %s", strings.Join(e.Problems, "; "))
+}
+
+// NewConfig returns a config that only contains the default values.
+func NewConfig() *Config {
+	c := &Config{
+		AssetStorage: "local",
+		Callback: CallbackConfig{
+			Port: 9000,
+		},
+		SpeechToText: SpeechToTextConfig{
+			// The default timeout of the SDK is 30 seconds, which isn't always
+			// long enough for the files to go through.
+			Timeout: 90 * time.Second,
+		},
+		NaturalLanguage: NaturalLanguageConfig{
+			EntityLimit:   10000,
+			CategoryLimit: 100,
+		},
+		OMDb: OMDbConfig{
+			Timeout: 30 * time.Second,
+		},
+		S3: S3Config{
+			Timeout: 30 * time.Minute,
+		},
+		Transcript: TranscriptConfig{
+			MinConfidence: 0.7,
+		},
+		// The timeouts are generous, they're only there so a hung process
+		// doesn't block a batch forever.
+		FFmpeg: FFmpegConfig{
+			Timeout: time.Hour,
+		},
+		YouTubeDL: YouTubeDLConfig{
+			Timeout: 2 * time.Hour,
+		},
+		Log: LogConfig{
+			Dir:     "logs",
+			Level:   "info",
+			Format:  "text",
+			Files:   "per-service",
+			MaxSize: 100,
+			MaxAge:  60,
+		},
+		sources: make(map[string]Source),
+	}
+
+	for _, s := range c.Settings() {
+		c.sources[s.Key] = SourceDefault
+	}
+
+	return c
+}
+
+// LoadConfig returns the config loaded from the defaults, the config file,
+// the environment, and the overrides in the specified options. It returns an
+// error if a file can't be read or a value can't be parsed, but the values
+// aren't validated, so call Validate before using the config.
+func LoadConfig(opts ConfigOptions) (*Config, error) {
+	c := NewConfig()
+	c.dir = opts.Dir
+
+	// The `.env` file is read first, since it can specify the config file.
+	dotEnv, err := c.readDotEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	path, required := opts.Path, true
+	if path == "" {
+		path = os.Getenv("ALIBI_CONFIG")
+	}
+	if path == "" {
+		// A relative path in the `.env` file is relative to the file.
+		path = dotEnv["ALIBI_CONFIG"]
+		if path != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(opts.Dir, path)
+		}
+	}
+	if path == "" {
+		path, required = filepath.Join(opts.Dir, DefaultConfigPath), false
+	}
+
+	if required || FileExists(path) {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.loadEnv(dotEnv); err != nil {
+		return nil, err
+	}
+
+	for _, override := range opts.Overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid setting %q, expected key=value", override)
+		}
+
+		if err := c.set(strings.TrimSpace(parts[0]), parts[1], SourceFlag); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Path returns the path to the config file that was loaded or an empty string
+// if there wasn't one.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Dir returns the directory that the default config file and the `.env` file
+// are loaded from or an empty string if it's the working directory.
+func (c *Config) Dir() string {
+	return c.dir
+}
+
+// DotEnvPath returns the path to the `.env` file that is loaded.
+func (c *Config) DotEnvPath() string {
+	return filepath.Join(c.dir, DotEnvPath)
+}
+
+// Source returns the layer the setting with the specified key was loaded from.
+func (c *Config) Source(key string) Source {
+	return c.sources[key]
+}
+
+// Settings returns every setting in the config in the order they're shown.
+func (c *Config) Settings() []*Setting {
+	return []*Setting{
+		{Key: "investigationsPath", EnvVar: "INVESTIGATIONS_PATH", value: &c.InvestigationsPath},
+		{Key: "assetStorage", EnvVar: "ASSET_STORAGE", value: &c.AssetStorage},
+		{Key: "gitAutoCommit", EnvVar: "GIT_AUTO_COMMIT", value: &c.GitAutoCommit},
+		{Key: "callback.url", EnvVar: "CALLBACK_URL", value: &c.Callback.URL},
+		{Key: "callback.port", EnvVar: "CALLBACK_PORT", value: &c.Callback.Port},
+		{Key: "speechToText.apiKey", EnvVar: "IBM_STT_API_KEY", Secret: true, value: &c.SpeechToText.APIKey},
+		{Key: "speechToText.url", EnvVar: "IBM_STT_URL", value: &c.SpeechToText.URL},
+		{Key: "speechToText.timeout", EnvVar: "IBM_STT_TIMEOUT", value: &c.SpeechToText.Timeout},
+		{Key: "naturalLanguage.apiKey", EnvVar: "IBM_NLU_API_KEY", Secret: true, value: &c.NaturalLanguage.APIKey},
+		{Key: "naturalLanguage.url", EnvVar: "IBM_NLU_URL", value: &c.NaturalLanguage.URL},
+		{Key: "naturalLanguage.entityLimit", EnvVar: "IBM_NLU_ENTITY_LIMIT", value: &c.NaturalLanguage.EntityLimit},
+		{Key: "naturalLanguage.categoryLimit", EnvVar: "IBM_NLU_CATEGORY_LIMIT", value: &c.NaturalLanguage.CategoryLimit},
+		{Key: "gcp.credentialsPath", EnvVar: "GOOGLE_APPLICATION_CREDENTIALS", value: &c.GCP.CredentialsPath},
+		{Key: "omdb.apiKey", EnvVar: "OMDB_API_KEY", Secret: true, value: &c.OMDb.APIKey},
+		{Key: "omdb.url", EnvVar: "OMDB_URL", value: &c.OMDb.URL},
+		{Key: "omdb.timeout", EnvVar: "OMDB_TIMEOUT", value: &c.OMDb.Timeout},
+		{Key: "s3.endpoint", EnvVar: "S3_ENDPOINT", value: &c.S3.Endpoint},
+		{Key: "s3.region", EnvVar: "S3_REGION", value: &c.S3.Region},
+		{Key: "s3.bucket", EnvVar: "S3_BUCKET", value: &c.S3.Bucket},
+		{Key: "s3.prefix", EnvVar: "S3_PREFIX", value: &c.S3.Prefix},
+		{Key: "s3.accessKeyId", EnvVar: "S3_ACCESS_KEY_ID", Secret: true, value: &c.S3.AccessKeyID},
+		{Key: "s3.secretAccessKey", EnvVar: "S3_SECRET_ACCESS_KEY", Secret: true, value: &c.S3.SecretAccessKey},
+		{Key: "s3.timeout", EnvVar: "S3_TIMEOUT", value: &c.S3.Timeout},
+		{Key: "transcript.minConfidence", EnvVar: "TRANSCRIPT_MIN_CONFIDENCE", value: &c.Transcript.MinConfidence},
+		{Key: "ffmpeg.timeout", EnvVar: "FFMPEG_TIMEOUT", value: &c.FFmpeg.Timeout},
+		{Key: "youtubeDL.timeout", EnvVar: "YOUTUBE_DL_TIMEOUT", value: &c.YouTubeDL.Timeout},
+		{Key: "log.dir", EnvVar: "LOG_DIR", value: &c.Log.Dir},
+		{Key: "log.level", EnvVar: "LOG_LEVEL", value: &c.Log.Level},
+		{Key: "log.format", EnvVar: "LOG_FORMAT", value: &c.Log.Format},
+		{Key: "log.files", EnvVar: "LOG_FILES", value: &c.Log.Files},
+		{Key: "log.maxSize", EnvVar: "LOG_MAX_SIZE", value: &c.Log.MaxSize},
+		{Key: "log.maxAge", EnvVar: "LOG_MAX_AGE", value: &c.Log.MaxAge},
+		{Key: "log.compress", EnvVar: "LOG_COMPRESS", value: &c.Log.Compress},
+	}
+}
+
+// Validate returns a ConfigError listing every invalid value in the config or
+// nil if the config is valid.
+func (c *Config) Validate() error {
+	problems := make([]string, 0)
+	addProblem := func(key string, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s (from %s) %s",
+			key, c.sources[key], fmt.Sprintf(format, args...)))
+	}
+
+	// The ledger, manifests, and locks are kept in the investigations
+	// directory even if the assets are stored in S3.
+	if c.InvestigationsPath == "" {
+		addProblem("investigationsPath", "is required")
+	}
+
+	switch strings.ToLower(c.AssetStorage) {
+	case "local":
+	case "s3":
+		if c.S3.Bucket == "" {
+			addProblem("s3.bucket", "is required when assetStorage is s3")
+		}
+		if c.S3.Timeout <= 0 {
+			addProblem("s3.timeout", "must be greater than 0")
+		}
+	default:
+		addProblem("assetStorage", "must be local or s3, not %q", c.AssetStorage)
+	}
+
+	if c.Callback.Port < 1 || c.Callback.Port > 65535 {
+		addProblem("callback.port", "must be between 1 and 65535, not %d", c.Callback.Port)
+	}
+
+	if c.SpeechToText.Timeout <= 0 {
+		addProblem("speechToText.timeout", "must be greater than 0")
+	}
+
+	if c.OMDb.Timeout <= 0 {
+		addProblem("omdb.timeout", "must be greater than 0")
+	}
+
+	if c.FFmpeg.Timeout <= 0 {
+		addProblem("ffmpeg.timeout", "must be greater than 0")
+	}
+
+	if c.YouTubeDL.Timeout <= 0 {
+		addProblem("youtubeDL.timeout", "must be greater than 0")
+	}
+
+	if c.NaturalLanguage.EntityLimit < 1 {
+		addProblem("naturalLanguage.entityLimit", "must be at least 1")
+	}
+
+	if c.NaturalLanguage.CategoryLimit < 1 {
+		addProblem("naturalLanguage.categoryLimit", "must be at least 1")
+	}
+
+	if c.Transcript.MinConfidence < 0 || c.Transcript.MinConfidence > 1 {
+		addProblem("transcript.minConfidence", "must be between 0 and 1, not %g",
+			c.Transcript.MinConfidence)
+	}
+
+	validLevel := false
+	for _, level := range logLevels {
+		if strings.EqualFold(c.Log.Level, level) {
+			validLevel = true
+		}
+	}
+	if !validLevel {
+		addProblem("log.level", "must be one of %s, not %q",
+			strings.Join(logLevels, ", "), c.Log.Level)
+	}
+
+	switch strings.ToLower(c.Log.Format) {
+	case "text", "json":
+	default:
+		addProblem("log.format", "must be text or json, not %q", c.Log.Format)
+	}
+
+	switch strings.ToLower(c.Log.Files) {
+	case "per-service", "combined":
+		if c.Log.Dir == "" {
+			addProblem("log.dir", "is required when log.files is %s", c.Log.Files)
+		}
+	case "off":
+	default:
+		addProblem("log.files", "must be per-service, combined, or off, not %q", c.Log.Files)
+	}
+
+	if c.Log.MaxSize < 1 {
+		addProblem("log.maxSize", "must be at least 1")
+	}
+
+	if c.Log.MaxAge < 0 {
+		addProblem("log.maxAge", "must be 0 or greater")
+	}
+
+	urls := map[string]string{
+		"callback.url":        c.Callback.URL,
+		"speechToText.url":    c.SpeechToText.URL,
+		"naturalLanguage.url": c.NaturalLanguage.URL,
+		"omdb.url":            c.OMDb.URL,
+		"s3.endpoint":         c.S3.Endpoint,
+	}
+	keys := make([]string, 0, len(urls))
+	for key := range urls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if urls[key] == "" {
+			continue
+		}
+
+		u, err := url.Parse(urls[key])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			addProblem(key, "must be an http or https URL, not %q", urls[key])
+		}
+	}
+
+	if len(problems) != 0 {
+		return &ConfigError{Problems: problems}
+	}
+
+	return nil
+}
+
+// loadFile sets the values in the JSON config file at the specified path.
+// Settings with a dot in the key are nested objects in the file (e.g.
+// {"callback": {"port": 9001}}).
+func (c *Config) loadFile(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.UseNumber()
+
+	var values map[string]interface{}
+	if err := dec.Decode(&values); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	flattened := make(map[string]interface{})
+	flatten("", values, flattened)
+
+	keys := make([]string, 0, len(flattened))
+	for key := range flattened {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := flattened[key]
+		switch value.(type) {
+		case string, json.Number, bool:
+		case nil:
+			continue
+		default:
+			return fmt.Errorf("invalid value for %s in %s", key, path)
+		}
+
+		if err := c.set(key, fmt.Sprint(value), SourceFile); err != nil {
+			return fmt.Errorf("%w in %s", err, path)
+		}
+	}
+
+	c.path = path
+	return nil
+}
+
+// flatten adds the values in the specified object to the specified map with
+// the keys of nested objects joined by dots.
+func flatten(prefix string, values map[string]interface{}, flattened map[string]interface{}) {
+	for key, value := range values {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(prefix+key+".", nested, flattened)
+			continue
+		}
+
+		flattened[prefix+key] = value
+	}
+}
+
+// readDotEnv returns the values in the `.env` file or an empty map if there
+// isn't one.
+func (c *Config) readDotEnv() (map[string]string, error) {
+	dotEnvPath := c.DotEnvPath()
+	if !FileExists(dotEnvPath) {
+		return make(map[string]string), nil
+	}
+
+	values, err := dotenv.ReadFile(dotEnvPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", dotEnvPath, err)
+	}
+
+	return values, nil
+}
+
+// loadEnv sets the values from the environment variables and the specified
+// values from the `.env` file. Empty values are ignored, so the placeholders
+// copied from `.env.example` don't clear values from the config file.
+func (c *Config) loadEnv(dotEnv map[string]string) error {
+	for _, s := range c.Settings() {
+		value, source := os.Getenv(s.EnvVar), SourceEnv
+		if value == "" {
+			value, source = dotEnv[s.EnvVar], SourceDotEnv
+		}
+
+		if value == "" {
+			continue
+		}
+
+		if err := c.set(s.Key, value, source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// set parses the specified value and assigns it to the setting with the
+// specified key or environment variable name.
+func (c *Config) set(key string, value string, source Source) error {
+	for _, s := range c.Settings() {
+		if s.Key != key && s.EnvVar != key {
+			continue
+		}
+
+		if err := s.set(value); err != nil {
+			return fmt.Errorf("invalid value %q for %s (from %s): %w", value, s.Key, source, err)
+		}
+
+		c.sources[s.Key] = source
+		return nil
+	}
+
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// RedactArgs returns a copy of the command line arguments with the values of
+// secret settings passed with `--set KEY=VALUE` redacted the same way as
+// DisplayValue, so the command can be recorded without leaking credentials.
+func (c *Config) RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 0; i < len(redacted); i++ {
+		switch arg := redacted[i]; {
+		case arg == "--set" && i+1 < len(redacted):
+			i++
+			redacted[i] = c.redactOverride(redacted[i])
+
+		case strings.HasPrefix(arg, "--set="):
+			redacted[i] = "--set=" + c.redactOverride(strings.TrimPrefix(arg, "--set="))
+		}
+	}
+
+	return redacted
+}
+
+// redactOverride redacts the value of the "key=value" override if the key is
+// a secret setting.
+func (c *Config) redactOverride(override string) string {
+	parts := strings.SplitN(override, "=", 2)
+	if len(parts) != 2 {
+		return override
+	}
+
+	key := strings.TrimSpace(parts[0])
+	for _, s := range c.Settings() {
+		if (s.Key == key || s.EnvVar == key) && s.Secret {
+			return parts[0] + "=" + redact(parts[1])
+		}
+	}
+
+	return override
+}
+
+// Value returns the value of the setting formatted as a string.
+func (s *Setting) Value() string {
+	switch v := s.value.(type) {
+	case *string:
+		return *v
+	case *int:
+		return strconv.Itoa(*v)
+	case *float64:
+		return strconv.FormatFloat(*v, 'g', -1, 64)
+	case *bool:
+		return strconv.FormatBool(*v)
+	case *time.Duration:
+		return v.String()
+	}
+
+	return ""
+}
+
+// DisplayValue returns the value of the setting formatted as a string with
+// secrets redacted.
+func (s *Setting) DisplayValue() string {
+	value := s.Value()
+	if !s.Secret {
+		return value
+	}
+
+	return redact(value)
+}
+
+// redact returns the secret value masked so only the last few characters are
+// shown, which is enough to tell keys apart.
+func redact(value string) string {
+	if value == "" {
+		return value
+	}
+
+	if len(value) <= 8 {
+		return "********"
+	}
+
+	return "********" + value[len(value)-4:]
+}
+
+func (s *Setting) set(value string) error {
+	value = strings.TrimSpace(value)
+	switch v := s.value.(type) {
+	case *string:
+		*v = value
+
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a whole number")
+		}
+		*v = n
+
+	case *float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		*v = n
+
+	case *bool:
+		switch strings.ToLower(value) {
+		case "1", "true", "yes", "on":
+			*v = true
+		case "", "0", "false", "no", "off":
+			*v = false
+		default:
+			return fmt.Errorf("expected true or false")
+		}
+
+	case *time.Duration:
+		// Plain numbers are treated as seconds.
+		if seconds, err := strconv.Atoi(value); err == nil {
+			*v = time.Duration(seconds) * time.Second
+			return nil
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration (e.g. 90s)")
+		}
+		*v = d
+	}
+
+	return nil
+}
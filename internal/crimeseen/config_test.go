@@ -0,0 +1,510 @@
+package crimeseen
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestConfigDir returns a temporary config directory containing the
+// specified files along with the function that removes it. The environment
+// variables of every setting are cleared until the function is called, so
+// the environment the tests run in doesn't leak into the config.
+func newTestConfigDir(t *testing.T, files map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "crimeseen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envVars := []string{"ALIBI_CONFIG"}
+	for _, s := range NewConfig().Settings() {
+		envVars = append(envVars, s.EnvVar)
+	}
+
+	restoreEnv := make(map[string]string)
+	for _, name := range envVars {
+		if value, ok := os.LookupEnv(name); ok {
+			restoreEnv[name] = value
+		}
+		os.Unsetenv(name)
+	}
+
+	cleanup := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+		for name, value := range restoreEnv {
+			os.Setenv(name, value)
+		}
+		os.RemoveAll(dir)
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+
+	return dir, cleanup
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir, cleanup := newTestConfigDir(t, map[string]string{
+		DefaultConfigPath: `{
+  "log": {"level": "debug"},
+  "speechToText": {"url": "https://file.example.com"},
+  "omdb": {"url": "https://file.example.com"},
+  "callback": {"port": 9001}
+}`,
+		DotEnvPath: strings.Join([]string{
+			"IBM_STT_URL=https://dotenv.example.com",
+			"OMDB_URL=https://dotenv.example.com",
+			"CALLBACK_PORT=9002",
+			"IBM_NLU_URL=",
+		}, "\n"),
+	})
+	defer cleanup()
+
+	os.Setenv("OMDB_URL", "https://env.example.com")
+	os.Setenv("CALLBACK_PORT", "9003")
+
+	c, err := LoadConfig(ConfigOptions{
+		Dir:       dir,
+		Overrides: []string{"callback.port=9004"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := filepath.Join(dir, DefaultConfigPath); c.Path() != want {
+		t.Errorf("got config path %q, want %q", c.Path(), want)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		source Source
+	}{
+		{"naturalLanguage.entityLimit", "10000", SourceDefault},
+		{"naturalLanguage.url", "", SourceDefault},
+		{"log.level", "debug", SourceFile},
+		{"speechToText.url", "https://dotenv.example.com", SourceDotEnv},
+		{"omdb.url", "https://env.example.com", SourceEnv},
+		{"callback.port", "9004", SourceFlag},
+	}
+
+	for _, test := range tests {
+		setting := findSetting(c, test.key)
+		if setting == nil {
+			t.Errorf("setting %s is missing", test.key)
+			continue
+		}
+
+		if got := setting.Value(); got != test.value {
+			t.Errorf("got %s = %q, want %q", test.key, got, test.value)
+		}
+
+		if got := c.Source(test.key); got != test.source {
+			t.Errorf("got %s from %s, want %s", test.key, got, test.source)
+		}
+	}
+}
+
+func TestLoadConfigPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		env      string
+		path     string
+		wantPath string
+		err      string
+	}{
+		{
+			name:     "default",
+			files:    map[string]string{DefaultConfigPath: `{"log": {"level": "warn"}}`},
+			wantPath: DefaultConfigPath,
+		},
+		{
+			name: "no default",
+		},
+		{
+			name: "relative to .env",
+			files: map[string]string{
+				DotEnvPath:           "ALIBI_CONFIG=configs/alibi.json",
+				"configs/alibi.json": `{"log": {"level": "warn"}}`,
+				DefaultConfigPath:    `{"log": {"level": "error"}}`,
+			},
+			wantPath: "configs/alibi.json",
+		},
+		{
+			name: "environment before .env",
+			files: map[string]string{
+				DotEnvPath:           "ALIBI_CONFIG=configs/alibi.json",
+				"configs/alibi.json": `{"log": {"level": "error"}}`,
+				"env.json":           `{"log": {"level": "warn"}}`,
+			},
+			env:      "env.json",
+			wantPath: "env.json",
+		},
+		{
+			name: "option before environment",
+			files: map[string]string{
+				"env.json":    `{"log": {"level": "error"}}`,
+				"option.json": `{"log": {"level": "warn"}}`,
+			},
+			env:      "env.json",
+			path:     "option.json",
+			wantPath: "option.json",
+		},
+		{
+			name:  "missing from .env",
+			files: map[string]string{DotEnvPath: "ALIBI_CONFIG=missing.json"},
+			err:   "error reading config file",
+		},
+	}
+
+	for _, test := range tests {
+		dir, cleanup := newTestConfigDir(t, test.files)
+
+		// The paths from the environment and options are relative to the
+		// working directory, so they're made absolute for the test.
+		if test.env != "" {
+			os.Setenv("ALIBI_CONFIG", filepath.Join(dir, test.env))
+		}
+		path := ""
+		if test.path != "" {
+			path = filepath.Join(dir, test.path)
+		}
+
+		c, err := LoadConfig(ConfigOptions{Path: path, Dir: dir})
+		cleanup()
+
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: got error %q", test.name, err)
+			continue
+		}
+
+		wantPath, wantLevel := "", "info"
+		if test.wantPath != "" {
+			wantPath, wantLevel = filepath.Join(dir, test.wantPath), "warn"
+		}
+
+		if c.Path() != wantPath || c.Log.Level != wantLevel {
+			t.Errorf("%s: got config %q with log level %q, want %q with %q",
+				test.name, c.Path(), c.Log.Level, wantPath, wantLevel)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		dotEnv    string
+		overrides []string
+		err       string
+	}{
+		{name: "unknown flag", overrides: []string{"callback.host=x"}, err: `unknown setting "callback.host"`},
+		{name: "unknown file key", file: `{"callback": {"host": "x"}}`, err: `unknown setting "callback.host"`},
+		{name: "missing value", overrides: []string{"callback.port"}, err: "expected key=value"},
+		{name: "invalid int", overrides: []string{"callback.port=x"}, err: "callback.port (from flag): expected a whole number"},
+		{name: "invalid float", file: `{"transcript": {"minConfidence": "x"}}`, err: "transcript.minConfidence (from file): expected a number"},
+		{name: "invalid bool", dotEnv: "GIT_AUTO_COMMIT=maybe", err: "gitAutoCommit (from .env): expected true or false"},
+		{name: "invalid duration", overrides: []string{"ffmpeg.timeout=soon"}, err: "ffmpeg.timeout (from flag): expected a duration"},
+		{name: "array", file: `{"log": {"level": ["debug"]}}`, err: "invalid value for log.level"},
+		{name: "malformed file", file: `{"log": `, err: "error parsing config file"},
+	}
+
+	for _, test := range tests {
+		files := make(map[string]string)
+		if test.file != "" {
+			files[DefaultConfigPath] = test.file
+		}
+		if test.dotEnv != "" {
+			files[DotEnvPath] = test.dotEnv
+		}
+
+		dir, cleanup := newTestConfigDir(t, files)
+		_, err := LoadConfig(ConfigOptions{Dir: dir, Overrides: test.overrides})
+		cleanup()
+
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestLoadConfigValues(t *testing.T) {
+	dir, cleanup := newTestConfigDir(t, map[string]string{
+		DefaultConfigPath: `{
+  "gitAutoCommit": "yes",
+  "transcript": {"minConfidence": 0.5},
+  "s3": {"timeout": 90, "region": null},
+  "log": {"maxAge": 7, "compress": true}
+}`,
+	})
+	defer cleanup()
+
+	os.Setenv("FFMPEG_TIMEOUT", "1h30m")
+
+	c, err := LoadConfig(ConfigOptions{
+		Dir:       dir,
+		Overrides: []string{"youtubeDL.timeout=45", "log.compress=off"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []interface{}{
+		c.GitAutoCommit,
+		c.Transcript.MinConfidence,
+		c.S3.Timeout,
+		c.S3.Region,
+		c.Log.MaxAge,
+		c.Log.Compress,
+		c.FFmpeg.Timeout,
+		c.YouTubeDL.Timeout,
+	}
+	want := []interface{}{
+		true,
+		0.5,
+		90 * time.Second,
+		"",
+		7,
+		false,
+		90 * time.Minute,
+		45 * time.Second,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got values %v, want %v", got, want)
+	}
+}
+
+func TestSettingSet(t *testing.T) {
+	var d time.Duration
+	var b bool
+	duration := &Setting{Key: "duration", value: &d}
+	boolean := &Setting{Key: "bool", value: &b}
+
+	tests := []struct {
+		setting *Setting
+		value   string
+		want    string
+	}{
+		{duration, "90", "1m30s"},
+		{duration, " 2m ", "2m0s"},
+		{duration, "1h30m", "1h30m0s"},
+		{duration, "1.5", ""},
+		{duration, "soon", ""},
+		{boolean, "true", "true"},
+		{boolean, "Yes", "true"},
+		{boolean, "on", "true"},
+		{boolean, "1", "true"},
+		{boolean, "off", "false"},
+		{boolean, "", "false"},
+		{boolean, "maybe", ""},
+	}
+
+	for _, test := range tests {
+		err := test.setting.set(test.value)
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("set(%q) on %s didn't return an error", test.value, test.setting.Key)
+		case test.want != "" && err != nil:
+			t.Errorf("set(%q) on %s returned error: %s", test.value, test.setting.Key, err)
+		case test.want != "" && test.setting.Value() != test.want:
+			t.Errorf("set(%q) on %s = %s, want %s",
+				test.value, test.setting.Key, test.setting.Value(), test.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []string
+		want      []string
+	}{
+		{
+			name:      "valid",
+			overrides: []string{"investigationsPath=/investigations"},
+		},
+		{
+			name: "missing investigations path",
+			want: []string{"investigationsPath (from default) is required"},
+		},
+		{
+			name: "s3 without bucket",
+			overrides: []string{
+				"investigationsPath=/investigations",
+				"assetStorage=s3",
+				"s3.timeout=0",
+			},
+			want: []string{
+				"s3.bucket (from default) is required when assetStorage is s3",
+				"s3.timeout (from flag) must be greater than 0",
+			},
+		},
+		{
+			name: "out of range",
+			overrides: []string{
+				"investigationsPath=/investigations",
+				"callback.port=70000",
+				"transcript.minConfidence=1.5",
+				"log.maxAge=-1",
+			},
+			want: []string{
+				"callback.port (from flag) must be between 1 and 65535, not 70000",
+				"transcript.minConfidence (from flag) must be between 0 and 1, not 1.5",
+				"log.maxAge (from flag) must be 0 or greater",
+			},
+		},
+		{
+			name: "invalid choices",
+			overrides: []string{
+				"investigationsPath=/investigations",
+				"assetStorage=ftp",
+				"log.level=loud",
+				"log.files=off",
+				"log.dir=",
+			},
+			want: []string{
+				`assetStorage (from flag) must be local or s3, not "ftp"`,
+				`log.level (from flag) must be one of panic, fatal, error, warn, warning, info, debug, trace, not "loud"`,
+			},
+		},
+		{
+			name: "invalid URLs",
+			overrides: []string{
+				"investigationsPath=/investigations",
+				"callback.url=example.com",
+				"omdb.url=ftp://example.com",
+			},
+			want: []string{
+				`callback.url (from flag) must be an http or https URL, not "example.com"`,
+				`omdb.url (from flag) must be an http or https URL, not "ftp://example.com"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		dir, cleanup := newTestConfigDir(t, nil)
+		c, err := LoadConfig(ConfigOptions{Dir: dir, Overrides: test.overrides})
+		cleanup()
+		if err != nil {
+			t.Errorf("%s: got error %q", test.name, err)
+			continue
+		}
+
+		err = c.Validate()
+		if len(test.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: got error %q", test.name, err)
+			}
+			continue
+		}
+
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("%s: got error %v, want a ConfigError", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(configErr.Problems, test.want) {
+			t.Errorf("%s: got problems %q, want %q", test.name, configErr.Problems, test.want)
+		}
+	}
+}
+
+func TestDisplayValue(t *testing.T) {
+	c := NewConfig()
+	c.Callback.URL = "https://example.com/callback"
+
+	tests := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"omdb.apiKey", "", ""},
+		{"omdb.apiKey", "abcd1234", "********"},
+		{"omdb.apiKey", "abcdefghijkl", "********ijkl"},
+		{"s3.secretAccessKey", "abcdefghijkl", "********ijkl"},
+		{"callback.url", "https://example.com/callback", "https://example.com/callback"},
+	}
+
+	for _, test := range tests {
+		if err := c.set(test.key, test.value, SourceFlag); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := findSetting(c, test.key).DisplayValue(); got != test.want {
+			t.Errorf("DisplayValue() of %s %q = %q, want %q", test.key, test.value, got, test.want)
+		}
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	c := NewConfig()
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"transcribe", "-s", "3"},
+			[]string{"transcribe", "-s", "3"},
+		},
+		{
+			[]string{"--set", "speechToText.apiKey=abcdefghijkl", "recognize"},
+			[]string{"--set", "speechToText.apiKey=********ijkl", "recognize"},
+		},
+		{
+			[]string{"--set=omdb.apiKey=short", "sync"},
+			[]string{"--set=omdb.apiKey=********", "sync"},
+		},
+		{
+			[]string{"--set", "S3_SECRET_ACCESS_KEY=abcdefghijkl"},
+			[]string{"--set", "S3_SECRET_ACCESS_KEY=********ijkl"},
+		},
+		{
+			[]string{"--set", "callback.port=9001", "--set"},
+			[]string{"--set", "callback.port=9001", "--set"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := c.RedactArgs(test.args); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RedactArgs(%q) = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+// findSetting returns the setting of the config with the specified key or nil
+// if there isn't one.
+func findSetting(c *Config, key string) *Setting {
+	for _, s := range c.Settings() {
+		if s.Key == key {
+			return s
+		}
+	}
+
+	return nil
+}
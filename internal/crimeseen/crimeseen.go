@@ -3,12 +3,16 @@
 package crimeseen
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Mkdirp creates the specified directory path if it doesn't already exist.
@@ -46,32 +50,62 @@ func PaddedNumberString(value int) string {
 	return numString
 }
 
+// FormatBytes returns the size in the largest unit that keeps the value above
+// 1 (e.g. "1.5 GB").
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 // WriteJSONFile writes the specified contents as JSON to the specified path.
+// The contents are written to a temporary file first, so the file is never
+// left half-written.
 func WriteJSONFile(path string, contents interface{}) error {
 	b, err := json.MarshalIndent(contents, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+	return WriteFileAtomic(path, b)
+}
+
+// Wait pauses for the specified duration or until the specified context is
+// canceled, in which case it returns the context's error.
+func Wait(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	defer file.Close()
+}
 
-	_, err = io.WriteString(file, string(b))
+// FileHash returns the hex-encoded SHA-256 hash of the contents of the file at
+// the specified path.
+func FileHash(path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return err
+		return "", err
 	}
+	defer file.Close()
 
-	return file.Sync()
-}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
 
-// RunCommand is a wrapper around exec.Command that redirects output to the
-// terminal.
-func RunCommand(command string, args ...string) error {
-	cmd := exec.Command(command, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
@@ -0,0 +1,45 @@
+package crimeseen
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FileLock is an advisory lock held on a lock file. It only keeps out other
+// code that takes the same lock, which is enough to keep the callback server
+// and the commands from writing the same files at the same time.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile blocks until it holds an exclusive lock on the file at the
+// specified path. The file (and any parent directories) are created if they
+// don't exist. Call Unlock to release the lock.
+func LockFile(path string) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FileLock{file: file}, nil
+}
+
+// Unlock releases the lock. The lock file is left in place, because removing
+// it could let another process lock a file that was already replaced.
+func (fl *FileLock) Unlock() error {
+	err := unlockFile(fl.file)
+	if closeErr := fl.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,262 @@
+package handdelivered
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportOptions contains the options used to export bundles.
+type ExportOptions struct {
+	// AssetTypes are the asset types to include in the bundles.
+	AssetTypes []whodunit.AssetType
+
+	// OutputDir is the directory the bundles are written to.
+	OutputDir string
+
+	// Grouping determines whether a bundle is written for each season or
+	// each episode.
+	Grouping Grouping
+
+	// Overwrite replaces bundles that already exist in the output directory.
+	Overwrite bool
+}
+
+// Export writes a bundle with every existing asset of the specified asset
+// types for the episodes in the specified selection of the specified
+// workspace and returns the paths to the bundles. Episodes without any of the
+// assets are skipped.
+func Export(
+	ctx context.Context,
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	opts ExportOptions,
+) ([]string, error) {
+	c, err := ws.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	bundlePaths := make([]string, 0)
+	for _, group := range groupEpisodes(c.Select(sel), opts.Grouping) {
+		if err := ctx.Err(); err != nil {
+			return bundlePaths, err
+		}
+
+		bundlePath := filepath.Join(opts.OutputDir, group.name+BundleExt)
+		bundle, err := exportBundle(ctx, bundlePath, group.episodes, opts)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"bundle": bundlePath,
+				"error":  err,
+			}).Errorln("Error exporting bundle")
+			return bundlePaths, err
+		}
+
+		if bundle == nil {
+			log.WithField("bundle", filepath.Base(bundlePath)).Debugln(
+				"No assets to export, skipping")
+			continue
+		}
+
+		log.WithFields(logrus.Fields{
+			"bundle":   bundlePath,
+			"episodes": len(bundle.Episodes),
+			"files":    len(bundle.Files),
+		}).Infoln("Exported bundle")
+		bundlePaths = append(bundlePaths, bundlePath)
+	}
+
+	return bundlePaths, nil
+}
+
+// episodeGroup is the episodes that are written to a single bundle.
+type episodeGroup struct {
+	name     string
+	episodes []*whodunit.Episode
+}
+
+// groupEpisodes splits the specified episodes into the groups written to each
+// bundle. The episodes are already sorted, so episodes in the same season are
+// next to each other.
+func groupEpisodes(episodes []*whodunit.Episode, grouping Grouping) []*episodeGroup {
+	groups := make([]*episodeGroup, 0)
+	for _, ep := range episodes {
+		name := ep.Name()
+		if grouping == GroupBySeason {
+			name = strings.ReplaceAll(ep.Season().DirName(), "/", "-")
+		}
+
+		if len(groups) == 0 || groups[len(groups)-1].name != name {
+			groups = append(groups, &episodeGroup{name: name})
+		}
+
+		last := groups[len(groups)-1]
+		last.episodes = append(last.episodes, ep)
+	}
+
+	return groups
+}
+
+// exportBundle writes the bundle for the specified episodes to the specified
+// path and returns its manifest. The bundle is written to a temporary file
+// first, so a failed export doesn't leave a partial bundle behind. Nothing is
+// written (and nil is returned) if there are no asset files.
+func exportBundle(
+	ctx context.Context,
+	bundlePath string,
+	episodes []*whodunit.Episode,
+	opts ExportOptions,
+) (*Bundle, error) {
+	if _, err := os.Stat(bundlePath); err == nil && !opts.Overwrite {
+		return nil, fmt.Errorf("bundle %s already exists", bundlePath)
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(bundlePath), filepath.Base(bundlePath)+".*.part")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	gw := gzip.NewWriter(tempFile)
+	tw := tar.NewWriter(gw)
+
+	bundle := &Bundle{
+		Version:   BundleVersion,
+		CreatedAt: time.Now().UTC(),
+		Episodes:  make([]*whodunit.Episode, 0, len(episodes)),
+		Files:     make([]*BundleFile, 0),
+	}
+
+	for _, ep := range episodes {
+		fileCount := len(bundle.Files)
+		for _, assetType := range opts.AssetTypes {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			if !ep.AssetExists(assetType) {
+				continue
+			}
+
+			file, err := writeAsset(tw, ep, assetType)
+			if err != nil {
+				return nil, fmt.Errorf("error adding %s: %w", ep.AssetFileName(assetType), err)
+			}
+			bundle.Files = append(bundle.Files, file)
+		}
+
+		if len(bundle.Files) != fileCount {
+			bundle.Episodes = append(bundle.Episodes, ep)
+		}
+	}
+
+	if len(bundle.Files) == 0 {
+		return nil, nil
+	}
+
+	// The manifest is written last, since the hashes are calculated as the
+	// asset files are added.
+	if err := writeManifest(tw, bundle); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tempFile.Name(), bundlePath); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// writeAsset adds the asset file of the episode to the bundle archive and
+// returns its details.
+func writeAsset(
+	tw *tar.Writer,
+	ep *whodunit.Episode,
+	assetType whodunit.AssetType,
+) (*BundleFile, error) {
+	info, err := ep.StatAsset(assetType)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := ep.OpenAsset(assetType)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	file := &BundleFile{
+		Name:      ep.Name(),
+		AssetType: assetType,
+		Path:      info.Path,
+		Size:      info.Size,
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    file.archivePath(),
+		Mode:    0644,
+		Size:    info.Size,
+		ModTime: info.ModTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The tar writer returns an error if the file doesn't match the size in
+	// the header, so a file that changes while it's being read is caught.
+	hash := sha256.New()
+	if _, err := io.Copy(tw, io.TeeReader(r, hash)); err != nil {
+		return nil, err
+	}
+
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return file, nil
+}
+
+// writeManifest adds the manifest of the bundle to the bundle archive.
+func writeManifest(tw *tar.Writer, bundle *Bundle) error {
+	b, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0644,
+		Size:    int64(len(b)),
+		ModTime: bundle.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(b)
+	return err
+}
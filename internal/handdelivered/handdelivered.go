@@ -0,0 +1,141 @@
+// Package handdelivered packs the assets of episodes into portable bundles and
+// unpacks bundles into the asset storage, so finished transcripts and analyses
+// can be exchanged without copying the whole investigations directory.
+package handdelivered
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+)
+
+// BundleVersion is the version of the bundle layout written by Export. Import
+// refuses bundles with a newer version.
+const BundleVersion = 1
+
+// ManifestName is the name of the manifest file in the bundle archive.
+const ManifestName = "bundle.json"
+
+// BundleExt is the extension of the bundle archives.
+const BundleExt = ".tar.gz"
+
+// assetsPrefix is the directory the asset files are stored under in the
+// bundle archive. The rest of the path is the key of the file in the asset
+// storage.
+const assetsPrefix = "assets/"
+
+// Grouping determines how many episodes are written to each bundle.
+type Grouping string
+
+const (
+	// GroupBySeason writes a bundle for each season.
+	GroupBySeason Grouping = "season"
+
+	// GroupByEpisode writes a bundle for each episode.
+	GroupByEpisode Grouping = "episode"
+)
+
+// Groupings returns the keys of every grouping for use in flag enums.
+func Groupings() []string {
+	return []string{string(GroupBySeason), string(GroupByEpisode)}
+}
+
+// Bundle is the manifest of a bundle archive. It contains the catalog entry of
+// each episode in the bundle and the details of every asset file, so the files
+// can be checked before they're placed in the asset storage.
+type Bundle struct {
+	Version   int                 `json:"version"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Episodes  []*whodunit.Episode `json:"episodes"`
+	Files     []*BundleFile       `json:"files"`
+}
+
+// BundleFile contains the details of a single asset file in the bundle.
+type BundleFile struct {
+	Name      string             `json:"name"`
+	AssetType whodunit.AssetType `json:"assetType"`
+	Path      string             `json:"path"`
+	Size      int64              `json:"size"`
+	SHA256    string             `json:"sha256"`
+}
+
+var log = waterlogged.New("handdelivered")
+
+// archivePath returns the path of the file in the bundle archive.
+func (bf *BundleFile) archivePath() string {
+	return assetsPrefix + bf.Path
+}
+
+// checkPath returns an error if the path of the file isn't where the asset of
+// the specified episode belongs in the asset storage. Video and audio files
+// can have any extension, since youtube-dl doesn't always write `.mp4` files.
+func (bf *BundleFile) checkPath(ep *whodunit.Episode) error {
+	dir := path.Join(bf.AssetType.DirName(), ep.Season().DirName())
+	expected := path.Join(dir, ep.AssetFileName(bf.AssetType))
+	if bf.Path == expected {
+		return nil
+	}
+
+	prefix := path.Join(dir, ep.Name()) + "."
+	ext := strings.TrimPrefix(bf.Path, prefix)
+	if bf.AssetType.IsMedia() && strings.HasPrefix(bf.Path, prefix) &&
+		ext != "" && !strings.ContainsAny(ext, "./\\") {
+		return nil
+	}
+
+	return fmt.Errorf("file %s should be %s", bf.Path, expected)
+}
+
+// validate returns an error if the manifest is from a newer version, any of
+// the episodes has a title that isn't normalized, or any of the files can't be
+// matched to an episode in the bundle. The title is part of the path of every
+// asset file, so a title like "x/../y" could place files anywhere.
+func (b *Bundle) validate() error {
+	if b.Version < 1 || b.Version > BundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+
+	episodes := make(map[string]*whodunit.Episode, len(b.Episodes))
+	for _, ep := range b.Episodes {
+		if ep.Title == "" || ep.Title != whodunit.NormalizeTitle(ep.Title) {
+			return fmt.Errorf("episode s%de%d has an invalid title %q",
+				ep.SeasonNumber, ep.EpisodeNumber, ep.Title)
+		}
+
+		if ep.SeasonNumber < 1 || ep.EpisodeNumber < 1 {
+			return fmt.Errorf("episode %s has an invalid season or episode number", ep.Name())
+		}
+
+		if _, ok := episodes[ep.Name()]; ok {
+			return fmt.Errorf("episode %s is listed more than once", ep.Name())
+		}
+		episodes[ep.Name()] = ep
+	}
+
+	paths := make(map[string]bool, len(b.Files))
+	for _, file := range b.Files {
+		if paths[file.Path] {
+			return fmt.Errorf("file %s is listed more than once", file.Path)
+		}
+		paths[file.Path] = true
+
+		ep, ok := episodes[file.Name]
+		if !ok {
+			return fmt.Errorf("file %s belongs to unknown episode %s", file.Path, file.Name)
+		}
+
+		if err := file.checkPath(ep); err != nil {
+			return err
+		}
+
+		if len(file.SHA256) != 64 {
+			return fmt.Errorf("file %s has an invalid hash", file.Path)
+		}
+	}
+
+	return nil
+}
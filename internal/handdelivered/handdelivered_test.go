@@ -0,0 +1,181 @@
+package handdelivered
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+)
+
+const testHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestBundleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		episode *whodunit.Episode
+		path    string
+		err     string
+	}{
+		{
+			name:    "valid",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone"},
+			path:    "transcripts/season-3/03-02-knot-for-everyone.txt",
+		},
+		{
+			name: "valid series",
+			episode: &whodunit.Episode{
+				Series:        whodunit.SeriesForensicFilesII,
+				SeasonNumber:  1,
+				EpisodeNumber: 2,
+				Title:         "the-cold-case",
+			},
+			path: "transcripts/forensic-files-ii/season-1/forensic-files-ii-01-02-the-cold-case.txt",
+		},
+		{
+			name:    "title with path",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "x/../../y"},
+			path:    "transcripts/season-3/03-02-x/../../y.txt",
+			err:     "invalid title",
+		},
+		{
+			name:    "title not normalized",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "Knot For Everyone"},
+			path:    "transcripts/season-3/03-02-Knot For Everyone.txt",
+			err:     "invalid title",
+		},
+		{
+			name:    "empty title",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 2},
+			path:    "transcripts/season-3/03-02-.txt",
+			err:     "invalid title",
+		},
+		{
+			name:    "invalid season",
+			episode: &whodunit.Episode{SeasonNumber: 0, EpisodeNumber: 2, Title: "knot-for-everyone"},
+			path:    "transcripts/season-0/00-02-knot-for-everyone.txt",
+			err:     "invalid season or episode number",
+		},
+		{
+			name:    "wrong path",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone"},
+			path:    "transcripts/season-3/../../03-02-knot-for-everyone.txt",
+			err:     "should be",
+		},
+	}
+
+	for _, test := range tests {
+		bundle := &Bundle{
+			Version:  BundleVersion,
+			Episodes: []*whodunit.Episode{test.episode},
+			Files: []*BundleFile{{
+				Name:      test.episode.Name(),
+				AssetType: whodunit.AssetTypeTranscript,
+				Path:      test.path,
+				SHA256:    testHash,
+			}},
+		}
+		if _, err := whodunit.NewCatalog(bundle.Episodes); err != nil {
+			t.Fatal(err)
+		}
+
+		err := bundle.validate()
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: got error %q", test.name, err)
+
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestResolveEpisodes(t *testing.T) {
+	c, err := whodunit.NewCatalog([]*whodunit.Episode{
+		{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone"},
+		{Series: "forensic-files-classics", SeasonNumber: 1, EpisodeNumber: 1, Title: "the-list"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		episode *whodunit.Episode
+		isNew   bool
+		err     string
+	}{
+		{
+			name:    "existing",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone"},
+		},
+		{
+			name:    "renamed",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-anyone"},
+			err:     "in catalog",
+		},
+		{
+			name:    "new",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 3, Title: "bitter-pills"},
+			isNew:   true,
+		},
+		{
+			name: "new in known series",
+			episode: &whodunit.Episode{
+				Series:        whodunit.SeriesForensicFilesII,
+				SeasonNumber:  1,
+				EpisodeNumber: 1,
+				Title:         "the-cold-case",
+			},
+			isNew: true,
+		},
+		{
+			name: "new in catalog series",
+			episode: &whodunit.Episode{
+				Series:        "forensic-files-classics",
+				SeasonNumber:  1,
+				EpisodeNumber: 2,
+				Title:         "the-magic-bullet",
+			},
+			isNew: true,
+		},
+		{
+			name: "new in unknown series",
+			episode: &whodunit.Episode{
+				Series:        "made-up-files",
+				SeasonNumber:  1,
+				EpisodeNumber: 1,
+				Title:         "the-cold-case",
+			},
+			err: "unknown series",
+		},
+		{
+			name:    "new with invalid title",
+			episode: &whodunit.Episode{SeasonNumber: 3, EpisodeNumber: 3, Title: "x/../y"},
+			err:     "invalid title",
+		},
+	}
+
+	for _, test := range tests {
+		bundle := &Bundle{Episodes: []*whodunit.Episode{test.episode}}
+		episodes, newEpisodes, err := resolveEpisodes(c, bundle)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: got error %q", test.name, err)
+			continue
+		}
+
+		if isNew := len(newEpisodes) == 1; isNew != test.isNew {
+			t.Errorf("%s: got new %t, want %t", test.name, isNew, test.isNew)
+		}
+
+		if episodes[test.episode.Name()] == nil {
+			t.Errorf("%s: episode %s wasn't resolved", test.name, test.episode.Name())
+		}
+	}
+}
@@ -0,0 +1,437 @@
+package handdelivered
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/sirupsen/logrus"
+)
+
+// extractedFile is an asset file from the bundle archive that was extracted to
+// a temporary directory.
+type extractedFile struct {
+	tempPath string
+	size     int64
+	sha256   string
+}
+
+// placement is an asset file from the bundle that gets written to the asset
+// storage.
+type placement struct {
+	file        *BundleFile
+	episode     *whodunit.Episode
+	tempPath    string
+	existingKey string
+}
+
+// Import checks the bundle at the specified path against its manifest and
+// places the asset files in the asset storage of the specified workspace.
+// Nothing is written unless every file in the bundle matches the manifest and
+// belongs to an episode in the catalog (or in the bundle, in which case the
+// episode is added to the catalog once its files are placed). Asset files
+// that already exist with different contents are only replaced if overwrite
+// is true.
+func Import(
+	ctx context.Context,
+	ws *whodunit.Workspace,
+	bundlePath string,
+	overwrite bool,
+) error {
+	c, err := ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := ioutil.TempDir("", "handdelivered")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	bundle, extracted, err := extractBundle(ws, bundlePath, tempDir)
+	if err != nil {
+		return fmt.Errorf("error reading bundle %s: %w", bundlePath, err)
+	}
+
+	if err := checkFiles(bundle, extracted); err != nil {
+		return fmt.Errorf("bundle %s is invalid: %w", bundlePath, err)
+	}
+
+	episodes, newEpisodes, err := resolveEpisodes(c, bundle)
+	if err != nil {
+		return err
+	}
+
+	placements, unchangedCount, err := planPlacements(bundle, episodes, extracted, overwrite)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range placements {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := place(p); err != nil {
+			return fmt.Errorf("error placing %s: %w", p.file.Path, err)
+		}
+
+		log.WithField("file", p.file.Path).Infoln("Placed asset")
+	}
+
+	// The new episodes are only added once their files are in place, so a
+	// failed import never leaves episodes in the catalog without assets. If
+	// the import is run again, the files that were placed are unchanged.
+	if len(newEpisodes) != 0 {
+		for _, ep := range newEpisodes {
+			if err := c.Add(ep); err != nil {
+				return err
+			}
+		}
+
+		if err := ws.SaveCatalog(); err != nil {
+			return fmt.Errorf("files placed, but unable to save catalog: %w", err)
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"bundle":    bundlePath,
+		"placed":    len(placements),
+		"unchanged": unchangedCount,
+		"added":     len(newEpisodes),
+	}).Infoln("Imported bundle")
+	return nil
+}
+
+// extractBundle extracts the asset files in the bundle archive at the
+// specified path to the specified directory and returns the manifest along
+// with the extracted files keyed by path in the asset storage. The files are
+// given generated names, so paths in the archive can't escape the directory.
+// The episodes in the manifest are linked to the specified workspace.
+func extractBundle(
+	ws *whodunit.Workspace,
+	bundlePath string,
+	dir string,
+) (*Bundle, map[string]*extractedFile, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gr.Close()
+
+	var bundle *Bundle
+	extracted := make(map[string]*extractedFile)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case header.Typeflag == tar.TypeDir:
+			continue
+
+		case header.Typeflag != tar.TypeReg:
+			return nil, nil, fmt.Errorf("unexpected entry %s in archive", header.Name)
+
+		case header.Name == ManifestName:
+			if bundle != nil {
+				return nil, nil, fmt.Errorf("archive has more than one %s", ManifestName)
+			}
+
+			bundle = &Bundle{}
+			if err := json.NewDecoder(tr).Decode(bundle); err != nil {
+				return nil, nil, fmt.Errorf("error parsing %s: %w", ManifestName, err)
+			}
+
+		case strings.HasPrefix(header.Name, assetsPrefix):
+			key := strings.TrimPrefix(header.Name, assetsPrefix)
+			if _, ok := extracted[key]; ok {
+				return nil, nil, fmt.Errorf("archive has more than one %s", header.Name)
+			}
+
+			tempPath := filepath.Join(dir, strconv.Itoa(len(extracted)))
+			ef, err := extractFile(tr, tempPath)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error extracting %s: %w", header.Name, err)
+			}
+			extracted[key] = ef
+
+		default:
+			return nil, nil, fmt.Errorf("unexpected entry %s in archive", header.Name)
+		}
+	}
+
+	if bundle == nil {
+		return nil, nil, fmt.Errorf("archive has no %s", ManifestName)
+	}
+
+	// The catalog links the episodes to their seasons and the workspace,
+	// which are needed to check where the files belong.
+	bundleCatalog, err := whodunit.NewCatalog(bundle.Episodes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	bundleCatalog.SetWorkspace(ws)
+
+	if err := bundle.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return bundle, extracted, nil
+}
+
+// extractFile writes the contents of the specified reader to the file at the
+// specified path and returns its details.
+func extractFile(r io.Reader, tempPath string) (*extractedFile, error) {
+	f, err := os.Create(tempPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(f, io.TeeReader(r, hash))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return &extractedFile{
+		tempPath: tempPath,
+		size:     size,
+		sha256:   hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// checkFiles returns an error if any of the files in the manifest are missing
+// from the archive or don't match the recorded size and hash, or if the
+// archive has files that aren't in the manifest.
+func checkFiles(bundle *Bundle, extracted map[string]*extractedFile) error {
+	listed := make(map[string]bool, len(bundle.Files))
+	for _, file := range bundle.Files {
+		listed[file.Path] = true
+
+		ef, ok := extracted[file.Path]
+		if !ok {
+			return fmt.Errorf("file %s is missing", file.Path)
+		}
+
+		if ef.size != file.Size {
+			return fmt.Errorf("file %s is %d bytes, expected %d",
+				file.Path, ef.size, file.Size)
+		}
+
+		if ef.sha256 != file.SHA256 {
+			return fmt.Errorf("file %s doesn't match the hash in the manifest", file.Path)
+		}
+	}
+
+	for key := range extracted {
+		if !listed[key] {
+			return fmt.Errorf("file %s isn't in the manifest", key)
+		}
+	}
+
+	return nil
+}
+
+// resolveEpisodes returns the episodes in the catalog that match the episodes
+// in the bundle keyed by name, along with the episodes from the bundle that
+// aren't in the catalog yet. It returns an error if an episode in the catalog
+// has a different name than the one in the bundle, since the files would end
+// up in the wrong place, or if a new episode has an invalid title or belongs
+// to a series that isn't known or in the catalog.
+func resolveEpisodes(
+	c *whodunit.Catalog,
+	bundle *Bundle,
+) (map[string]*whodunit.Episode, []*whodunit.Episode, error) {
+	catalogSeries := make(map[whodunit.Series]bool)
+	for _, series := range c.Series() {
+		catalogSeries[series] = true
+	}
+
+	episodes := make(map[string]*whodunit.Episode, len(bundle.Episodes))
+	newEpisodes := make([]*whodunit.Episode, 0)
+	for _, ep := range bundle.Episodes {
+		existing := c.SeriesEpisode(ep.Series, ep.SeasonNumber, ep.EpisodeNumber)
+		switch {
+		case existing == nil:
+			if ep.Title == "" || ep.Title != whodunit.NormalizeTitle(ep.Title) {
+				return nil, nil, fmt.Errorf("episode %s in bundle has an invalid title", ep.Name())
+			}
+
+			if !ep.Series.IsKnown() && !catalogSeries[ep.Series.Key()] {
+				return nil, nil, fmt.Errorf("episode %s in bundle is in unknown series %s",
+					ep.Name(), ep.Series)
+			}
+
+			newEpisodes = append(newEpisodes, ep)
+			episodes[ep.Name()] = ep
+
+		case existing.Name() != ep.Name():
+			return nil, nil, fmt.Errorf("episode %s in bundle is %s in catalog",
+				ep.Name(), existing.Name())
+
+		default:
+			episodes[ep.Name()] = existing
+		}
+	}
+
+	return episodes, newEpisodes, nil
+}
+
+// planPlacements returns the files from the bundle that need to be written to
+// the asset storage in the order they're produced in the pipeline, so assets
+// aren't flagged as stale because their inputs were written after them. It
+// also returns the number of files that already exist with the same contents.
+// It returns an error listing every file that would be replaced if overwrite
+// is false.
+func planPlacements(
+	bundle *Bundle,
+	episodes map[string]*whodunit.Episode,
+	extracted map[string]*extractedFile,
+	overwrite bool,
+) ([]*placement, int, error) {
+	placements := make([]*placement, 0, len(bundle.Files))
+	unchangedCount := 0
+	conflicts := make([]string, 0)
+	for _, file := range bundle.Files {
+		ep := episodes[file.Name]
+		p := &placement{
+			file:     file,
+			episode:  ep,
+			tempPath: extracted[file.Path].tempPath,
+		}
+
+		if ep.AssetExists(file.AssetType) {
+			p.existingKey = ep.AssetKey(file.AssetType)
+			hash, err := hashAsset(ep, file.AssetType)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			if hash == file.SHA256 {
+				unchangedCount++
+				continue
+			}
+
+			if !overwrite {
+				conflicts = append(conflicts, p.existingKey)
+				continue
+			}
+		}
+
+		placements = append(placements, p)
+	}
+
+	if len(conflicts) != 0 {
+		return nil, 0, fmt.Errorf("%d file(s) already exist with different contents "+
+			"(use --overwrite to replace them): %s",
+			len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	order := make(map[whodunit.AssetType]int)
+	for i, assetType := range whodunit.AllAssetTypes() {
+		order[assetType] = i
+	}
+
+	sort.SliceStable(placements, func(i, j int) bool {
+		return order[placements[i].file.AssetType] < order[placements[j].file.AssetType]
+	})
+
+	return placements, unchangedCount, nil
+}
+
+// place writes the file to the asset storage and records it in the ledger and
+// the season manifest. If the existing file has a different extension (e.g. a
+// `.mkv` video replaced by an `.mp4`), the existing file is removed.
+func place(p *placement) error {
+	unlock, err := p.episode.LockAsset(p.file.AssetType)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r, err := os.Open(p.tempPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	ws, err := p.episode.Workspace()
+	if err != nil {
+		return err
+	}
+
+	storage, err := ws.Storage()
+	if err != nil {
+		return err
+	}
+
+	w, err := storage.Create(p.file.Path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		coldstorage.Abort(w)
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if p.existingKey != "" && p.existingKey != p.file.Path {
+		if err := storage.Remove(p.existingKey); err != nil {
+			return err
+		}
+	}
+
+	return p.episode.CompleteAsset(p.file.AssetType)
+}
+
+// hashAsset returns the hex-encoded SHA-256 hash of the contents of the asset
+// file.
+func hashAsset(ep *whodunit.Episode, assetType whodunit.AssetType) (string, error) {
+	r, err := ep.OpenAsset(assetType)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -3,12 +3,12 @@
 package hearnoevil
 
 import (
-	"bufio"
-	"os/exec"
+	"context"
+	"fmt"
 	"strings"
 
 	"github.com/IBM/go-sdk-core/core"
-	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/housecall"
 	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 	"github.com/sirupsen/logrus"
@@ -18,22 +18,24 @@ import (
 // Eyewitness contains properties and methods used to start recognition
 // jobs.
 type Eyewitness struct {
+	ws          *whodunit.Workspace
 	s2t         *s2tInstance
 	callbackURL string
 }
 
 var log = waterlogged.New("hearnoevil")
 
-// NewEyewitness returns a new instance of Eyewitness.
-func NewEyewitness(callbackURL string) *Eyewitness {
-	env := crimeseen.NewEnv()
-	sts := newS2TInstance(env)
-	ew := &Eyewitness{s2t: sts}
+// NewEyewitness returns a new instance of Eyewitness that recognizes the
+// episodes in the specified workspace.
+func NewEyewitness(ws *whodunit.Workspace, callbackURL string) *Eyewitness {
+	config := ws.Config()
+	sts := newS2TInstance(config)
+	ew := &Eyewitness{ws: ws, s2t: sts}
 
 	if callbackURL != "" {
 		ew.RegisterCallbackURL(callbackURL)
 	} else {
-		callbackURL = env.CallbackURL()
+		callbackURL = config.Callback.URL
 	}
 	ew.callbackURL = callbackURL
 
@@ -64,103 +66,94 @@ func (ew *Eyewitness) RegisterCallbackURL(callbackURL string) {
 }
 
 // Recognize makes a call to the speech-to-text service to create a recognition
-// job for a single episode in the specified season or all episodes if the season
-// was not specified.
-func (ew *Eyewitness) Recognize(seasonNumber int, episodeNumber int) {
-	ew.interrogate()
+// job for each episode in the specified selection, creating up to the
+// specified number of jobs at the same time.
+func (ew *Eyewitness) Recognize(
+	ctx context.Context,
+	sel *whodunit.Selection,
+	concurrency int,
+) error {
+	port := ew.ws.Config().Callback.Port
+	if !housecall.NgrokRunning(port) {
+		return fmt.Errorf("ngrok is not running, run `ngrok http %d`", port)
+	}
 
-	onEpisode := func(ep *whodunit.Episode) {
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
 		r := NewRecognition(ep)
-		r.StartJob(ew.s2t, ew.callbackURL)
+		return r.StartJob(ew.s2t, ew.callbackURL)
 	}
 
-	if err := whodunit.Solve(seasonNumber, episodeNumber, onEpisode); err != nil {
+	summary, err := ew.ws.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
 		log.WithError(err).Errorln("Error recognizing episode(s)")
+		return err
 	}
+
+	summary.Log(log)
+	return summary.Err()
 }
 
-// Investigate logs the episode statuses.
-func (ew *Eyewitness) Investigate(status whodunit.AssetStatus) {
+// Investigate logs the episode statuses. It returns an error if the catalog
+// can't be loaded or the recognition jobs can't be checked.
+func (ew *Eyewitness) Investigate(status whodunit.AssetStatus) error {
+	c, err := ew.ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	jobStatuses, err := ew.jobStatuses()
+	if err != nil {
+		return err
+	}
+
 	totalCount := 0
 	table := whodunit.NewStatusTable(whodunit.AssetTypeRecognition, status)
-	jep := ew.jobEpisodeMap()
-	for season := 1; season <= whodunit.SeasonCount; season++ {
-		s := whodunit.NewSeason(season)
-		if err := s.PopulateEpisodes(); err != nil {
-			panic("Could not get season episodes")
-		}
 
-		for _, ep := range s.AllEpisodes() {
-			je := jep[ep.Name()]
-			if je != nil {
-				ep.SetAssetStatus(je.AssetStatus(whodunit.AssetTypeRecognition))
-			}
+	// The status of a job takes precedence over the status of the asset. It's
+	// only shown in the table, since the catalog episodes are shared.
+	for _, ep := range c.Episodes() {
+		status := ep.AssetStatus(whodunit.AssetTypeRecognition)
+		if jobStatus, ok := jobStatuses[ep.Name()]; ok {
+			status = jobStatus
+		}
 
-			if table.AddRow(ep) {
-				totalCount++
-			}
+		if table.AddRowWithStatus(ep, status) {
+			totalCount++
 		}
 	}
 
 	table.RenderTable(totalCount)
+	return nil
 }
 
 // StartCallbackServer starts the callback server to receive responses from the
 // speech-to-text service.
 func (ew *Eyewitness) StartCallbackServer() {
-	cs := newCallbackServer()
+	cs := newCallbackServer(ew.ws)
 	cs.Start()
 }
 
-func (ew *Eyewitness) jobEpisodeMap() map[string]*whodunit.Episode {
+// jobStatuses returns the status of the recognition jobs keyed by the name of
+// the episode they were started for.
+func (ew *Eyewitness) jobStatuses() (map[string]whodunit.AssetStatus, error) {
 	result, _, err := ew.s2t.CheckJobs(&stv1.CheckJobsOptions{})
 	if err != nil {
-		log.WithError(err).Fatalln("Error getting recognition jobs")
+		return nil, fmt.Errorf("error getting recognition jobs: %w", err)
 	}
 
-	epMap := make(map[string]*whodunit.Episode, 0)
+	statuses := make(map[string]whodunit.AssetStatus, 0)
 	for _, job := range result.Recognitions {
 		name := *job.UserToken
-		ep, err := whodunit.NewEpisodeFromName(name)
-		if err != nil {
-			log.WithError(err).Fatalln("Error parsing episode name")
+		if _, err := whodunit.NewEpisodeFromName(name); err != nil {
+			return nil, fmt.Errorf("error parsing episode name of job: %w", err)
 		}
 
 		if strings.Contains(*job.Status, "compl") {
-			ep.SetAssetStatus(whodunit.AssetStatusComplete)
+			statuses[name] = whodunit.AssetStatusComplete
 		} else {
-			ep.SetAssetStatus(whodunit.AssetStatusInProcess)
-		}
-
-		epMap[name] = ep
-	}
-
-	return epMap
-}
-
-func (ew *Eyewitness) interrogate() {
-	const ErrorMessage = "ngrok is not running, run `ngrok http 9000`"
-
-	cmd := exec.Command("ps", "aux")
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		log.WithError(err).Fatalln(ErrorMessage)
-	}
-
-	// Start the command after having set up the pipe.
-	if err := cmd.Start(); err != nil {
-		log.WithError(err).Fatalln(ErrorMessage)
-	}
-
-	// Read command's stdout line by line.
-	in := bufio.NewScanner(stdout)
-
-	for in.Scan() {
-		text := in.Text()
-		if strings.Contains(text, "ngrok http 9000") {
-			return
+			statuses[name] = whodunit.AssetStatusInProcess
 		}
 	}
 
-	log.WithError(err).Fatalln(ErrorMessage)
+	return statuses, nil
 }
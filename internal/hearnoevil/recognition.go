@@ -2,11 +2,10 @@ package hearnoevil
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"os"
+	"fmt"
+	"io"
 
 	"github.com/IBM/go-sdk-core/core"
-	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
 	"github.com/mikerourke/forensic-files-api/internal/visibilityzero"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 	"github.com/sirupsen/logrus"
@@ -26,40 +25,52 @@ func NewRecognition(ep *whodunit.Episode) *Recognition {
 }
 
 // StartJob starts a new recognition job.
-func (r *Recognition) StartJob(stt *s2tInstance, callbackURL string) {
-	if r.Exists() {
+func (r *Recognition) StartJob(stt *s2tInstance, callbackURL string) error {
+	if r.Exists() && !r.AssetStale(whodunit.AssetTypeRecognition) {
 		log.WithField("file", r.FileName()).Infoln(
 			"Skipping job, already exists")
-		return
+		return nil
 	}
 
 	a := visibilityzero.NewAudio(r.Episode)
-	if !a.Exists() {
+	if !a.AssetComplete(whodunit.AssetTypeAudio) {
 		log.WithField("file", a.FileName()).Warnln(
-			"Skipping job, audio file not found")
-		return
+			"Skipping job, audio file not found or not complete")
+		return nil
 	}
 
 	audio := a.Open()
 	if audio == nil {
-		return
+		return fmt.Errorf("unable to open audio file %s", a.FileName())
 	}
+	defer audio.Close()
 
 	log.WithFields(logrus.Fields{
+		"series":  r.Series.String(),
 		"season":  r.SeasonNumber,
 		"episode": r.EpisodeNumber,
 	}).Infoln("Creating Recognition job")
+	// The recognition stays in process until the callback server receives
+	// the results, so it's only marked as complete in the ledger from there.
+	if err := r.BeginAsset(whodunit.AssetTypeRecognition); err != nil {
+		return err
+	}
+
 	_, _, err := stt.CreateJob(r.jobOptions(audio, callbackURL))
 	if err != nil {
-		log.WithError(err).Errorln("Error creating job")
-		return
+		err = fmt.Errorf("error creating job: %w", err)
+		if ledgerErr := r.FailAsset(whodunit.AssetTypeRecognition, err); ledgerErr != nil {
+			log.WithError(ledgerErr).Errorln("Error updating ledger")
+		}
+		return err
 	}
 
 	log.Infoln("Job successfully created")
+	return nil
 }
 
 func (r *Recognition) jobOptions(
-	audio *os.File,
+	audio io.ReadCloser,
 	callbackURL string,
 ) *stv1.CreateJobOptions {
 	return &stv1.CreateJobOptions{
@@ -74,10 +85,24 @@ func (r *Recognition) jobOptions(
 }
 
 // WriteResults writes the specified contents to a new JSON file in the
-// `/recognitions` directory.
+// `/recognitions` directory and records the outcome in the ledger. The
+// recognition is locked while it's written, so results for the same episode
+// that arrive at the same time are written one after the other.
 func (r *Recognition) WriteResults(contents interface{}) error {
-	path := r.AssetFilePath(whodunit.AssetTypeRecognition)
-	return crimeseen.WriteJSONFile(path, contents)
+	unlock, err := r.LockAsset(whodunit.AssetTypeRecognition)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := r.WriteAssetJSON(whodunit.AssetTypeRecognition, contents); err != nil {
+		if ledgerErr := r.FailAsset(whodunit.AssetTypeRecognition, err); ledgerErr != nil {
+			log.WithError(ledgerErr).Errorln("Error updating ledger")
+		}
+		return err
+	}
+
+	return r.CompleteAsset(whodunit.AssetTypeRecognition)
 }
 
 // ReadResults returns the results from the recognition JSON file.
@@ -85,14 +110,7 @@ func (r *Recognition) ReadResults() (
 	[]stv1.SpeechRecognitionResult,
 	error,
 ) {
-	path := r.FilePath()
-	jsonFile, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-
-	defer jsonFile.Close()
-	bytes, err := ioutil.ReadAll(jsonFile)
+	bytes, err := r.ReadAsset(whodunit.AssetTypeRecognition)
 	if err != nil {
 		return nil, err
 	}
@@ -103,6 +121,10 @@ func (r *Recognition) ReadResults() (
 		return nil, err
 	}
 
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no results in %s", r.FileName())
+	}
+
 	return contents[0].Results, nil
 }
 
@@ -111,9 +133,9 @@ func (r *Recognition) Exists() bool {
 	return r.AssetExists(whodunit.AssetTypeRecognition)
 }
 
-// FilePath returns the path to the recognition file in the `/assets` directory.
+// FilePath returns the path to the recognition file in the asset storage.
 func (r *Recognition) FilePath() string {
-	return r.AssetFilePath(whodunit.AssetTypeRecognition)
+	return r.AssetKey(whodunit.AssetTypeRecognition)
 }
 
 // FileName returns the name of the audio file in the `/assets` directory.
@@ -2,37 +2,45 @@ package hearnoevil
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
 
 	"github.com/0xAX/notificator"
 	"github.com/google/uuid"
+	"github.com/mikerourke/forensic-files-api/internal/trailoftruth"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 	stv1 "github.com/watson-developer-cloud/go-sdk/speechtotextv1"
 )
 
-type callbackServer struct{}
-
-var (
+type callbackServer struct {
+	ws             *whodunit.Workspace
 	notify         *notificator.Notificator
-	notifyIconPath = filepath.Join(whodunit.AssetsDirPath, "notify.png")
-)
+	notifyIconPath string
+}
 
-func newCallbackServer() *callbackServer {
-	notify = notificator.New(notificator.Options{
+func newCallbackServer(ws *whodunit.Workspace) *callbackServer {
+	notifyIconPath := filepath.Join(ws.AssetsPath(), "notify.png")
+	notify := notificator.New(notificator.Options{
 		DefaultIcon: notifyIconPath,
 		AppName:     "Forensic Files API",
 	})
 
-	return &callbackServer{}
+	return &callbackServer{
+		ws:             ws,
+		notify:         notify,
+		notifyIconPath: notifyIconPath,
+	}
 }
 
 // Start starts an HTTP server that listens for responses from the
-// speech-to-text service. The server runs on port 9000 and is used to validate
-// registered callback URLs or write recognition results to JSON files.
+// speech-to-text service. The server runs on the port in the `callback.port`
+// setting (9000 by default) and is used to validate registered callback URLs
+// or write recognition results to JSON files.
 func (cs *callbackServer) Start() {
-	log.Infoln("Starting callback URL server on port 9000")
+	port := cs.ws.Config().Callback.Port
+	log.WithField("port", port).Infoln("Starting callback URL server")
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
 		challengeString := r.URL.Query().Get("challenge_string")
@@ -44,7 +52,7 @@ func (cs *callbackServer) Start() {
 	}
 
 	http.HandleFunc("/", handler)
-	log.Fatalln(http.ListenAndServe(":9000", nil))
+	log.Fatalln(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
 }
 
 // onRegister responds to the request to register a new callback
@@ -88,20 +96,30 @@ func (cs *callbackServer) onResponse(r *http.Request) {
 
 	log.WithField("file", userToken).Infoln("Writing results to file")
 
-	ep, err := whodunit.NewEpisodeFromName(userToken)
+	c, err := cs.ws.Catalog()
 	if err != nil {
-		log.WithError(err).Errorln("Unable to get episode from user token")
+		log.WithError(err).Errorln("Unable to load catalog")
+		return
+	}
+
+	ep := c.EpisodeByName(userToken)
+	if ep == nil {
+		log.WithField("token", userToken).Errorln(
+			"Unable to get episode from user token")
 		return
 	}
 
 	rec := NewRecognition(ep)
 	if err = rec.WriteResults(jobContents.Results); err != nil {
 		log.WithError(err).Errorln("Error writing recognition results")
+		return
 	}
 
 	log.WithField("file", userToken).Infoln(
 		"Successfully wrote Recognition to JSON")
+	trailoftruth.Record(r.Context(), ep, whodunit.AssetTypeRecognition,
+		trailoftruth.ProviderIBMSpeechToText)
 
-	notify.Push("Recognition Complete", ep.DisplayTitle(),
-		notifyIconPath, notificator.UR_NORMAL)
+	cs.notify.Push("Recognition Complete", ep.DisplayTitle(),
+		cs.notifyIconPath, notificator.UR_NORMAL)
 }
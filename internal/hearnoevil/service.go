@@ -1,8 +1,6 @@
 package hearnoevil
 
 import (
-	"time"
-
 	"github.com/IBM/go-sdk-core/core"
 	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
 	stv1 "github.com/watson-developer-cloud/go-sdk/speechtotextv1"
@@ -14,9 +12,9 @@ type s2tInstance struct {
 
 // newS2TInstance returns an instance of the speech-to-text service that
 // can be used to register callback URLs and create recognition jobs.
-func newS2TInstance(env *crimeseen.Env) *s2tInstance {
+func newS2TInstance(config *crimeseen.Config) *s2tInstance {
 	authenticator := &core.IamAuthenticator{
-		ApiKey: env.IBMSpeechToTextAPIKey(),
+		ApiKey: config.SpeechToText.APIKey,
 	}
 
 	options := &stv1.SpeechToTextV1Options{
@@ -30,11 +28,11 @@ func newS2TInstance(env *crimeseen.Env) *s2tInstance {
 	}
 
 	// The default timeout is 30 seconds. Depending on the file, that might not
-	// cut the mustard. We're increasing it to 90 seconds to make sure the files
-	// go through:
-	speechToText.Service.Client.Timeout = time.Second * 90
+	// cut the mustard, so it's configurable (90 seconds by default) to make
+	// sure the files go through:
+	speechToText.Service.Client.Timeout = config.SpeechToText.Timeout
 
-	err = speechToText.SetServiceURL(env.IBMSpeechToTextAPIUrl())
+	err = speechToText.SetServiceURL(config.SpeechToText.URL)
 	if err != nil {
 		log.WithError(err).Fatalln("Error setting service URL")
 	}
@@ -0,0 +1,131 @@
+package housecall
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// binary is an external executable that one or more of the commands run.
+type binary struct {
+	name        string
+	versionArgs []string
+	usedFor     string
+
+	// required returns true if the commands can't be used without the
+	// executable.
+	required func(config *crimeseen.Config) bool
+}
+
+// versionTimeout is how long an executable has to print its version.
+const versionTimeout = 10 * time.Second
+
+var binaries = []*binary{
+	{
+		name:        "youtube-dl",
+		versionArgs: []string{"--version"},
+		usedFor:     "downloading videos",
+		required:    always,
+	},
+	{
+		name:        "ffmpeg",
+		versionArgs: []string{"-version"},
+		usedFor:     "extracting audio",
+		required:    always,
+	},
+	{
+		name:        "ffprobe",
+		versionArgs: []string{"-version"},
+		usedFor:     "probing and verifying media",
+		required:    always,
+	},
+	{
+		name:        "git",
+		versionArgs: []string{"--version"},
+		usedFor:     "committing assets",
+		required: func(config *crimeseen.Config) bool {
+			return config.GitAutoCommit
+		},
+	},
+	{
+		name:        "ngrok",
+		versionArgs: []string{"version"},
+		usedFor:     "exposing the callback server",
+		required:    never,
+	},
+}
+
+func always(*crimeseen.Config) bool { return true }
+
+func never(*crimeseen.Config) bool { return false }
+
+// RequireBinary returns an error if the executable with the specified name
+// can't be found, so commands that need it fail before doing any work.
+func RequireBinary(name string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("could not find %s executable, it may not be installed "+
+			"(run `alibi doctor` to check the setup)", name)
+	}
+
+	return nil
+}
+
+// checkBinaries checks that every executable can be found and reports its
+// version.
+func checkBinaries(config *crimeseen.Config) []*Result {
+	results := make([]*Result, 0, len(binaries))
+	for _, b := range binaries {
+		check := "binary " + b.name
+		if err := RequireBinary(b.name); err != nil {
+			if b.required(config) {
+				results = append(results, fail(check, "not found, needed for %s", b.usedFor))
+			} else {
+				results = append(results, warn(check, "not found, needed for %s", b.usedFor))
+			}
+			continue
+		}
+
+		version, err := b.version()
+		if err != nil {
+			results = append(results, warn(check, "found, but unable to get version: %v", err))
+			continue
+		}
+
+		results = append(results, pass(check, "%s", version))
+	}
+
+	return results
+}
+
+// version returns the first line the executable prints when asked for its
+// version without the copyright notice (e.g. "ffmpeg version 4.2.2").
+func (b *binary) version() (string, error) {
+	version := ""
+	cmd := &crimeseen.Command{
+		Name:    b.name,
+		Args:    b.versionArgs,
+		Timeout: versionTimeout,
+		Progress: func(line string) {
+			if version == "" {
+				version = line
+			}
+		},
+	}
+	if err := cmd.Run(context.Background()); err != nil {
+		return "", err
+	}
+
+	if index := strings.Index(version, " Copyright"); index != -1 {
+		version = version[:index]
+	}
+
+	if version == "" {
+		return "", fmt.Errorf("%s didn't print a version", b.name)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,60 @@
+package housecall
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// NgrokRunning returns true if ngrok is forwarding requests to the specified
+// port, which is how the speech-to-text service reaches the callback server.
+func NgrokRunning(port int) bool {
+	ngrokCommand := fmt.Sprintf("ngrok http %d", port)
+	out, err := exec.Command("ps", "aux").Output()
+	if err != nil {
+		return false
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), ngrokCommand) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkCallback checks that the callback server can listen on the configured
+// port and that ngrok is forwarding requests to it. Invalid ports are
+// reported by checkConfig.
+func checkCallback(config *crimeseen.Config) []*Result {
+	port := config.Callback.Port
+	if port < 1 || port > 65535 {
+		return nil
+	}
+
+	results := make([]*Result, 0, 2)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		results = append(results, warn("callback port",
+			"port %d is in use, the callback server may already be running", port))
+	} else {
+		listener.Close()
+		results = append(results, pass("callback port", "port %d is free", port))
+	}
+
+	if NgrokRunning(port) {
+		results = append(results, pass("ngrok", "forwarding to port %d", port))
+	} else {
+		results = append(results, warn("ngrok",
+			"not running, run `ngrok http %d` before recognizing audio", port))
+	}
+
+	return results
+}
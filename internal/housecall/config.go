@@ -0,0 +1,157 @@
+package housecall
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// optionalSetting is a setting that's only needed by some of the commands.
+type optionalSetting struct {
+	key     string
+	usedFor string
+}
+
+var optionalSettings = []*optionalSetting{
+	{key: "speechToText.apiKey", usedFor: "recognizing audio"},
+	{key: "speechToText.url", usedFor: "recognizing audio"},
+	{key: "callback.url", usedFor: "receiving recognition results"},
+	{key: "naturalLanguage.apiKey", usedFor: "IBM analyses"},
+	{key: "naturalLanguage.url", usedFor: "IBM analyses"},
+	{key: "gcp.credentialsPath", usedFor: "GCP analyses"},
+	{key: "omdb.apiKey", usedFor: "syncing the catalog"},
+}
+
+// ibmKeyPattern matches the format of IBM Cloud API keys.
+var ibmKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{44}$`)
+
+// checkConfig checks that the config is valid and reports the settings that
+// are missing.
+func checkConfig(config *crimeseen.Config) []*Result {
+	results := make([]*Result, 0)
+	if config.Path() == "" && !crimeseen.FileExists(config.DotEnvPath()) {
+		results = append(results, warn("config",
+			"no %s or %s file found, only environment variables are used",
+			filepath.Join(config.Dir(), crimeseen.DefaultConfigPath), config.DotEnvPath()))
+	}
+
+	if err := config.Validate(); err == nil {
+		path := config.Path()
+		if path == "" {
+			path = "no config file"
+		}
+		results = append(results, pass("config", "every setting is valid (%s)", path))
+	} else {
+		if configErr, ok := err.(*crimeseen.ConfigError); ok {
+			for _, problem := range configErr.Problems {
+				results = append(results, fail("config", "%s", problem))
+			}
+		} else {
+			results = append(results, fail("config", "%s", err))
+		}
+	}
+
+	values := make(map[string]*crimeseen.Setting)
+	for _, s := range config.Settings() {
+		values[s.Key] = s
+	}
+
+	for _, opt := range optionalSettings {
+		s := values[opt.key]
+		if s.Value() == "" {
+			results = append(results, warn("setting "+opt.key,
+				"not set (%s), needed for %s", s.EnvVar, opt.usedFor))
+		}
+	}
+
+	return results
+}
+
+// gcpCredentials contains the fields of a GCP credentials file that are
+// checked.
+type gcpCredentials struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	ClientID     string `json:"client_id"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// checkGCPCredentials checks that the GCP credentials file can be read and
+// contains a service account key or user credentials. Missing credentials are
+// reported by checkConfig.
+func checkGCPCredentials(config *crimeseen.Config) []*Result {
+	const check = "gcp credentials"
+	path := config.GCP.CredentialsPath
+	if path == "" {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []*Result{fail(check, "unable to read %s: %v", path, err)}
+	}
+
+	var creds gcpCredentials
+	if err := json.Unmarshal(contents, &creds); err != nil {
+		return []*Result{fail(check, "%s isn't a valid JSON file: %v", path, err)}
+	}
+
+	switch creds.Type {
+	case "service_account":
+		if creds.ClientEmail == "" || !strings.Contains(creds.PrivateKey, "PRIVATE KEY") {
+			return []*Result{fail(check,
+				"%s is missing the client email or private key", path)}
+		}
+		return []*Result{pass(check, "service account %s (project %s)",
+			creds.ClientEmail, creds.ProjectID)}
+
+	case "authorized_user":
+		if creds.ClientID == "" || creds.RefreshToken == "" {
+			return []*Result{fail(check,
+				"%s is missing the client ID or refresh token", path)}
+		}
+		return []*Result{pass(check, "user credentials (client %s)", creds.ClientID)}
+
+	case "":
+		return []*Result{fail(check, "%s doesn't have a credentials type", path)}
+
+	default:
+		return []*Result{warn(check, "%s has unexpected credentials type %q",
+			path, creds.Type)}
+	}
+}
+
+// checkIBMKeys checks that the IBM API keys look like IBM Cloud API keys,
+// which catches keys that were copied with extra characters or truncated.
+// Missing keys are reported by checkConfig.
+func checkIBMKeys(config *crimeseen.Config) []*Result {
+	keys := []struct {
+		check string
+		value string
+	}{
+		{"ibm speech-to-text key", config.SpeechToText.APIKey},
+		{"ibm natural language key", config.NaturalLanguage.APIKey},
+	}
+
+	results := make([]*Result, 0, len(keys))
+	for _, key := range keys {
+		switch {
+		case key.value == "":
+			continue
+		case ibmKeyPattern.MatchString(key.value):
+			results = append(results, pass(key.check, "looks like an IBM Cloud API key"))
+		default:
+			results = append(results, warn(key.check,
+				"doesn't look like an IBM Cloud API key (expected 44 letters, digits, "+
+					"dashes, or underscores, got %d characters)", len(key.value)))
+		}
+	}
+
+	return results
+}
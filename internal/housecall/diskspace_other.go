@@ -0,0 +1,11 @@
+//go:build !darwin && !freebsd && !linux
+// +build !darwin,!freebsd,!linux
+
+package housecall
+
+import "errors"
+
+// freeSpace isn't supported on this platform.
+func freeSpace(path string) (uint64, error) {
+	return 0, errors.New("not supported on this platform")
+}
@@ -0,0 +1,17 @@
+//go:build darwin || freebsd || linux
+// +build darwin freebsd linux
+
+package housecall
+
+import "syscall"
+
+// freeSpace returns the number of bytes available to the user on the
+// filesystem that contains the specified path.
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
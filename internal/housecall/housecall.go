@@ -0,0 +1,98 @@
+// Package housecall checks the environment the tools run in (the external
+// executables, the settings, the investigations directory, and so on) and
+// reports what needs fixing before any work is started.
+package housecall
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusPass means nothing needs to be done.
+	StatusPass Status = "pass"
+
+	// StatusWarn means some of the commands won't work, but the ones that
+	// don't need the missing piece will.
+	StatusWarn Status = "warn"
+
+	// StatusFail means the problem needs to be fixed before the tools can be
+	// used.
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Check   string
+	Status  Status
+	Message string
+}
+
+// Examine runs every check against the specified workspace and returns the
+// results in the order they were run. The config of the workspace doesn't need
+// to be valid, since invalid settings are reported as failures.
+func Examine(ws *whodunit.Workspace) []*Result {
+	config := ws.Config()
+	results := make([]*Result, 0)
+	results = append(results, checkBinaries(config)...)
+	results = append(results, checkConfig(config)...)
+	results = append(results, checkGCPCredentials(config)...)
+	results = append(results, checkIBMKeys(config)...)
+	results = append(results, checkCatalog(ws)...)
+	results = append(results, checkInvestigationsPath(config)...)
+	results = append(results, checkDiskSpace(config)...)
+	results = append(results, checkCallback(config)...)
+	return results
+}
+
+// Failed returns true if any of the specified results failed.
+func Failed(results []*Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Report writes the specified results to the specified writer as a table with
+// the count of each status in the footer.
+func Report(w io.Writer, results []*Result) {
+	counts := make(map[Status]int)
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Check", "Status", "Details"})
+	for _, r := range results {
+		table.Append([]string{r.Check, strings.ToUpper(string(r.Status)), r.Message})
+		counts[r.Status]++
+	}
+	table.SetFooter([]string{
+		"Total",
+		fmt.Sprintf("%d pass, %d warn, %d fail",
+			counts[StatusPass], counts[StatusWarn], counts[StatusFail]),
+		"",
+	})
+	table.Render()
+}
+
+func pass(check string, format string, args ...interface{}) *Result {
+	return &Result{Check: check, Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(check string, format string, args ...interface{}) *Result {
+	return &Result{Check: check, Status: StatusWarn, Message: fmt.Sprintf(format, args...)}
+}
+
+func fail(check string, format string, args ...interface{}) *Result {
+	return &Result{Check: check, Status: StatusFail, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,119 @@
+package housecall
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+)
+
+const (
+	// lowDiskSpace is the free space below which a warning is reported. A
+	// single episode video is a few hundred MB, so this leaves room for a
+	// couple of seasons.
+	lowDiskSpace = 10 << 30
+
+	// criticalDiskSpace is the free space below which the check fails, since
+	// a download or extraction is likely to run out of space partway.
+	criticalDiskSpace = 1 << 30
+)
+
+// checkCatalog checks that the episodes JSON file can be loaded, which fails
+// if the workspace isn't the root of the repo.
+func checkCatalog(ws *whodunit.Workspace) []*Result {
+	const check = "catalog"
+	c, err := ws.Catalog()
+	if err != nil {
+		return []*Result{fail(check, "unable to load %s: %v", ws.CatalogPath(), err)}
+	}
+
+	return []*Result{pass(check, "%d episodes in %s", c.EpisodeCount(), ws.CatalogPath())}
+}
+
+// checkInvestigationsPath checks that the investigations directory exists and
+// that files can be written to it. The ledger, manifests, and locks are always
+// kept there, even if the assets are stored somewhere else.
+func checkInvestigationsPath(config *crimeseen.Config) []*Result {
+	const check = "investigations path"
+	path := config.InvestigationsPath
+	if path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return []*Result{fail(check, "%s doesn't exist", path)}
+	}
+
+	if !info.IsDir() {
+		return []*Result{fail(check, "%s isn't a directory", path)}
+	}
+
+	temp, err := ioutil.TempFile(path, ".alibi-doctor-*")
+	if err != nil {
+		return []*Result{fail(check, "%s isn't writable: %v", path, err)}
+	}
+	temp.Close()
+	os.Remove(temp.Name())
+
+	return []*Result{pass(check, "%s is writable", path)}
+}
+
+// checkDiskSpace checks the free space on the filesystem of each asset
+// directory. Assets that aren't stored locally are skipped.
+func checkDiskSpace(config *crimeseen.Config) []*Result {
+	if strings.ToLower(config.AssetStorage) == "s3" {
+		return []*Result{pass("disk space", "assets are stored in S3 bucket %s", config.S3.Bucket)}
+	}
+
+	if config.InvestigationsPath == "" {
+		return nil
+	}
+
+	results := make([]*Result, 0)
+	for _, assetType := range whodunit.AllAssetTypes() {
+		check := "disk space " + assetType.DirName()
+		dirPath := existingDir(filepath.Join(config.InvestigationsPath, assetType.DirName()))
+		if dirPath == "" {
+			continue
+		}
+
+		free, err := freeSpace(dirPath)
+		if err != nil {
+			results = append(results, warn(check, "unable to check free space: %v", err))
+			continue
+		}
+
+		formatted := crimeseen.FormatBytes(int64(free))
+		switch {
+		case free < criticalDiskSpace:
+			results = append(results, fail(check, "only %s free in %s", formatted, dirPath))
+		case free < lowDiskSpace:
+			results = append(results, warn(check, "only %s free in %s", formatted, dirPath))
+		default:
+			results = append(results, pass(check, "%s free", formatted))
+		}
+	}
+
+	return results
+}
+
+// existingDir returns the specified directory or its closest parent that
+// exists, so the free space can be checked before the directory is created.
+// It returns an empty string if none of them exist.
+func existingDir(path string) string {
+	for {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return ""
+		}
+		path = parent
+	}
+}
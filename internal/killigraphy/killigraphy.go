@@ -3,27 +3,34 @@
 package killigraphy
 
 import (
+	"context"
+
 	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 )
 
 var log = waterlogged.New("killigraphy")
 
-// Transcribe creates a transcript for the specified episode number from the
-// specified season number or all seasons.
-func Transcribe(seasonNumber int, episodeNumber int) {
-	onEpisode := func(ep *whodunit.Episode) {
+// Transcribe creates a transcript for each episode in the specified selection
+// of the specified workspace, creating up to the specified number of
+// transcripts at the same time.
+func Transcribe(
+	ctx context.Context,
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	concurrency int,
+) error {
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
 		t := NewTranscript(ep)
-		t.Create()
+		return t.Create()
 	}
 
-	if err := whodunit.Solve(seasonNumber, episodeNumber, onEpisode); err != nil {
+	summary, err := ws.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
 		log.WithError(err).Errorln("Error transcribing episode(s)")
+		return err
 	}
-}
 
-// Investigate logs the episode statuses.
-func Investigate(status whodunit.AssetStatus) {
-	table := whodunit.NewStatusTable(whodunit.AssetTypeTranscript, status)
-	table.Log()
+	summary.Log(log)
+	return summary.Err()
 }
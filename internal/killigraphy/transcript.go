@@ -1,12 +1,13 @@
 package killigraphy
 
 import (
-	"io"
-	"io/ioutil"
-	"os"
+	"context"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/mikerourke/forensic-files-api/internal/hearnoevil"
+	"github.com/mikerourke/forensic-files-api/internal/trailoftruth"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 )
 
@@ -24,7 +25,7 @@ func NewTranscript(ep *whodunit.Episode) *Transcript {
 
 // Read returns the contents of the transcript file.
 func (t *Transcript) Read() string {
-	contents, err := ioutil.ReadFile(t.FilePath())
+	contents, err := t.ReadAsset(whodunit.AssetTypeTranscript)
 	if err != nil {
 		log.WithError(err).Errorln("Error reading transcript file")
 		return ""
@@ -34,66 +35,75 @@ func (t *Transcript) Read() string {
 }
 
 // Create creates a transcript file from a recognition.
-func (t *Transcript) Create() {
-	if t.Exists() {
+func (t *Transcript) Create() error {
+	if t.Exists() && !t.AssetStale(whodunit.AssetTypeTranscript) {
 		log.WithField("file", t.FileName()).Warnln(
 			"Transcript already exists, skipping")
-		return
+		return nil
 	}
 
-	contents := t.recognitionContents()
-	if contents == "" {
-		return
+	r := hearnoevil.NewRecognition(t.Episode)
+	if !r.AssetComplete(whodunit.AssetTypeRecognition) {
+		log.WithField("file", r.FileName()).Warnln(
+			"Recognition not found or not complete, skipping")
+		return nil
 	}
 
-	file, err := os.Create(t.FilePath())
+	err := t.Track(whodunit.AssetTypeTranscript, func() error {
+		return t.write(r)
+	})
 	if err != nil {
-		log.WithError(err).Errorln("Error creating transcript file")
-		return
-	}
-	defer file.Close()
-
-	if _, err = io.WriteString(file, contents); err != nil {
-		log.WithError(err).Errorln("Error writing transcript file")
-		return
-	}
-
-	if err := file.Sync(); err != nil {
-		log.WithError(err).Errorln("Error syncing transcript file")
+		return err
 	}
 
 	log.WithField("file", t.FileName()).Infoln("Transcript successfully written")
+	trailoftruth.Record(context.Background(), t.Episode, whodunit.AssetTypeTranscript, trailoftruth.ProviderAlibi)
+	return nil
 }
 
-func (t *Transcript) recognitionContents() string {
-	r := hearnoevil.NewRecognition(t.Episode)
-	if !r.Exists() {
-		log.WithField("file", r.FileName()).Warnln(
-			"Recognition not found, skipping")
-		return ""
+func (t *Transcript) write(r *hearnoevil.Recognition) error {
+	contents, err := t.recognitionContents(r)
+	if err != nil {
+		return err
 	}
 
-	if t.Exists() {
-		log.WithField("file", t.FileName()).Warnln(
-			"Transcript already exists, skipping")
+	if contents == "" {
+		return errors.New("recognition has no lines that meet the confidence threshold")
+	}
+
+	err = t.WriteAsset(whodunit.AssetTypeTranscript, []byte(contents))
+	if err != nil {
+		return fmt.Errorf("error writing transcript file: %w", err)
 	}
 
+	return nil
+}
+
+func (t *Transcript) recognitionContents(
+	r *hearnoevil.Recognition,
+) (string, error) {
 	results, err := r.ReadResults()
 	if err != nil {
-		log.WithError(err).Fatalln("Error getting recognition results")
+		return "", fmt.Errorf("error getting recognition results: %w", err)
+	}
+
+	ws, err := t.Workspace()
+	if err != nil {
+		return "", err
 	}
 
+	minConfidence := ws.Config().Transcript.MinConfidence
 	lines := make([]string, 0)
 	for _, result := range results {
 		for _, alt := range result.Alternatives {
 			content := *alt.Transcript
 			words := strings.Fields(content)
-			var confidence float32
+			var confidence float64
 			if alt.Confidence != nil {
-				confidence = float32(*alt.Confidence)
+				confidence = *alt.Confidence
 			}
 
-			if confidence >= 0.7 && len(words) > 2 {
+			if confidence >= minConfidence && len(words) > 2 {
 				validWords := make([]string, 0)
 				for i, word := range words {
 					if !strings.Contains(word, "%HESITATION") {
@@ -114,7 +124,7 @@ func (t *Transcript) recognitionContents() string {
 		}
 	}
 
-	return strings.Join(lines, "\n")
+	return strings.Join(lines, "\n"), nil
 }
 
 // Exists return true if the transcript file exists in the `/assets` directory.
@@ -122,9 +132,9 @@ func (t *Transcript) Exists() bool {
 	return t.AssetExists(whodunit.AssetTypeTranscript)
 }
 
-// FilePath returns the path to the transcript file in the `/assets` directory.
+// FilePath returns the path to the transcript file in the asset storage.
 func (t *Transcript) FilePath() string {
-	return t.AssetFilePath(whodunit.AssetTypeTranscript)
+	return t.AssetKey(whodunit.AssetTypeTranscript)
 }
 
 // FileName returns the name of the transcript file in the `/assets` directory.
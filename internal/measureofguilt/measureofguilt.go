@@ -0,0 +1,101 @@
+// Package measureofguilt probes the video and audio files with ffprobe and
+// records the container, duration, codecs, and bitrate of each one in the
+// episode catalog.
+package measureofguilt
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/mikerourke/forensic-files-api/internal/sharperimage"
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/sirupsen/logrus"
+)
+
+// DurationTolerance is the maximum difference in seconds between the length of
+// the video and the audio extracted from it before the audio is considered
+// incomplete.
+const DurationTolerance = 2.0
+
+var log = waterlogged.New("measureofguilt")
+
+// Measure probes the assets of the specified media types (video and/or audio)
+// for each episode in the specified selection of the specified workspace,
+// probing up to the specified number of episodes at the same time, and saves
+// the results to the catalog. If both the video and audio were probed, the
+// episode fails if the durations don't match.
+func Measure(
+	ctx context.Context,
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	concurrency int,
+	assetTypes []whodunit.AssetType,
+) error {
+	if !sharperimage.IsInstalled() {
+		return sharperimage.ErrNotInstalled
+	}
+
+	c, err := ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
+		for _, assetType := range assetTypes {
+			if !ep.AssetExists(assetType) {
+				continue
+			}
+
+			info, err := ep.ProbeMedia(ctx, assetType)
+			if err != nil {
+				return fmt.Errorf("error probing %s: %w",
+					ep.AssetFileName(assetType), err)
+			}
+
+			ep.SetMediaInfo(assetType, info)
+			log.WithFields(logrus.Fields{
+				"file":      info.Path,
+				"container": info.Container,
+				"duration":  info.Length().String(),
+			}).Infoln("Probed media")
+		}
+
+		return compareDurations(ep)
+	}
+
+	summary, err := c.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
+		log.WithError(err).Errorln("Error probing episode(s)")
+		return err
+	}
+
+	summary.Log(log)
+
+	if len(summary.Succeeded()) != 0 {
+		if err := ws.SaveCatalog(); err != nil {
+			return fmt.Errorf("error saving catalog: %w", err)
+		}
+	}
+
+	return summary.Err()
+}
+
+// compareDurations returns an error if the audio recorded for the episode is
+// a different length than the video it was extracted from.
+func compareDurations(ep *whodunit.Episode) error {
+	video := ep.MediaInfo(whodunit.AssetTypeVideo)
+	audio := ep.MediaInfo(whodunit.AssetTypeAudio)
+	if video == nil || audio == nil {
+		return nil
+	}
+
+	difference := audio.Duration - video.Duration
+	if math.Abs(difference) > DurationTolerance {
+		return fmt.Errorf("audio is %.1fs long, but video is %.1fs long",
+			audio.Duration, video.Duration)
+	}
+
+	return nil
+}
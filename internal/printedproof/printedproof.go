@@ -0,0 +1,182 @@
+// Package printedproof verifies the asset files against the season manifests
+// to find files that were truncated or corrupted (e.g. by a killed youtube-dl
+// or ffmpeg run), so they aren't sent to any of the paid APIs.
+package printedproof
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/sharperimage"
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/sirupsen/logrus"
+)
+
+// Options contains the options used to verify assets.
+type Options struct {
+	// AssetTypes are the asset types to verify.
+	AssetTypes []whodunit.AssetType
+
+	// Deep has ffprobe read every packet of video and audio files instead of
+	// just the headers, which catches files that were truncated partway.
+	Deep bool
+
+	// Reset moves corrupt files aside and resets their status to pending, so
+	// they get processed again. If false, corrupt assets are flagged as
+	// failed in the ledger.
+	Reset bool
+}
+
+var log = waterlogged.New("printedproof")
+
+// CorruptSuffix is appended to the name of corrupt files that are moved aside
+// when resetting them.
+const CorruptSuffix = ".corrupt"
+
+// Verify checks the assets of each episode in the specified selection of the
+// specified workspace against the season manifests, verifying up to the
+// specified number of episodes at the same time. Assets that pass verification
+// are added to the manifest if they weren't already in it.
+func Verify(
+	ctx context.Context,
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	concurrency int,
+	opts Options,
+) error {
+	if err := interrogate(opts.AssetTypes); err != nil {
+		return err
+	}
+
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
+		corruptCount := 0
+		for _, assetType := range opts.AssetTypes {
+			err := verifyAsset(ctx, ep, assetType, opts)
+			if err == nil {
+				continue
+			}
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			corruptCount++
+			log.WithFields(logrus.Fields{
+				"file":  ep.AssetFileName(assetType),
+				"error": err,
+			}).Warnln("Corrupt asset found")
+
+			if err := condemn(ep, assetType, err, opts.Reset); err != nil {
+				return err
+			}
+		}
+
+		if corruptCount != 0 {
+			return fmt.Errorf("%d corrupt asset(s) found", corruptCount)
+		}
+		return nil
+	}
+
+	summary, err := ws.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
+		log.WithError(err).Errorln("Error verifying episode(s)")
+		return err
+	}
+
+	summary.Log(log)
+	return summary.Err()
+}
+
+// verifyAsset returns an error describing why the asset is corrupt or nil if
+// the asset is fine (or there's nothing to verify).
+func verifyAsset(
+	ctx context.Context,
+	ep *whodunit.Episode,
+	assetType whodunit.AssetType,
+	opts Options,
+) error {
+	recorded := ep.ManifestEntry(assetType)
+	if !ep.AssetExists(assetType) {
+		if recorded != nil {
+			return fmt.Errorf("file recorded in manifest is missing")
+		}
+		return nil
+	}
+
+	current, err := ep.Fingerprint(ctx, assetType)
+	if err != nil {
+		return err
+	}
+
+	if recorded != nil && !recorded.Matches(current) {
+		return fmt.Errorf("contents changed since recorded (size %d, now %d)",
+			recorded.Size, current.Size)
+	}
+
+	if opts.Deep && assetType.IsMedia() {
+		ws, err := ep.Workspace()
+		if err != nil {
+			return err
+		}
+
+		localPath, release, err := ep.FetchAsset(assetType)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		if err := sharperimage.ScanFile(ctx, localPath, ws.Config().FFmpeg.Timeout); err != nil {
+			return err
+		}
+	}
+
+	if recorded != nil {
+		current.RecordedAt = recorded.RecordedAt
+	}
+
+	verifiedAt := time.Now().UTC()
+	current.VerifiedAt = &verifiedAt
+	return ep.RecordManifestEntry(current)
+}
+
+// condemn flags the corrupt asset as failed in the ledger or, if reset is
+// true, moves the file aside and resets the asset to pending.
+func condemn(
+	ep *whodunit.Episode,
+	assetType whodunit.AssetType,
+	cause error,
+	reset bool,
+) error {
+	reason := fmt.Sprintf("corrupt: %v", cause)
+	if !reset {
+		return ep.FailAsset(assetType, fmt.Errorf("%s", reason))
+	}
+
+	if ep.AssetExists(assetType) {
+		if err := ep.QuarantineAsset(assetType, CorruptSuffix); err != nil {
+			return err
+		}
+	}
+
+	if err := ep.RemoveManifestEntry(assetType); err != nil {
+		return err
+	}
+
+	log.WithField("file", ep.AssetFileName(assetType)).Infoln(
+		"Moved corrupt file aside and reset to pending")
+	return ep.ResetAsset(assetType, reason)
+}
+
+// interrogate returns an error if ffprobe is needed to verify any of the
+// specified asset types and it isn't installed.
+func interrogate(assetTypes []whodunit.AssetType) error {
+	for _, assetType := range assetTypes {
+		if assetType.IsMedia() && !sharperimage.IsInstalled() {
+			return sharperimage.ErrNotInstalled
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package sharperimage examines the video and audio files with ffprobe to get
+// details about the media and make sure the files aren't corrupt.
+package sharperimage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// ErrNotInstalled is returned when the ffprobe executable can't be found.
+var ErrNotInstalled = errors.New("could not find ffprobe executable, it may not be installed")
+
+// Probe contains the details ffprobe reported for a media file. The codecs
+// are taken from the first video and audio stream in the file.
+type Probe struct {
+	FormatName string
+	Duration   time.Duration
+	Size       int64
+	BitRate    int64
+	VideoCodec string
+	AudioCodec string
+	SampleRate int
+}
+
+// probeOutput is the JSON output from ffprobe.
+type probeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		Size       string `json:"size"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType  string `json:"codec_type"`
+		CodecName  string `json:"codec_name"`
+		SampleRate string `json:"sample_rate"`
+	} `json:"streams"`
+}
+
+// IsInstalled returns true if the ffprobe executable can be found.
+func IsInstalled() bool {
+	_, err := exec.LookPath("ffprobe")
+	return err == nil
+}
+
+// ProbeFile runs ffprobe on the file at the specified path and returns the
+// details of the media. It returns an error if ffprobe can't read the file or
+// doesn't finish before the timeout.
+func ProbeFile(ctx context.Context, path string, timeout time.Duration) (*Probe, error) {
+	stdout, err := ffprobe(ctx, timeout,
+		"-v", "error",
+		"-show_format",
+		"-show_streams",
+		"-of", "json",
+		path)
+	if err != nil {
+		return nil, err
+	}
+
+	var output probeOutput
+	if err := json.Unmarshal(stdout, &output); err != nil {
+		return nil, fmt.Errorf("error parsing ffprobe output: %w", err)
+	}
+
+	seconds, err := strconv.ParseFloat(output.Format.Duration, 64)
+	if err != nil || seconds <= 0 {
+		return nil, fmt.Errorf("ffprobe could not determine duration of %s", path)
+	}
+
+	size, _ := strconv.ParseInt(output.Format.Size, 10, 64)
+	bitRate, _ := strconv.ParseInt(output.Format.BitRate, 10, 64)
+
+	probe := &Probe{
+		FormatName: output.Format.FormatName,
+		Duration:   time.Duration(seconds * float64(time.Second)),
+		Size:       size,
+		BitRate:    bitRate,
+	}
+
+	for _, stream := range output.Streams {
+		switch stream.CodecType {
+		case "video":
+			if probe.VideoCodec == "" {
+				probe.VideoCodec = stream.CodecName
+			}
+
+		case "audio":
+			if probe.AudioCodec == "" {
+				probe.AudioCodec = stream.CodecName
+				probe.SampleRate, _ = strconv.Atoi(stream.SampleRate)
+			}
+		}
+	}
+
+	return probe, nil
+}
+
+// ScanFile has ffprobe read every packet in the file at the specified path
+// and returns an error if any problems were reported. This takes a lot longer
+// than ProbeFile, but catches files that were truncated partway through.
+func ScanFile(ctx context.Context, path string, timeout time.Duration) error {
+	_, err := ffprobe(ctx, timeout,
+		"-v", "error",
+		"-count_packets",
+		"-show_entries", "stream=nb_read_packets",
+		"-of", "json",
+		path)
+	return err
+}
+
+// ffprobe runs ffprobe with the specified arguments and returns the output.
+// Anything written to stderr is treated as an error, since ffprobe is always
+// run with `-v error`.
+func ffprobe(ctx context.Context, timeout time.Duration, args ...string) ([]byte, error) {
+	if !IsInstalled() {
+		return nil, ErrNotInstalled
+	}
+
+	var stdout bytes.Buffer
+	cmd := &crimeseen.Command{
+		Name:         "ffprobe",
+		Args:         args,
+		Timeout:      timeout,
+		Stdout:       &stdout,
+		FailOnStderr: true,
+	}
+
+	if err := cmd.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
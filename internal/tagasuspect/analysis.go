@@ -1,19 +1,19 @@
 package tagasuspect
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/IBM/go-sdk-core/core"
-	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
 	"github.com/mikerourke/forensic-files-api/internal/killigraphy"
+	"github.com/mikerourke/forensic-files-api/internal/trailoftruth"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
-	"github.com/sirupsen/logrus"
 	nluv1 "github.com/watson-developer-cloud/go-sdk/naturallanguageunderstandingv1"
 	languagepb "google.golang.org/genproto/googleapis/cloud/language/v1"
 )
@@ -35,24 +35,21 @@ func newAnalysis(ep *whodunit.Episode, d *Detective) *Analysis {
 	return &Analysis{
 		Episode:   ep,
 		detective: d,
-		assetType: assetTypeForCloudService(d.cloudService),
+		assetType: AssetTypeForCloudService(d.cloudService),
 	}
 }
 
 // WriteCSV converts the entities to CSV records and writes the results to a file.
-func (a *Analysis) WriteCSV(outputDir string) {
+func (a *Analysis) WriteCSV(outputDir string) error {
 	if !a.Exists() {
 		log.WithField("file", a.FileName()).Warnln(
 			"Analysis does not exist, skipping")
-		return
+		return nil
 	}
 
 	entities, err := a.ReadResults()
 	if err != nil {
-		log.WithFields(logrus.Fields{
-			"file":  a.FileName(),
-			"error": err,
-		}).Fatalln("Unable to read the JSON file")
+		return fmt.Errorf("unable to read %s: %w", a.FileName(), err)
 	}
 
 	records := make([][]string, 0)
@@ -65,64 +62,80 @@ func (a *Analysis) WriteCSV(outputDir string) {
 
 	f, err := os.Create(a.csvFilePath(outputDir))
 	if err != nil {
-		log.WithError(err).Fatalln("Error creating CSV file")
+		return fmt.Errorf("error creating CSV file: %w", err)
 	}
 
 	defer f.Close()
 	w := csv.NewWriter(f)
 	for _, record := range records {
 		if err := w.Write(record); err != nil {
-			log.WithError(err).Fatalln("Error writing record to CSV")
+			return fmt.Errorf("error writing record to CSV: %w", err)
 		}
 	}
 
 	w.Flush()
 	if err := w.Error(); err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	log.WithField("file", a.FileName()).Infoln(
 		"Successfully created CSV file")
+	return nil
 }
 
 // Create creates a new analysis file by sending the transcript to the NLP
 // service and writing the results to the `/assets` directory.
-func (a *Analysis) Create(overwrite bool) {
+func (a *Analysis) Create(ctx context.Context, overwrite bool) error {
 	t := killigraphy.NewTranscript(a.Episode)
-	if !t.Exists() {
+	if !t.AssetComplete(whodunit.AssetTypeTranscript) {
 		log.WithField("file", t.FileName()).Warnln(
-			"Transcript not found, skipping")
-		return
+			"Transcript not found or not complete, skipping")
+		return nil
 	}
 
-	if a.Exists() && !overwrite {
+	if a.Exists() && !overwrite && !a.AssetStale(a.assetType) {
 		log.WithField("file", a.FileName()).Warnln(
 			"Analysis already exists, skipping")
-		return
+		return nil
 	}
 
 	log.WithField("file", a.FileName()).Infoln("Starting analysis")
 
+	err := a.Track(a.assetType, func() error {
+		return a.write(ctx, t)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("Analysis successfully written")
+	trailoftruth.Record(ctx, a.Episode, a.assetType, a.provider())
+	return nil
+}
+
+func (a *Analysis) write(ctx context.Context, t *killigraphy.Transcript) error {
 	var result interface{}
 	var err error
 	if a.detective.cloudService == CloudServiceGCP {
-		result, err = a.gcpAPIResult(t.Read())
+		result, err = a.gcpAPIResult(ctx, t.Read())
 	} else {
 		result, err = a.ibmAPIResult(t.Read())
 	}
 	if err != nil {
-		log.WithError(err).Errorln("Error submitting analysis request")
+		return fmt.Errorf("error submitting analysis request: %w", err)
 	}
 
-	if err := crimeseen.WriteJSONFile(a.FilePath(), result); err != nil {
-		log.WithError(err).Errorln("Error writing analysis file")
-		return
+	if err := a.WriteAssetJSON(a.assetType, result); err != nil {
+		return fmt.Errorf("error writing analysis file: %w", err)
 	}
 
-	log.Infoln("Analysis successfully written")
+	return nil
 }
 
-func (a *Analysis) gcpAPIResult(contents string) (interface{}, error) {
+func (a *Analysis) gcpAPIResult(
+	ctx context.Context,
+	contents string,
+) (interface{}, error) {
 	doc := &languagepb.Document{
 		Type: languagepb.Document_PLAIN_TEXT,
 		Source: &languagepb.Document_Content{
@@ -135,7 +148,7 @@ func (a *Analysis) gcpAPIResult(contents string) (interface{}, error) {
 		EncodingType: languagepb.EncodingType_UTF8,
 	}
 
-	resp, err := a.detective.client.AnalyzeEntities(a.detective.ctx, req)
+	resp, err := a.detective.client.AnalyzeEntities(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -154,16 +167,17 @@ func (a *Analysis) gcpAPIResult(contents string) (interface{}, error) {
 }
 
 func (a *Analysis) ibmAPIResult(contents string) (interface{}, error) {
+	config := a.detective.ws.Config()
 	result, _, err := a.detective.service.Analyze(
 		&nluv1.AnalyzeOptions{
 			Text: &contents,
 			Features: &nluv1.Features{
 				Relations: &nluv1.RelationsOptions{},
 				Entities: &nluv1.EntitiesOptions{
-					Limit: core.Int64Ptr(10000),
+					Limit: core.Int64Ptr(int64(config.NaturalLanguage.EntityLimit)),
 				},
 				Categories: &nluv1.CategoriesOptions{
-					Limit: core.Int64Ptr(100),
+					Limit: core.Int64Ptr(int64(config.NaturalLanguage.CategoryLimit)),
 				},
 			},
 		},
@@ -178,14 +192,7 @@ func (a *Analysis) ibmAPIResult(contents string) (interface{}, error) {
 // ReadResults returns the results of the analysis as an array of entity
 // records.
 func (a *Analysis) ReadResults() ([]AnalysisEntity, error) {
-	path := a.FilePath()
-	jsonFile, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-
-	defer jsonFile.Close()
-	bytes, err := ioutil.ReadAll(jsonFile)
+	bytes, err := a.ReadAsset(a.assetType)
 	if err != nil {
 		return nil, err
 	}
@@ -199,6 +206,41 @@ func (a *Analysis) ReadResults() ([]AnalysisEntity, error) {
 	return contents, nil
 }
 
+// provider returns the provider recorded when the analysis is committed to
+// the investigations repo.
+func (a *Analysis) provider() trailoftruth.Provider {
+	if a.detective.cloudService == CloudServiceGCP {
+		return trailoftruth.ProviderGCPNaturalLanguage
+	}
+	return trailoftruth.ProviderIBMNaturalLanguageUnderstanding
+}
+
+// CountEntities returns the number of entities in the analysis of the episode
+// produced by the specified cloud service. The GCP analysis is an array of
+// entity records and the IBM analysis is the full response from the service,
+// so they're read differently.
+func CountEntities(ep *whodunit.Episode, cloudService CloudService) (int, error) {
+	bytes, err := ep.ReadAsset(AssetTypeForCloudService(cloudService))
+	if err != nil {
+		return 0, err
+	}
+
+	if cloudService == CloudServiceIBM {
+		var result nluv1.AnalysisResults
+		if err := json.Unmarshal(bytes, &result); err != nil {
+			return 0, err
+		}
+		return len(result.Entities), nil
+	}
+
+	var entities []AnalysisEntity
+	if err := json.Unmarshal(bytes, &entities); err != nil {
+		return 0, err
+	}
+
+	return len(entities), nil
+}
+
 func (a *Analysis) csvFilePath(outputDir string) string {
 	fileName := strings.Replace(a.FileName(), ".json", ".csv", -1)
 	return filepath.Join(outputDir, fileName)
@@ -209,9 +251,9 @@ func (a *Analysis) Exists() bool {
 	return a.AssetExists(a.assetType)
 }
 
-// FilePath returns the path to the analysis file in the `/assets` directory.
+// FilePath returns the path to the analysis file in the asset storage.
 func (a *Analysis) FilePath() string {
-	return a.AssetFilePath(a.assetType)
+	return a.AssetKey(a.assetType)
 }
 
 // FileName returns the name of the analysis file in the `/assets` directory.
@@ -5,7 +5,6 @@ import (
 
 	language "cloud.google.com/go/language/apiv1"
 	"github.com/IBM/go-sdk-core/core"
-	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
 	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 	"github.com/sirupsen/logrus"
@@ -27,6 +26,7 @@ const (
 // Detective contains properties and methods used to start entity analysis
 // jobs.
 type Detective struct {
+	ws           *whodunit.Workspace
 	cloudService CloudService
 	client       *language.Client
 	ctx          context.Context
@@ -34,14 +34,12 @@ type Detective struct {
 	service      *nluv1.NaturalLanguageUnderstandingV1
 }
 
-var (
-	env = crimeseen.NewEnv()
-	log = waterlogged.New("tagasuspect")
-)
+var log = waterlogged.New("tagasuspect")
 
-// NewDetective returns a new instance of a detective.
-func NewDetective() *Detective {
-	return &Detective{}
+// NewDetective returns a new instance of a detective that analyzes the
+// episodes in the specified workspace.
+func NewDetective(ws *whodunit.Workspace) *Detective {
+	return &Detective{ws: ws}
 }
 
 // OpenCase creates a new instance of an NLP client. We're doing this here
@@ -49,14 +47,15 @@ func NewDetective() *Detective {
 // See https://cloud.google.com/natural-language/docs/reference/rest
 func (d *Detective) OpenCase(cloudService CloudService) {
 	d.cloudService = cloudService
+	config := d.ws.Config()
 	if cloudService == CloudServiceGCP {
-		if env.GCPCredsPath() == "" {
-			log.Fatalln("GCP credentials file not specified in .env file")
+		if config.GCP.CredentialsPath == "" {
+			log.Fatalln("GCP credentials file not specified in config")
 		}
 
 		ctx := context.Background()
 		d.ctx = ctx
-		d.withFile = option.WithCredentialsFile(env.GCPCredsPath())
+		d.withFile = option.WithCredentialsFile(config.GCP.CredentialsPath)
 
 		client, err := language.NewClient(d.ctx, d.withFile)
 		if err != nil {
@@ -67,12 +66,12 @@ func (d *Detective) OpenCase(cloudService CloudService) {
 	}
 
 	if cloudService == CloudServiceIBM {
-		if env.IBMLangAPIKey() == "" || env.IBMLangAPIUrl() == "" {
-			log.Fatalln("IBM credentials file not specified in .env file")
+		if config.NaturalLanguage.APIKey == "" || config.NaturalLanguage.URL == "" {
+			log.Fatalln("IBM credentials not specified in config")
 		}
 
 		authenticator := &core.IamAuthenticator{
-			ApiKey: env.IBMLangAPIKey(),
+			ApiKey: config.NaturalLanguage.APIKey,
 		}
 		options := &nluv1.NaturalLanguageUnderstandingV1Options{
 			Version:       "2019-07-12",
@@ -84,7 +83,7 @@ func (d *Detective) OpenCase(cloudService CloudService) {
 			logrus.WithError(err).Fatalln("Could not create new IBM service")
 		}
 
-		if err := svc.SetServiceURL(env.IBMLangAPIUrl()); err != nil {
+		if err := svc.SetServiceURL(config.NaturalLanguage.URL); err != nil {
 			logrus.WithError(err).Fatalln("Could not set IBM service URL")
 		}
 
@@ -92,28 +91,49 @@ func (d *Detective) OpenCase(cloudService CloudService) {
 	}
 }
 
-// Analyze submits a request to analyze the entities in a transcript associated
-// with an episode.
-func (d *Detective) Analyze(seasonNumber int, episodeNumber int, overwrite bool) {
-	onEpisode := func(ep *whodunit.Episode) {
+// Analyze submits a request to analyze the entities in the transcript
+// associated with each episode in the specified selection.
+func (d *Detective) Analyze(
+	ctx context.Context,
+	sel *whodunit.Selection,
+	concurrency int,
+	overwrite bool,
+) error {
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
 		a := newAnalysis(ep, d)
-		a.Create(overwrite)
+		return a.Create(ctx, overwrite)
 	}
 
-	if err := whodunit.Solve(seasonNumber, episodeNumber, onEpisode); err != nil {
+	summary, err := d.ws.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
 		log.WithError(err).Errorln("Error analyzing episode(s)")
+		return err
 	}
+
+	summary.Log(log)
+	return summary.Err()
 }
 
-func (d *Detective) FileReport(seasonNumber int, episodeNumber int, outputDir string) {
-	onEpisode := func(ep *whodunit.Episode) {
+// FileReport writes a CSV file of the analysis entities for each episode in
+// the specified selection to the specified output directory.
+func (d *Detective) FileReport(
+	ctx context.Context,
+	sel *whodunit.Selection,
+	outputDir string,
+) error {
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
 		a := newAnalysis(ep, d)
-		a.WriteCSV(outputDir)
+		return a.WriteCSV(outputDir)
 	}
 
-	if err := whodunit.Solve(seasonNumber, episodeNumber, onEpisode); err != nil {
+	summary, err := d.ws.SolveContext(ctx, sel, 1, onEpisode)
+	if err != nil {
 		log.WithError(err).Errorln("Error analyzing episode(s)")
+		return err
 	}
+
+	summary.Log(log)
+	return summary.Err()
 }
 
 // CloseCase closes the GCP NLP client.
@@ -126,14 +146,9 @@ func (d *Detective) CloseCase() {
 	log.Println("Case closed")
 }
 
-// Investigate logs the episode statuses.
-func Investigate(cloudService CloudService, status whodunit.AssetStatus) {
-	assetType := assetTypeForCloudService(cloudService)
-	table := whodunit.NewStatusTable(assetType, status)
-	table.Log()
-}
-
-func assetTypeForCloudService(cloudService CloudService) whodunit.AssetType {
+// AssetTypeForCloudService returns the analysis asset type associated with the
+// specified cloud service.
+func AssetTypeForCloudService(cloudService CloudService) whodunit.AssetType {
 	if cloudService == CloudServiceGCP {
 		return whodunit.AssetTypeGCPAnalysis
 	}
@@ -0,0 +1,401 @@
+// Package timewilltell aggregates the status and contents of the assets across
+// the catalog, so the progress of the pipeline can be reported without
+// counting files by hand.
+package timewilltell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/hearnoevil"
+	"github.com/mikerourke/forensic-files-api/internal/tagasuspect"
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+)
+
+var log = waterlogged.New("timewilltell")
+
+// Stats contains the statistics gathered for the episodes in a selection.
+type Stats struct {
+	AssetTypes []whodunit.AssetType `json:"assetTypes"`
+	Seasons    []*SeasonStats       `json:"seasons"`
+	Total      *SeasonStats         `json:"total"`
+	Audio      *AudioStats          `json:"audio"`
+	Storage    []*StorageStats      `json:"storage"`
+	Confidence *ConfidenceStats     `json:"recognitionConfidence"`
+	Entities   []*EntityStats       `json:"entities"`
+}
+
+// SeasonStats contains the count of episodes in a season along with the count
+// of episodes where each asset is complete.
+type SeasonStats struct {
+	Series       whodunit.Series            `json:"series,omitempty"`
+	SeasonNumber int                        `json:"season,omitempty"`
+	EpisodeCount int                        `json:"episodes"`
+	Complete     map[whodunit.AssetType]int `json:"complete"`
+}
+
+// AudioStats contains the total length of the audio of the episodes and the
+// length that still needs to be recognized. The length of episodes that
+// haven't been probed is estimated from the runtime in the catalog.
+type AudioStats struct {
+	Total          time.Duration `json:"-"`
+	Remaining      time.Duration `json:"-"`
+	EstimatedCount int           `json:"estimated"`
+	UnknownCount   int           `json:"unknown"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, so the lengths are
+// written in hours instead of nanoseconds.
+func (as *AudioStats) MarshalJSON() ([]byte, error) {
+	type alias AudioStats
+	return json.Marshal(&struct {
+		TotalHours     float64 `json:"totalHours"`
+		RemainingHours float64 `json:"remainingHours"`
+		*alias
+	}{
+		TotalHours:     as.Total.Hours(),
+		RemainingHours: as.Remaining.Hours(),
+		alias:          (*alias)(as),
+	})
+}
+
+// StorageStats contains the count and total size of the files in the asset
+// storage for an asset type.
+type StorageStats struct {
+	AssetType whodunit.AssetType `json:"assetType"`
+	FileCount int                `json:"files"`
+	Bytes     int64              `json:"bytes"`
+}
+
+// ConfidenceStats contains the mean confidence of the speech recognition
+// results.
+type ConfidenceStats struct {
+	Mean        float64 `json:"mean"`
+	ResultCount int     `json:"results"`
+	FileCount   int     `json:"files"`
+}
+
+// EntityStats contains the count of entities found by an analysis provider.
+type EntityStats struct {
+	AssetType    whodunit.AssetType `json:"assetType"`
+	EpisodeCount int                `json:"episodes"`
+	EntityCount  int                `json:"entities"`
+}
+
+// analysisProviders are the cloud services that produce entity analyses.
+var analysisProviders = []tagasuspect.CloudService{
+	tagasuspect.CloudServiceGCP,
+	tagasuspect.CloudServiceIBM,
+}
+
+// Gather returns the statistics for the episodes in the specified selection of
+// the specified workspace. The recognitions and analyses are read to get the
+// confidence and entity counts, so files that can't be read are logged and
+// skipped. The storage usage covers every file in the asset directories
+// regardless of the selection.
+func Gather(ws *whodunit.Workspace, sel *whodunit.Selection) (*Stats, error) {
+	c, err := ws.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		AssetTypes: whodunit.AllAssetTypes(),
+		Seasons:    make([]*SeasonStats, 0),
+		Total:      newSeasonStats("", 0),
+		Audio:      &AudioStats{},
+		Confidence: &ConfidenceStats{},
+		Entities:   make([]*EntityStats, 0),
+	}
+
+	entities := make(map[tagasuspect.CloudService]*EntityStats)
+	for _, cloudService := range analysisProviders {
+		entities[cloudService] = &EntityStats{
+			AssetType: tagasuspect.AssetTypeForCloudService(cloudService),
+		}
+		stats.Entities = append(stats.Entities, entities[cloudService])
+	}
+
+	var season *SeasonStats
+	var confidenceTotal float64
+	err = c.Solve(sel, func(ep *whodunit.Episode) {
+		if season == nil || season.Series != ep.Series ||
+			season.SeasonNumber != ep.SeasonNumber {
+			season = newSeasonStats(ep.Series, ep.SeasonNumber)
+			stats.Seasons = append(stats.Seasons, season)
+		}
+
+		statuses := make(map[whodunit.AssetType]whodunit.AssetStatus)
+		for _, assetType := range stats.AssetTypes {
+			statuses[assetType] = ep.AssetStatus(assetType)
+		}
+
+		season.add(statuses)
+		stats.Total.add(statuses)
+		stats.Audio.add(ep, statuses[whodunit.AssetTypeRecognition])
+
+		if statuses[whodunit.AssetTypeRecognition] == whodunit.AssetStatusComplete {
+			sum, count, err := recognitionConfidence(ep)
+			if err != nil {
+				logSkipped(ep, whodunit.AssetTypeRecognition, err)
+			} else {
+				confidenceTotal += sum
+				stats.Confidence.ResultCount += count
+				stats.Confidence.FileCount++
+			}
+		}
+
+		for _, cloudService := range analysisProviders {
+			es := entities[cloudService]
+			if statuses[es.AssetType] != whodunit.AssetStatusComplete {
+				continue
+			}
+
+			count, err := tagasuspect.CountEntities(ep, cloudService)
+			if err != nil {
+				logSkipped(ep, es.AssetType, err)
+				continue
+			}
+
+			es.EpisodeCount++
+			es.EntityCount += count
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Confidence.ResultCount != 0 {
+		stats.Confidence.Mean = confidenceTotal / float64(stats.Confidence.ResultCount)
+	}
+
+	stats.Storage, err = storageStats(ws, stats.AssetTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func newSeasonStats(series whodunit.Series, seasonNumber int) *SeasonStats {
+	return &SeasonStats{
+		Series:       series,
+		SeasonNumber: seasonNumber,
+		Complete:     make(map[whodunit.AssetType]int),
+	}
+}
+
+func (ss *SeasonStats) add(statuses map[whodunit.AssetType]whodunit.AssetStatus) {
+	ss.EpisodeCount++
+	for assetType, status := range statuses {
+		if status == whodunit.AssetStatusComplete {
+			ss.Complete[assetType]++
+		}
+	}
+}
+
+// Percent returns the percentage of episodes in the season where the asset is
+// complete.
+func (ss *SeasonStats) Percent(assetType whodunit.AssetType) float64 {
+	if ss.EpisodeCount == 0 {
+		return 0
+	}
+
+	return 100 * float64(ss.Complete[assetType]) / float64(ss.EpisodeCount)
+}
+
+// Label returns the name of the season shown in the report (e.g. "1" or
+// "forensic-files-ii:1").
+func (ss *SeasonStats) Label() string {
+	label := strconv.Itoa(ss.SeasonNumber)
+	if ss.Series.IsDefault() {
+		return label
+	}
+
+	return fmt.Sprintf("%s:%s", ss.Series, label)
+}
+
+// add adds the length of the audio of the episode to the totals. The length
+// is taken from the probed audio, then the probed video, then the runtime in
+// the catalog. The audio is remaining if the recognition isn't complete.
+func (as *AudioStats) add(ep *whodunit.Episode, recognitionStatus whodunit.AssetStatus) {
+	var length time.Duration
+	if info := ep.MediaInfo(whodunit.AssetTypeAudio); info != nil {
+		length = info.Length()
+	} else if info := ep.MediaInfo(whodunit.AssetTypeVideo); info != nil {
+		length = info.Length()
+	} else if ep.Runtime != 0 {
+		length = time.Duration(ep.Runtime) * time.Minute
+		as.EstimatedCount++
+	} else {
+		as.UnknownCount++
+		return
+	}
+
+	as.Total += length
+	if recognitionStatus != whodunit.AssetStatusComplete {
+		as.Remaining += length
+	}
+}
+
+// recognitionConfidence returns the sum of the confidence of each result in
+// the recognition of the episode along with the count of results that have a
+// confidence.
+func recognitionConfidence(ep *whodunit.Episode) (float64, int, error) {
+	results, err := hearnoevil.NewRecognition(ep).ReadResults()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sum, count := 0.0, 0
+	for _, result := range results {
+		for _, alt := range result.Alternatives {
+			if alt.Confidence != nil {
+				sum += *alt.Confidence
+				count++
+			}
+		}
+	}
+
+	return sum, count, nil
+}
+
+// storageStats returns the count and size of the files of each asset type in
+// the asset storage of the specified workspace.
+func storageStats(
+	ws *whodunit.Workspace,
+	assetTypes []whodunit.AssetType,
+) ([]*StorageStats, error) {
+	storage, err := ws.Storage()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*StorageStats, 0, len(assetTypes))
+	for _, assetType := range assetTypes {
+		files, err := storage.List(assetType.DirName() + "/")
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s files: %w", assetType, err)
+		}
+
+		ss := &StorageStats{AssetType: assetType}
+		for _, file := range files {
+			ss.FileCount++
+			ss.Bytes += file.Size
+		}
+		results = append(results, ss)
+	}
+
+	return results, nil
+}
+
+func logSkipped(ep *whodunit.Episode, assetType whodunit.AssetType, err error) {
+	log.WithFields(logrus.Fields{
+		"file":  ep.AssetFileName(assetType),
+		"error": err,
+	}).Warnln("Skipping unreadable file in stats")
+}
+
+// WriteJSON writes the statistics to the specified writer as JSON.
+func (s *Stats) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Report writes the statistics to the specified writer as tables.
+func (s *Stats) Report(w io.Writer) {
+	s.reportCompletion(w)
+	s.reportSummary(w)
+	s.reportStorage(w)
+}
+
+func (s *Stats) reportCompletion(w io.Writer) {
+	table := newTable(w)
+	header := []string{"Season", "Episodes"}
+	for _, assetType := range s.AssetTypes {
+		header = append(header, assetType.DisplayName())
+	}
+	table.SetHeader(header)
+
+	row := func(ss *SeasonStats, label string) []string {
+		cells := []string{label, strconv.Itoa(ss.EpisodeCount)}
+		for _, assetType := range s.AssetTypes {
+			cells = append(cells, fmt.Sprintf("%.1f%%", ss.Percent(assetType)))
+		}
+		return cells
+	}
+
+	for _, ss := range s.Seasons {
+		table.Append(row(ss, ss.Label()))
+	}
+	table.SetFooter(row(s.Total, "Total"))
+	table.Render()
+}
+
+func (s *Stats) reportSummary(w io.Writer) {
+	table := newTable(w)
+	table.SetHeader([]string{"Statistic", "Value"})
+
+	audioTotal := formatHours(s.Audio.Total)
+	if s.Audio.EstimatedCount != 0 {
+		audioTotal += fmt.Sprintf(" (%d estimated from runtime)", s.Audio.EstimatedCount)
+	}
+	if s.Audio.UnknownCount != 0 {
+		audioTotal += fmt.Sprintf(" (%d unknown)", s.Audio.UnknownCount)
+	}
+	table.Append([]string{"Audio", audioTotal})
+	table.Append([]string{"Audio Remaining", formatHours(s.Audio.Remaining)})
+
+	confidence := "n/a"
+	if s.Confidence.ResultCount != 0 {
+		confidence = fmt.Sprintf("%.3f (%d results in %d files)",
+			s.Confidence.Mean, s.Confidence.ResultCount, s.Confidence.FileCount)
+	}
+	table.Append([]string{"Recognition Confidence", confidence})
+
+	for _, es := range s.Entities {
+		table.Append([]string{
+			es.AssetType.DisplayName() + " Entities",
+			fmt.Sprintf("%d in %d episodes", es.EntityCount, es.EpisodeCount),
+		})
+	}
+	table.Render()
+}
+
+func (s *Stats) reportStorage(w io.Writer) {
+	table := newTable(w)
+	table.SetHeader([]string{"Directory", "Files", "Size"})
+
+	totalFiles, totalBytes := 0, int64(0)
+	for _, ss := range s.Storage {
+		table.Append([]string{
+			ss.AssetType.DirName(),
+			strconv.Itoa(ss.FileCount),
+			crimeseen.FormatBytes(ss.Bytes),
+		})
+		totalFiles += ss.FileCount
+		totalBytes += ss.Bytes
+	}
+	table.SetFooter([]string{"Total", strconv.Itoa(totalFiles), crimeseen.FormatBytes(totalBytes)})
+	table.Render()
+}
+
+func newTable(w io.Writer) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	return table
+}
+
+func formatHours(d time.Duration) string {
+	return fmt.Sprintf("%.1f hours", d.Hours())
+}
@@ -0,0 +1,290 @@
+// Package trailoftruth commits the artifacts produced by each stage of the
+// pipeline to the investigations git repo as they're written, so every
+// recognition, transcript, and analysis can be traced back to when and how it
+// was produced.
+package trailoftruth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/sirupsen/logrus"
+)
+
+// Provider identifies the service (or tool) that produced an artifact.
+type Provider string
+
+const (
+	// ProviderIBMSpeechToText is the IBM Watson speech-to-text service, which
+	// produces the recognitions.
+	ProviderIBMSpeechToText Provider = "ibm-speech-to-text"
+
+	// ProviderAlibi is this tool, which produces the transcripts from the
+	// recognitions.
+	ProviderAlibi Provider = "alibi"
+
+	// ProviderGCPNaturalLanguage is the GCP Natural Language API, which
+	// produces the GCP analyses.
+	ProviderGCPNaturalLanguage Provider = "gcp-natural-language"
+
+	// ProviderIBMNaturalLanguageUnderstanding is the IBM Watson Natural
+	// Language Understanding service, which produces the IBM analyses.
+	ProviderIBMNaturalLanguageUnderstanding Provider = "ibm-natural-language-understanding"
+)
+
+var (
+	// ErrNotInstalled is returned when the git executable can't be found.
+	ErrNotInstalled = errors.New("could not find git executable, it may not be installed")
+
+	// ErrNotLocal is returned when the assets aren't stored on the local
+	// filesystem, since there's no repo to commit them to.
+	ErrNotLocal = errors.New("auto-commit requires local asset storage")
+)
+
+var log = waterlogged.New("trailoftruth")
+
+const (
+	// gitTimeout is how long a single git command can run before it's killed.
+	gitTimeout = time.Minute
+
+	// indexLockAttempts is how many times a git command that failed because
+	// another git process (e.g. one started by hand) held the index lock is
+	// run before giving up.
+	indexLockAttempts = 5
+)
+
+// Enabled returns true if artifacts in the specified workspace should be
+// committed as they're produced. It's off unless the `gitAutoCommit` setting is
+// turned on.
+func Enabled(ws *whodunit.Workspace) bool {
+	return ws.Config().GitAutoCommit
+}
+
+// IsInstalled returns true if the git executable can be found.
+func IsInstalled() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// Record commits the asset of the episode if auto-commit is enabled. Failing
+// to commit doesn't undo the work that produced the asset, so errors are
+// logged rather than returned.
+func Record(
+	ctx context.Context,
+	ep *whodunit.Episode,
+	assetType whodunit.AssetType,
+	provider Provider,
+) {
+	if ws, err := ep.Workspace(); err != nil || !Enabled(ws) {
+		return
+	}
+
+	if err := Commit(ctx, ep, assetType, provider); err != nil {
+		log.WithFields(logrus.Fields{
+			"file":  ep.AssetFileName(assetType),
+			"error": err,
+		}).Warnln("Unable to commit artifact")
+	}
+}
+
+// Commit stages the asset file of the episode along with the season manifest
+// and commits them to the investigations repo with a message that names the
+// episode, stage, and provider. Only those files are committed, so anything
+// else staged in the repo is left alone. Nothing is committed if the files
+// haven't changed. The commit lock is held while staging and committing, so
+// stages running in other processes (e.g. the callback server) don't commit
+// each other's files.
+func Commit(
+	ctx context.Context,
+	ep *whodunit.Episode,
+	assetType whodunit.AssetType,
+	provider Provider,
+) error {
+	if !IsInstalled() {
+		return ErrNotInstalled
+	}
+
+	ws, err := ep.Workspace()
+	if err != nil {
+		return err
+	}
+
+	s, err := ws.Storage()
+	if err != nil {
+		return err
+	}
+
+	storage, ok := s.(coldstorage.LocalStorage)
+	if !ok {
+		return ErrNotLocal
+	}
+
+	assetPath := storage.LocalPath(ep.AssetKey(assetType))
+	paths := []string{assetPath}
+	manifestPath, err := ep.Season().ManifestPath()
+	if err != nil {
+		return err
+	}
+	if crimeseen.FileExists(manifestPath) {
+		paths = append(paths, manifestPath)
+	}
+
+	lock, err := crimeseen.LockFile(commitLockPath(ws))
+	if err != nil {
+		return fmt.Errorf("error locking repo: %w", err)
+	}
+	defer lock.Unlock()
+
+	git := func(args ...string) error {
+		if err := runGit(ctx, ws.InvestigationsPath(), args...); err != nil {
+			return fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return nil
+	}
+
+	isNew := git(append([]string{"ls-files", "--error-unmatch", "--"}, assetPath)...) != nil
+
+	if err := git(append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+
+	// `diff --quiet` exits with 1 if there are changes.
+	err = git(append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...)
+	if err == nil {
+		log.WithField("file", ep.AssetFileName(assetType)).Infoln(
+			"Artifact unchanged, nothing to commit")
+		return nil
+	}
+
+	var cmdErr *crimeseen.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 {
+		return err
+	}
+
+	message := commitMessage(ws, ep, assetType, provider, isNew)
+	args := append([]string{"commit", "--quiet", "-m", message, "--"}, paths...)
+	if err := git(args...); err != nil {
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"file":     ep.AssetFileName(assetType),
+		"provider": provider,
+	}).Infoln("Committed artifact")
+	return nil
+}
+
+// commitLockPath returns the path to the lock file held while committing. It's
+// kept in the .git directory of the investigations repo, so it's never
+// committed, unless the repo doesn't have one (e.g. it's a worktree), in which
+// case it's kept with the asset locks.
+func commitLockPath(ws *whodunit.Workspace) string {
+	gitDir := filepath.Join(ws.InvestigationsPath(), ".git")
+	if info, err := os.Stat(gitDir); err == nil && info.IsDir() {
+		return filepath.Join(gitDir, "alibi-commit.lock")
+	}
+
+	return filepath.Join(ws.LocksDirPath(), "alibi-commit.lock")
+}
+
+// commitMessage returns the commit message for the asset. The details are
+// written as git trailers, so they can be searched with `git log --grep` or
+// parsed with `git interpret-trailers`.
+func commitMessage(
+	ws *whodunit.Workspace,
+	ep *whodunit.Episode,
+	assetType whodunit.AssetType,
+	provider Provider,
+	isNew bool,
+) string {
+	verb := "Update"
+	if isNew {
+		verb = "Add"
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s %s for %s", verb, assetType, ep.Name()),
+		"",
+		fmt.Sprintf("%s season %d, episode %d: %s.",
+			ep.Series.Title(), ep.SeasonNumber, ep.EpisodeNumber, ep.DisplayTitle()),
+		"",
+		"Episode: " + ep.Name(),
+		"Series: " + ep.Series.String(),
+		fmt.Sprintf("Season: %d", ep.SeasonNumber),
+		fmt.Sprintf("Episode-Number: %d", ep.EpisodeNumber),
+		"Stage: " + assetType.String(),
+		"Provider: " + string(provider),
+		"Command: " + commandLine(ws.Config()),
+	}
+
+	producedAt := time.Now().UTC()
+	if entry := ep.LedgerEntry(assetType); entry != nil {
+		if !entry.CompletedAt.IsZero() {
+			producedAt = entry.CompletedAt
+		}
+		lines = append(lines, fmt.Sprintf("Attempts: %d", entry.Attempts))
+		if entry.Hash != "" {
+			lines = append(lines, "SHA256: "+entry.Hash)
+		}
+
+		inputs := make([]string, 0, len(entry.Inputs))
+		for inputType, hash := range entry.Inputs {
+			inputs = append(inputs, fmt.Sprintf("Input: %s %s", inputType, hash))
+		}
+		sort.Strings(inputs)
+		lines = append(lines, inputs...)
+	}
+	lines = append(lines, "Produced-At: "+producedAt.Format(time.RFC3339))
+
+	return strings.Join(lines, "\n")
+}
+
+// commandLine returns the command that is running (e.g. "alibi transcribe
+// -s 3"), which shows how the artifact was produced. The values of secret
+// settings passed with `--set` are redacted, since the message ends up in the
+// repo history.
+func commandLine(config *crimeseen.Config) string {
+	args := append([]string{filepath.Base(os.Args[0])}, config.RedactArgs(os.Args[1:])...)
+	return strings.Join(args, " ")
+}
+
+// runGit runs git with the specified arguments in the specified investigations
+// directory. If git fails because another git process holds the index lock,
+// it's run again after a short wait.
+func runGit(ctx context.Context, dir string, args ...string) error {
+	cmd := &crimeseen.Command{
+		Name:    "git",
+		Args:    append([]string{"-C", dir}, args...),
+		Timeout: gitTimeout,
+	}
+
+	var err error
+	for attempt := 1; attempt <= indexLockAttempts; attempt++ {
+		err = cmd.Run(ctx)
+
+		var cmdErr *crimeseen.CommandError
+		if !errors.As(err, &cmdErr) || !strings.Contains(cmdErr.Stderr, "index.lock") {
+			return err
+		}
+
+		log.WithField("attempt", attempt).Debugln("Git index is locked, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 200 * time.Millisecond):
+		}
+	}
+
+	return err
+}
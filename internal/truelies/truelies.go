@@ -0,0 +1,345 @@
+// Package truelies lints the episode catalog to find problems like numbering
+// gaps, duplicate episodes, and bad YouTube URLs before they show up later as
+// missing assets.
+package truelies
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/wastemismanagement"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Check identifies the kind of problem found in the catalog.
+type Check string
+
+const (
+	// CheckGap indicates that an episode number is missing from a season.
+	CheckGap Check = "gap"
+
+	// CheckDuplicate indicates that an episode number, title, name, or URL
+	// is used by more than one episode.
+	CheckDuplicate Check = "duplicate"
+
+	// CheckURL indicates that the YouTube URL is empty or malformed.
+	CheckURL Check = "url"
+
+	// CheckSeason indicates that the season or episode number is out of
+	// range.
+	CheckSeason Check = "season"
+
+	// CheckOrphan indicates that an asset file isn't owned by any episode in
+	// the catalog.
+	CheckOrphan Check = "orphan"
+)
+
+// Problem is a single problem found in the catalog.
+type Problem struct {
+	Check   Check
+	Series  whodunit.Series
+	Season  int
+	Episode int
+	Message string
+}
+
+// Location returns the season and episode the problem applies to formatted
+// like a selection term (e.g. "s3e2" or "forensic-files-ii:s1e4") or an empty
+// string if it doesn't apply to an episode.
+func (p *Problem) Location() string {
+	location := ""
+	switch {
+	case p.Season == 0:
+		return ""
+	case p.Episode == 0:
+		location = fmt.Sprintf("s%d", p.Season)
+	default:
+		location = fmt.Sprintf("s%de%d", p.Season, p.Episode)
+	}
+
+	if p.Series.IsDefault() {
+		return location
+	}
+
+	return fmt.Sprintf("%s:%s", p.Series, location)
+}
+
+// Lint checks the specified episodes read from the episodes JSON file and
+// returns the problems found. The episodes are checked as they are in the
+// file, since a catalog can't be built from duplicates. If storage isn't nil,
+// the asset files are checked for orphans too.
+func Lint(episodes []*whodunit.Episode, storage coldstorage.Storage) ([]*Problem, error) {
+	problems := make([]*Problem, 0)
+	problems = append(problems, checkDuplicates(episodes)...)
+	problems = append(problems, checkURLs(episodes)...)
+
+	c, err := whodunit.NewCatalog(uniqueEpisodes(episodes))
+	if err != nil {
+		return nil, err
+	}
+
+	problems = append(problems, checkSeasons(c)...)
+	problems = append(problems, checkGaps(c)...)
+
+	if storage != nil {
+		orphans, err := checkOrphans(c, storage)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, orphans...)
+	}
+
+	return problems, nil
+}
+
+// uniqueEpisodes returns the first of the episodes with each series, season,
+// and episode number, which are the ones the duplicates are reported against.
+func uniqueEpisodes(episodes []*whodunit.Episode) []*whodunit.Episode {
+	numbers := make(map[string]bool)
+	unique := make([]*whodunit.Episode, 0, len(episodes))
+	for _, ep := range episodes {
+		number := episodeNumber(ep)
+		if !numbers[number] {
+			numbers[number] = true
+			unique = append(unique, ep)
+		}
+	}
+
+	return unique
+}
+
+// episodeNumber returns the series, season, and episode number of the episode
+// as a string that can be used as a key.
+func episodeNumber(ep *whodunit.Episode) string {
+	return fmt.Sprintf("%s:s%de%d", ep.Series.Key(), ep.SeasonNumber, ep.EpisodeNumber)
+}
+
+// checkSeasons reports seasons and episodes with numbers less than 1 and
+// seasons beyond the count of seasons of the series in the catalog, which
+// means there's a gap in the season numbers.
+func checkSeasons(c *whodunit.Catalog) []*Problem {
+	problems := make([]*Problem, 0)
+	for _, s := range c.Seasons() {
+		seasonCount := c.SeriesSeasonCount(s.Series)
+		if s.SeasonNumber < 1 || s.SeasonNumber > seasonCount {
+			problems = append(problems, &Problem{
+				Check:  CheckSeason,
+				Series: s.Series,
+				Season: s.SeasonNumber,
+				Message: fmt.Sprintf("season %d is beyond the %d season(s) of %s in the catalog",
+					s.SeasonNumber, seasonCount, s.Series.Title()),
+			})
+		}
+	}
+
+	for _, ep := range c.Episodes() {
+		if ep.EpisodeNumber < 1 {
+			problems = append(problems, &Problem{
+				Check:   CheckSeason,
+				Series:  ep.Series,
+				Season:  ep.SeasonNumber,
+				Episode: ep.EpisodeNumber,
+				Message: "episode number is less than 1",
+			})
+		}
+	}
+
+	return problems
+}
+
+// checkGaps reports episode numbers missing from each season.
+func checkGaps(c *whodunit.Catalog) []*Problem {
+	problems := make([]*Problem, 0)
+	for _, s := range c.Seasons() {
+		episodes := s.AllEpisodes()
+		if len(episodes) == 0 {
+			continue
+		}
+
+		last := episodes[len(episodes)-1].EpisodeNumber
+		for n := 1; n < last; n++ {
+			if s.Episode(n) == nil {
+				problems = append(problems, &Problem{
+					Check:   CheckGap,
+					Series:  s.Series,
+					Season:  s.SeasonNumber,
+					Episode: n,
+					Message: fmt.Sprintf("episode %d is missing from season %d",
+						n, s.SeasonNumber),
+				})
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkDuplicates reports episodes that have the same numbers or title as an
+// earlier episode in the same series or the same video as an earlier episode
+// in the catalog. The name of an episode is made up of the numbers and title,
+// so episodes with the same name are reported as one duplicate.
+func checkDuplicates(episodes []*whodunit.Episode) []*Problem {
+	problems := make([]*Problem, 0)
+	numbers := make(map[string]*whodunit.Episode)
+	titles := make(map[string]*whodunit.Episode)
+	videos := make(map[string]*whodunit.Episode)
+
+	report := func(ep *whodunit.Episode, first *whodunit.Episode, what string) {
+		firstProblem := &Problem{
+			Series:  first.Series,
+			Season:  first.SeasonNumber,
+			Episode: first.EpisodeNumber,
+		}
+		problems = append(problems, &Problem{
+			Check:   CheckDuplicate,
+			Series:  ep.Series,
+			Season:  ep.SeasonNumber,
+			Episode: ep.EpisodeNumber,
+			Message: fmt.Sprintf("%s is the same as %s",
+				what, firstProblem.Location()),
+		})
+	}
+
+	for _, ep := range episodes {
+		number := episodeNumber(ep)
+		first, ok := numbers[number]
+		switch {
+		case ok && first.Title == ep.Title:
+			report(ep, first, fmt.Sprintf("name %q", ep.Name()))
+
+		case ok:
+			report(ep, first, "episode number")
+
+		default:
+			numbers[number] = ep
+		}
+
+		title := fmt.Sprintf("%s:%s", ep.Series.Key(), ep.Title)
+		if first, ok := titles[title]; ok && first.Name() != ep.Name() {
+			report(ep, first, fmt.Sprintf("title %q", ep.Title))
+		} else if !ok {
+			titles[title] = ep
+		}
+
+		videoID := youTubeVideoID(ep.URL)
+		if videoID == "" {
+			continue
+		}
+
+		if first, ok := videos[videoID]; ok {
+			report(ep, first, fmt.Sprintf("video %q", videoID))
+		} else {
+			videos[videoID] = ep
+		}
+	}
+
+	return problems
+}
+
+// checkURLs reports episodes with an empty URL or a URL that isn't a YouTube
+// video.
+func checkURLs(episodes []*whodunit.Episode) []*Problem {
+	problems := make([]*Problem, 0)
+	for _, ep := range episodes {
+		message := ""
+		if strings.TrimSpace(ep.URL) == "" {
+			message = "URL is empty"
+		} else if youTubeVideoID(ep.URL) == "" {
+			message = fmt.Sprintf("URL %q isn't a YouTube video", ep.URL)
+		}
+
+		if message != "" {
+			problems = append(problems, &Problem{
+				Check:   CheckURL,
+				Series:  ep.Series,
+				Season:  ep.SeasonNumber,
+				Episode: ep.EpisodeNumber,
+				Message: message,
+			})
+		}
+	}
+
+	return problems
+}
+
+// youTubeVideoID returns the video ID from the specified YouTube URL or an
+// empty string if it isn't a valid YouTube video URL.
+func youTubeVideoID(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return ""
+	}
+
+	switch strings.TrimPrefix(u.Hostname(), "www.") {
+	case "youtube.com", "m.youtube.com":
+		if u.Path != "/watch" {
+			return ""
+		}
+		return u.Query().Get("v")
+
+	case "youtu.be":
+		return strings.Trim(u.Path, "/")
+	}
+
+	return ""
+}
+
+// checkOrphans reports asset files in the storage that aren't owned by any
+// episode in the catalog. Partial and quarantined files are skipped, since
+// those are left to `alibi gc`.
+func checkOrphans(c *whodunit.Catalog, storage coldstorage.Storage) ([]*Problem, error) {
+	problems := make([]*Problem, 0)
+	for _, assetType := range whodunit.AllAssetTypes() {
+		files, err := storage.List(assetType.DirName() + "/")
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s files: %w", assetType, err)
+		}
+
+		for _, file := range files {
+			if c.AssetOwner(assetType, file.Path) != nil {
+				continue
+			}
+
+			if _, ok := wastemismanagement.Leftover(file.Name()); ok {
+				continue
+			}
+
+			problems = append(problems, &Problem{
+				Check:   CheckOrphan,
+				Message: fmt.Sprintf("%s isn't owned by any episode", file.Path),
+			})
+		}
+	}
+
+	return problems, nil
+}
+
+// Report writes the problems to the terminal as a table.
+func Report(problems []*Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Series.Key() != problems[j].Series.Key() {
+			return problems[i].Series.Before(problems[j].Series)
+		}
+		if problems[i].Season != problems[j].Season {
+			return problems[i].Season < problems[j].Season
+		}
+		return problems[i].Episode < problems[j].Episode
+	})
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Check", "Location", "Problem"})
+	for _, p := range problems {
+		table.Append([]string{string(p.Check), p.Location(), p.Message})
+	}
+	table.SetFooter([]string{"", "Total", strconv.Itoa(len(problems))})
+	table.Render()
+}
@@ -1,6 +1,9 @@
 package videodiary
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
@@ -21,35 +24,68 @@ func NewVideo(ep *whodunit.Episode) *Video {
 }
 
 // Download downloads the video from YouTube.
-func (v *Video) Download(isPaused bool) {
+func (v *Video) Download(ctx context.Context, isPaused bool) error {
 	if v.Exists() {
 		log.Infoln("Episode already downloaded, skipping")
-		return
+		return nil
 	}
 
-	path := v.FilePath()
 	log.WithFields(logrus.Fields{
+		"series":  v.Series.String(),
 		"season":  v.SeasonNumber,
 		"episode": v.EpisodeNumber,
 		"title":   v.Title,
-		"path":    path,
+		"path":    v.FilePath(),
 		"url":     v.URL,
 	}).Infoln("Downloading video from YouTube")
 
-	err := crimeseen.RunCommand("youtube-dl", "-o", path, v.URL)
+	err := v.Track(whodunit.AssetTypeVideo, func() error {
+		return v.ProduceAsset(whodunit.AssetTypeVideo, func(path string) error {
+			// The formats are merged into an `.mp4` file, so the video ends
+			// up at the path youtube-dl was given (and gets stored).
+			ws, err := v.Workspace()
+			if err != nil {
+				return err
+			}
+
+			cmd := &crimeseen.Command{
+				Name: "youtube-dl",
+				Args: []string{
+					"--newline",
+					"--merge-output-format", "mp4",
+					"-o", path,
+					v.URL,
+				},
+				Timeout:    ws.Config().YouTubeDL.Timeout,
+				Progress:   v.logProgress,
+				Signatures: youTubeDLSignatures,
+			}
+			if err := cmd.Run(ctx); err != nil {
+				return fmt.Errorf("error downloading video: %w", err)
+			}
+			return nil
+		})
+	})
 	if err != nil {
-		log.WithFields(logrus.Fields{
-			"error": err,
-			"title": v.Title,
-			"path":  path,
-		}).Errorln("Error downloading video")
+		return err
 	}
 
 	// We're hedging our bets here to make sure we don't exceed some kind of
 	// rate limit:
 	if isPaused {
 		log.Println("Download successful, waiting 1 minute")
-		time.Sleep(time.Minute * 1)
+		return crimeseen.Wait(ctx, time.Minute*1)
+	}
+
+	return nil
+}
+
+// logProgress logs the download progress lines youtube-dl writes with
+// `--newline` (e.g. "[download]  42.3% of 312.45MiB at 2.10MiB/s").
+func (v *Video) logProgress(line string) {
+	if strings.HasPrefix(line, "[download]") {
+		log.WithField("file", v.FileName()).Debugln(
+			strings.TrimSpace(strings.TrimPrefix(line, "[download]")))
 	}
 }
 
@@ -58,9 +94,9 @@ func (v *Video) Exists() bool {
 	return v.AssetExists(whodunit.AssetTypeVideo)
 }
 
-// FilePath returns the path to the video file in the `/assets` directory.
+// FilePath returns the path to the video file in the asset storage.
 func (v *Video) FilePath() string {
-	return v.AssetFilePath(whodunit.AssetTypeVideo)
+	return v.AssetKey(whodunit.AssetTypeVideo)
 }
 
 // FileName returns the name of the video file in the `/assets` directory.
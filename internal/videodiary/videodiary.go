@@ -3,39 +3,73 @@
 package videodiary
 
 import (
-	"os/exec"
+	"context"
+	"errors"
 
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/housecall"
 	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 )
 
+var (
+	// ErrVideoUnavailable is the reason youtube-dl failed when the video was
+	// removed or made private.
+	ErrVideoUnavailable = errors.New("video is unavailable")
+
+	// ErrRateLimited is the reason youtube-dl failed when YouTube is
+	// throttling the downloads.
+	ErrRateLimited = errors.New("rate limited by YouTube")
+
+	// ErrAgeRestricted is the reason youtube-dl failed when the video can't
+	// be downloaded without signing in.
+	ErrAgeRestricted = errors.New("video is age restricted")
+
+	// ErrNetwork is the reason youtube-dl failed when YouTube couldn't be
+	// reached.
+	ErrNetwork = errors.New("unable to reach YouTube")
+)
+
+// youTubeDLSignatures are the messages youtube-dl writes to stderr for the
+// failures that have a specific error.
+var youTubeDLSignatures = []crimeseen.Signature{
+	{Pattern: "Video unavailable", Err: ErrVideoUnavailable},
+	{Pattern: "This video is unavailable", Err: ErrVideoUnavailable},
+	{Pattern: "This video has been removed", Err: ErrVideoUnavailable},
+	{Pattern: "Private video", Err: ErrVideoUnavailable},
+	{Pattern: "HTTP Error 429", Err: ErrRateLimited},
+	{Pattern: "Too Many Requests", Err: ErrRateLimited},
+	{Pattern: "Sign in to confirm your age", Err: ErrAgeRestricted},
+	{Pattern: "Unable to download webpage", Err: ErrNetwork},
+	{Pattern: "Temporary failure in name resolution", Err: ErrNetwork},
+	{Pattern: "No space left on device", Err: crimeseen.ErrDiskFull},
+}
+
 var log = waterlogged.New("videodiary")
 
-// Download downloads the specified episode number from the specified season
-// number or all seasons.
-func Download(seasonNumber int, episodeNumber int) {
-	interrogate()
+// Download downloads each episode in the specified selection of the specified
+// workspace, running up to the specified number of downloads at the same time.
+func Download(
+	ctx context.Context,
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	concurrency int,
+) error {
+	if err := housecall.RequireBinary("youtube-dl"); err != nil {
+		return err
+	}
 
-	onEpisode := func(ep *whodunit.Episode) {
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
 		v := NewVideo(ep)
-		v.Download(seasonNumber == 0)
+		return v.Download(ctx, !sel.IsEpisode())
 	}
 
-	if err := whodunit.Solve(seasonNumber, episodeNumber, onEpisode); err != nil {
+	summary, err := ws.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
 		log.WithError(err).Errorln("Error downloading episode(s)")
+		return err
 	}
-}
 
-// Investigate logs the episode statuses.
-func Investigate(status whodunit.AssetStatus) {
-	table := whodunit.NewStatusTable(whodunit.AssetTypeVideo, status)
-	table.Log()
-}
-
-func interrogate() {
-	cmd := exec.Command("youtube-dl", "--version")
-	err := cmd.Run()
-	if err != nil {
-		log.Fatalln("Could not find youtube-dl executable, it may not be installed")
-	}
+	summary.Log(log)
+	return summary.Err()
 }
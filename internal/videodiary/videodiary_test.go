@@ -0,0 +1,54 @@
+package videodiary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// TestHelperProcess isn't a real test, it stands in for youtube-dl by writing
+// the message after "--" to stderr and failing.
+func TestHelperProcess(t *testing.T) {
+	args := os.Args
+	for len(args) != 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, args[1])
+	os.Exit(1)
+}
+
+func TestYouTubeDLSignatures(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   error
+	}{
+		{"ERROR: Video unavailable", ErrVideoUnavailable},
+		{"ERROR: This video has been removed by the uploader", ErrVideoUnavailable},
+		{"ERROR: Private video. Sign in if you've been granted access", ErrVideoUnavailable},
+		{"ERROR: Unable to download webpage: HTTP Error 429: Too Many Requests", ErrRateLimited},
+		{"ERROR: Sign in to confirm your age", ErrAgeRestricted},
+		{"ERROR: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>", ErrNetwork},
+		{"ERROR: unable to write data: [Errno 28] No space left on device", crimeseen.ErrDiskFull},
+	}
+
+	for _, test := range tests {
+		cmd := &crimeseen.Command{
+			Name:       os.Args[0],
+			Args:       []string{"-test.run=TestHelperProcess", "--", test.stderr},
+			Signatures: youTubeDLSignatures,
+		}
+
+		err := fmt.Errorf("error downloading video: %w", cmd.Run(context.Background()))
+		if !errors.Is(err, test.want) {
+			t.Errorf("got error %q for %q, want %q", err, test.stderr, test.want)
+		}
+	}
+}
@@ -1,14 +1,15 @@
 package visibilityzero
 
 import (
-	"os"
+	"context"
+	"fmt"
+	"io"
 	"strings"
 	"time"
 
 	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
 	"github.com/mikerourke/forensic-files-api/internal/videodiary"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
-	"github.com/sirupsen/logrus"
 )
 
 // Audio represents the MP3 file extracted from the video file.
@@ -24,42 +25,84 @@ func NewAudio(ep *whodunit.Episode) *Audio {
 }
 
 // Extract extracts audio from the video file.
-func (a *Audio) Extract(isPaused bool) {
+func (a *Audio) Extract(ctx context.Context, isPaused bool) error {
 	v := videodiary.NewVideo(a.Episode)
-	if !v.Exists() {
+	if !v.AssetComplete(whodunit.AssetTypeVideo) {
 		log.WithField("file", v.FileName()).Warnln(
-			"Skipping job, video file not found")
-		return
+			"Skipping job, video file not found or not complete")
+		return nil
+	}
+
+	if a.Exists() && !a.AssetStale(whodunit.AssetTypeAudio) {
+		log.WithField("file", a.FileName()).Infoln(
+			"Skipping job, audio already extracted")
+		return nil
 	}
 
 	log.WithField("video", v.FileName()).Infoln(
 		"Extracting audio from video file")
 
-	err := crimeseen.RunCommand("ffmpeg",
-		"-i", v.FilePath(),
-		"-loglevel", "quiet",
-		a.FilePath())
-	if err != nil {
-		if strings.Contains(err.Error(), "exit status 1") {
-			log.Infoln("Successfully extracted audio")
-		} else {
-			log.WithFields(logrus.Fields{
-				"error": err,
-				"video": v.FileName(),
-			}).Errorln("Error extracting audio")
+	err := a.Track(whodunit.AssetTypeAudio, func() error {
+		videoPath, release, err := v.FetchAsset(whodunit.AssetTypeVideo)
+		if err != nil {
+			return fmt.Errorf("error fetching %s: %w", v.FileName(), err)
 		}
+		defer release()
+
+		return a.ProduceAsset(whodunit.AssetTypeAudio, func(path string) error {
+			ws, err := a.Workspace()
+			if err != nil {
+				return err
+			}
+
+			cmd := &crimeseen.Command{
+				Name: "ffmpeg",
+				Args: []string{
+					"-y",
+					"-nostats",
+					"-loglevel", "error",
+					"-progress", "pipe:1",
+					"-i", videoPath,
+					path,
+				},
+				Timeout:    ws.Config().FFmpeg.Timeout,
+				Progress:   a.logProgress,
+				Signatures: ffmpegSignatures,
+			}
+			if err := cmd.Run(ctx); err != nil {
+				return fmt.Errorf("error extracting audio from %s: %w",
+					v.FileName(), err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
 	}
 
+	log.WithField("file", a.FileName()).Infoln("Successfully extracted audio")
+
 	// Adding a 30 second delay here so my laptop doesn't melt.
 	if isPaused {
 		log.Println("Extraction successful, waiting 30 seconds")
-		time.Sleep(time.Second * 30)
+		return crimeseen.Wait(ctx, time.Second*30)
+	}
+
+	return nil
+}
+
+// logProgress logs the position ffmpeg reached in the video, which it reports
+// as "out_time=HH:MM:SS.micro" lines with `-progress`.
+func (a *Audio) logProgress(line string) {
+	if strings.HasPrefix(line, "out_time=") {
+		log.WithField("file", a.FileName()).Debugln(
+			"Extracted up to " + strings.TrimPrefix(line, "out_time="))
 	}
 }
 
 // Open returns the audio file contents.
-func (a *Audio) Open() *os.File {
-	audio, err := os.Open(a.FilePath())
+func (a *Audio) Open() io.ReadCloser {
+	audio, err := a.OpenAsset(whodunit.AssetTypeAudio)
 	if err != nil {
 		log.WithError(err).Errorln("Error opening audio")
 		return nil
@@ -73,9 +116,9 @@ func (a *Audio) Exists() bool {
 	return a.AssetExists(whodunit.AssetTypeAudio)
 }
 
-// FilePath returns the path to the audio file in the `/assets` directory.
+// FilePath returns the path to the audio file in the asset storage.
 func (a *Audio) FilePath() string {
-	return a.AssetFilePath(whodunit.AssetTypeAudio)
+	return a.AssetKey(whodunit.AssetTypeAudio)
 }
 
 // FileName returns the name of the audio file in the `/assets` directory.
@@ -3,39 +3,61 @@
 package visibilityzero
 
 import (
-	"os/exec"
+	"context"
+	"errors"
 
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/housecall"
 	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
 	"github.com/mikerourke/forensic-files-api/internal/whodunit"
 )
 
+var (
+	// ErrNoAudioStream is the reason ffmpeg failed when the video doesn't
+	// have any audio to extract.
+	ErrNoAudioStream = errors.New("video has no audio stream")
+
+	// ErrInvalidVideo is the reason ffmpeg failed when the video file is
+	// corrupt or isn't a video.
+	ErrInvalidVideo = errors.New("video file is invalid or corrupt")
+)
+
+// ffmpegSignatures are the messages ffmpeg writes to stderr for the failures
+// that have a specific error.
+var ffmpegSignatures = []crimeseen.Signature{
+	{Pattern: "does not contain any stream", Err: ErrNoAudioStream},
+	{Pattern: "matches no streams", Err: ErrNoAudioStream},
+	{Pattern: "Invalid data found when processing input", Err: ErrInvalidVideo},
+	{Pattern: "moov atom not found", Err: ErrInvalidVideo},
+	{Pattern: "No space left on device", Err: crimeseen.ErrDiskFull},
+}
+
 var log = waterlogged.New("visibilityzero")
 
-// ExtractAudio extracts the audio from the specified season and episode (or
-// all if neither is specified) and saves it to an `.mp3` file.
-func ExtractAudio(seasonNumber int, episodeNumber int) {
-	interrogate()
+// ExtractAudio extracts the audio from each episode in the specified selection
+// of the specified workspace and saves it to an `.mp3` file, running up to the
+// specified number of extractions at the same time.
+func ExtractAudio(
+	ctx context.Context,
+	ws *whodunit.Workspace,
+	sel *whodunit.Selection,
+	concurrency int,
+) error {
+	if err := housecall.RequireBinary("ffmpeg"); err != nil {
+		return err
+	}
 
-	onEpisode := func(ep *whodunit.Episode) {
+	onEpisode := func(ctx context.Context, ep *whodunit.Episode) error {
 		a := NewAudio(ep)
-		a.Extract(episodeNumber == 0)
+		return a.Extract(ctx, !sel.IsEpisode())
 	}
 
-	if err := whodunit.Solve(seasonNumber, episodeNumber, onEpisode); err != nil {
+	summary, err := ws.SolveContext(ctx, sel, concurrency, onEpisode)
+	if err != nil {
 		log.WithError(err).Errorln("Error extracting audio from episode(s)")
+		return err
 	}
-}
-
-// Investigate logs the episode statuses.
-func Investigate(status whodunit.AssetStatus) {
-	table := whodunit.NewStatusTable(whodunit.AssetTypeAudio, status)
-	table.Log()
-}
 
-func interrogate() {
-	cmd := exec.Command("ffmpeg", "-version")
-	err := cmd.Run()
-	if err != nil {
-		log.Fatalln("Could not find ffmpeg executable, it may not be installed")
-	}
+	summary.Log(log)
+	return summary.Err()
 }
@@ -0,0 +1,52 @@
+package visibilityzero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// TestHelperProcess isn't a real test, it stands in for ffmpeg by writing the
+// message after "--" to stderr and failing.
+func TestHelperProcess(t *testing.T) {
+	args := os.Args
+	for len(args) != 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, args[1])
+	os.Exit(1)
+}
+
+func TestFFmpegSignatures(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   error
+	}{
+		{"Output file #0 does not contain any stream", ErrNoAudioStream},
+		{"Stream map '0:a' matches no streams.", ErrNoAudioStream},
+		{"video.mp4: Invalid data found when processing input", ErrInvalidVideo},
+		{"[mov,mp4,m4a,3gp,3g2,mj2 @ 0x1] moov atom not found", ErrInvalidVideo},
+		{"audio.mp3: No space left on device", crimeseen.ErrDiskFull},
+	}
+
+	for _, test := range tests {
+		cmd := &crimeseen.Command{
+			Name:       os.Args[0],
+			Args:       []string{"-test.run=TestHelperProcess", "--", test.stderr},
+			Signatures: ffmpegSignatures,
+		}
+
+		err := fmt.Errorf("error extracting audio: %w", cmd.Run(context.Background()))
+		if !errors.Is(err, test.want) {
+			t.Errorf("got error %q for %q, want %q", err, test.stderr, test.want)
+		}
+	}
+}
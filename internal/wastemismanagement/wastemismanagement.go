@@ -0,0 +1,203 @@
+// Package wastemismanagement finds files in the asset directories that are
+// safe to remove, like partial downloads and assets left behind when an
+// episode was renamed, so the disk doesn't fill up with files nobody knows
+// what to do with.
+package wastemismanagement
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/printedproof"
+	"github.com/mikerourke/forensic-files-api/internal/waterlogged"
+	"github.com/mikerourke/forensic-files-api/internal/whodunit"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+)
+
+// Kind identifies why a file is garbage.
+type Kind string
+
+const (
+	// KindOrphan is an asset file that isn't owned by any episode in the
+	// catalog (e.g. because the episode title was changed).
+	KindOrphan Kind = "orphan"
+
+	// KindPartial is a file left behind by a youtube-dl run or an asset
+	// write that was killed or failed partway.
+	KindPartial Kind = "partial"
+
+	// KindQuarantined is a corrupt file that was moved aside by verify.
+	KindQuarantined Kind = "quarantined"
+)
+
+// Kinds returns the keys of every kind of garbage for use in flag enums.
+func Kinds() []string {
+	return []string{string(KindOrphan), string(KindPartial), string(KindQuarantined)}
+}
+
+// partialMarkers are the parts of a file name that youtube-dl and the atomic
+// asset writes use for files that aren't finished.
+var partialMarkers = []string{crimeseen.TempFileSuffix, ".ytdl"}
+
+// Garbage is a single file that is safe to remove.
+type Garbage struct {
+	Kind      Kind
+	AssetType whodunit.AssetType
+	File      *coldstorage.FileInfo
+}
+
+// Options contains the options used to collect garbage.
+type Options struct {
+	// Kinds are the kinds of garbage to collect.
+	Kinds []Kind
+
+	// MinAge is how long ago a file needs to have been modified to be
+	// collected, so partial files of downloads that are still running
+	// aren't removed.
+	MinAge time.Duration
+}
+
+var log = waterlogged.New("wastemismanagement")
+
+// Collect returns the files in the asset directories of the specified storage
+// that are one of the kinds of garbage in the options, sorted by path.
+func Collect(
+	c *whodunit.Catalog,
+	storage coldstorage.Storage,
+	opts Options,
+) ([]*Garbage, error) {
+	kinds := make(map[Kind]bool, len(opts.Kinds))
+	for _, kind := range opts.Kinds {
+		kinds[kind] = true
+	}
+
+	cutoff := time.Now().Add(-opts.MinAge)
+	garbage := make([]*Garbage, 0)
+	for _, assetType := range whodunit.AllAssetTypes() {
+		files, err := storage.List(assetType.DirName() + "/")
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s files: %w", assetType, err)
+		}
+
+		for _, file := range files {
+			kind, ok := classify(c, assetType, file)
+			if !ok || !kinds[kind] || file.ModTime.After(cutoff) {
+				continue
+			}
+
+			garbage = append(garbage, &Garbage{
+				Kind:      kind,
+				AssetType: assetType,
+				File:      file,
+			})
+		}
+	}
+
+	sort.SliceStable(garbage, func(i, j int) bool {
+		return garbage[i].File.Path < garbage[j].File.Path
+	})
+
+	return garbage, nil
+}
+
+// classify returns the kind of garbage the file is or false if it isn't
+// garbage. Partial and quarantined files are named after the asset file, so
+// they're checked before ownership.
+func classify(
+	c *whodunit.Catalog,
+	assetType whodunit.AssetType,
+	file *coldstorage.FileInfo,
+) (Kind, bool) {
+	if kind, ok := Leftover(file.Name()); ok {
+		return kind, true
+	}
+
+	if c.AssetOwner(assetType, file.Path) == nil {
+		return KindOrphan, true
+	}
+
+	return "", false
+}
+
+// Leftover returns KindPartial or KindQuarantined if the file with the
+// specified name is a partial or quarantined file, or false if it's neither.
+func Leftover(name string) (Kind, bool) {
+	for _, marker := range partialMarkers {
+		if strings.HasSuffix(name, marker) || strings.Contains(name, marker+"-") {
+			return KindPartial, true
+		}
+	}
+
+	if strings.HasSuffix(name, printedproof.CorruptSuffix) {
+		return KindQuarantined, true
+	}
+
+	return "", false
+}
+
+// Size returns the total size of the specified files in bytes.
+func Size(garbage []*Garbage) int64 {
+	var size int64
+	for _, g := range garbage {
+		size += g.File.Size
+	}
+
+	return size
+}
+
+// Sweep removes the specified files from the storage. It keeps going if a file
+// can't be removed and returns an error with the number of files that
+// couldn't be removed once it's done.
+func Sweep(storage coldstorage.Storage, garbage []*Garbage) error {
+	failedCount := 0
+	for _, g := range garbage {
+		if err := storage.Remove(g.File.Path); err != nil {
+			failedCount++
+			log.WithFields(logrus.Fields{
+				"file":  g.File.Path,
+				"error": err,
+			}).Errorln("Error removing file")
+			continue
+		}
+
+		log.WithFields(logrus.Fields{
+			"file": g.File.Path,
+			"kind": g.Kind,
+		}).Infoln("Removed file")
+	}
+
+	if failedCount != 0 {
+		return fmt.Errorf("%d file(s) couldn't be removed", failedCount)
+	}
+
+	return nil
+}
+
+// Report writes the specified files to the specified writer as a table with
+// the total size that can be reclaimed in the footer.
+func Report(w io.Writer, garbage []*Garbage) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Kind", "File", "Size", "Modified"})
+	for _, g := range garbage {
+		table.Append([]string{
+			string(g.Kind),
+			g.File.Path,
+			crimeseen.FormatBytes(g.File.Size),
+			g.File.ModTime.Local().Format("2006-01-02 15:04"),
+		})
+	}
+	table.SetFooter([]string{
+		"Total", strconv.Itoa(len(garbage)), crimeseen.FormatBytes(Size(garbage)), "",
+	})
+	table.Render()
+}
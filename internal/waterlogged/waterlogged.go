@@ -4,49 +4,251 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/orandin/lumberjackrus"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// FormatText writes the log file entries as "key=value" pairs.
+	FormatText = "text"
+
+	// FormatJSON writes each log file entry as a JSON object.
+	FormatJSON = "json"
+)
+
+const (
+	// FilesPerService writes the entries of each logger to a file named after
+	// its service (e.g. `logs/videodiary.log`).
+	FilesPerService = "per-service"
+
+	// FilesCombined writes the entries of every logger to CombinedFileName,
+	// with the service in each entry.
+	FilesCombined = "combined"
+
+	// FilesOff doesn't write any log files.
+	FilesOff = "off"
+)
+
+// CombinedFileName is the name of the log file written to when the files are
+// combined.
+const CombinedFileName = "combined.log"
+
+// terminalLevel is the minimum level of the entries written to the terminal.
+const terminalLevel = logrus.InfoLevel
+
+// Settings contains the settings for the log files.
+type Settings struct {
+	// Dir is the directory the log files are written to.
+	Dir string
+
+	// Level is the minimum level of the entries written to the log files
+	// (e.g. "debug").
+	Level string
+
+	// Format is FormatText or FormatJSON.
+	Format string
+
+	// Files is FilesPerService, FilesCombined, or FilesOff.
+	Files string
+
+	// MaxSize is the size in megabytes a log file can reach before it's
+	// rotated.
+	MaxSize int
+
+	// MaxAge is the number of days rotated log files are kept. If it's 0,
+	// they're kept forever.
+	MaxAge int
+
+	// Compress gzips the rotated log files.
+	Compress bool
+}
+
 // Waterlogged represents the logger instance with service name details.
 type Waterlogged struct {
 	*logrus.Logger
 	serviceName string
 }
 
-// New creates a new logger instance with the specified service name
-// and creates a corresponding Lumberjack hook for file logging.
+// logFiles is the state shared by every logger, which is applied to the
+// loggers created before the settings were configured as well as the ones
+// created after.
+var logFiles struct {
+	mutex    sync.Mutex
+	loggers  []*Waterlogged
+	settings *Settings
+	level    logrus.Level
+
+	// combinedHook writes to the combined file. A single hook is shared by
+	// every logger, so the file is only rotated in one place.
+	combinedHook logrus.Hook
+}
+
+// New creates a new logger instance with the specified service name. If the
+// log files were configured with Configure, the entries are also written to
+// the log file for the service.
 func New(serviceName string) *Waterlogged {
 	wl := &Waterlogged{
 		Logger:      logrus.New(),
 		serviceName: serviceName,
 	}
-	wl.SetFormatter(&logrus.TextFormatter{})
+	wl.SetFormatter(&terminalFormatter{
+		Formatter: &logrus.TextFormatter{},
+		level:     terminalLevel,
+	})
+
+	logFiles.mutex.Lock()
+	defer logFiles.mutex.Unlock()
+
+	logFiles.loggers = append(logFiles.loggers, wl)
+	wl.applySettings()
 	return wl
 }
 
-func (wl *Waterlogged) addLumberjackHook() {
-	pwd, err := os.Getwd()
-	logDirPath := filepath.Join(pwd, "logs")
-	err = os.MkdirAll(logDirPath, os.ModePerm)
+// Configure applies the specified settings to every logger, including the
+// ones created later. It returns an error if the settings are invalid or the
+// log directory can't be created, in which case the loggers are left as they
+// were.
+func Configure(settings Settings) error {
+	level, err := logrus.ParseLevel(settings.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+
+	switch strings.ToLower(settings.Format) {
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("invalid log format %q", settings.Format)
+	}
+
+	files := strings.ToLower(settings.Files)
+	switch files {
+	case FilesPerService, FilesCombined:
+		if settings.Dir == "" {
+			return fmt.Errorf("log directory is required to write %s log files", files)
+		}
+
+		if err := os.MkdirAll(settings.Dir, os.ModePerm); err != nil {
+			return fmt.Errorf("error creating log directory: %w", err)
+		}
+
+	case FilesOff:
+	default:
+		return fmt.Errorf("invalid log files %q", settings.Files)
+	}
+
+	settings.Format = strings.ToLower(settings.Format)
+	settings.Files = files
+
+	logFiles.mutex.Lock()
+	defer logFiles.mutex.Unlock()
+
+	logFiles.settings = &settings
+	logFiles.level = level
+	logFiles.combinedHook = nil
+	if files == FilesCombined {
+		logFiles.combinedHook = newFileHook(&settings, level, CombinedFileName)
+	}
+
+	for _, wl := range logFiles.loggers {
+		wl.applySettings()
+	}
+
+	return nil
+}
+
+// applySettings replaces the file hook of the logger with one for the
+// configured settings. The logger level is lowered to the log file level if
+// it's more verbose than the terminal, since entries below the logger level
+// never reach the hooks. The terminal formatter drops the extra entries.
+func (wl *Waterlogged) applySettings() {
+	settings := logFiles.settings
+	if settings == nil {
+		return
+	}
+
+	hooks := make(logrus.LevelHooks)
+	switch settings.Files {
+	case FilesPerService:
+		hooks.Add(newFileHook(settings, logFiles.level, wl.serviceName+".log"))
+
+	case FilesCombined:
+		hooks.Add(&serviceHook{Hook: logFiles.combinedHook, serviceName: wl.serviceName})
+	}
+	wl.ReplaceHooks(hooks)
+
+	level := terminalLevel
+	if settings.Files != FilesOff && logFiles.level > level {
+		level = logFiles.level
+	}
+	wl.SetLevel(level)
+}
+
+// newFileHook returns a hook that writes the entries at or above the specified
+// level to the file with the specified name in the log directory, rotating it
+// once it reaches the maximum size.
+func newFileHook(settings *Settings, level logrus.Level, fileName string) logrus.Hook {
+	var formatter logrus.Formatter = &logrus.TextFormatter{
+		DisableColors: true,
+		FullTimestamp: true,
+	}
+	if settings.Format == FormatJSON {
+		formatter = &logrus.JSONFormatter{}
+	}
 
 	hook, err := lumberjackrus.NewHook(
 		&lumberjackrus.LogFile{
-			Filename:  filepath.Join(logDirPath, wl.serviceName+".log"),
-			MaxSize:   100,
-			MaxAge:    60,
-			Compress:  false,
+			Filename:  filepath.Join(settings.Dir, fileName),
+			MaxSize:   settings.MaxSize,
+			MaxAge:    settings.MaxAge,
+			Compress:  settings.Compress,
 			LocalTime: false,
 		},
-		logrus.InfoLevel,
-		&logrus.TextFormatter{},
+		level,
+		formatter,
 		&lumberjackrus.LogFileOpts{},
 	)
-
 	if err != nil {
-		fmt.Printf("Error adding Lumberjack hook: %v\n", err)
+		// This only fails if the log file is nil.
+		panic("Failed to create log file hook: " + err.Error())
+	}
+
+	return hook
+}
+
+// serviceHook adds the service name to the entries before passing them to the
+// wrapped hook, so the entries in the combined file can be told apart.
+type serviceHook struct {
+	logrus.Hook
+	serviceName string
+}
+
+func (h *serviceHook) Fire(entry *logrus.Entry) error {
+	data := make(logrus.Fields, len(entry.Data)+1)
+	for key, value := range entry.Data {
+		data[key] = value
+	}
+	data["service"] = h.serviceName
+
+	withService := *entry
+	withService.Data = data
+	return h.Hook.Fire(&withService)
+}
+
+// terminalFormatter only formats the entries at or above the specified level,
+// so the logger level can be lowered to write more detail to the log files
+// without flooding the terminal.
+type terminalFormatter struct {
+	logrus.Formatter
+	level logrus.Level
+}
+
+func (f *terminalFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > f.level {
+		return nil, nil
 	}
 
-	wl.AddHook(hook)
+	return f.Formatter.Format(entry)
 }
@@ -0,0 +1,380 @@
+package whodunit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// Catalog is the in-memory representation of the episodes JSON file in the
+// `/assets` directory. It is loaded once and used for every season and
+// episode lookup, so the JSON file doesn't need to be parsed over and over.
+type Catalog struct {
+	seasons   map[seasonKey]*Season
+	episodes  []*Episode
+	workspace *Workspace
+}
+
+// seasonKey identifies a season of a series in the catalog.
+type seasonKey struct {
+	series       Series
+	seasonNumber int
+}
+
+// LoadCatalog returns a new catalog populated from the episodes JSON file at
+// the specified path.
+func LoadCatalog(path string) (*Catalog, error) {
+	jsonFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer jsonFile.Close()
+
+	return ReadCatalog(jsonFile)
+}
+
+// ReadCatalog returns a new catalog populated from the specified reader. The
+// contents need to be in the same format as the episodes JSON file, which is
+// an object of episodes keyed by padded season number. Seasons of a series
+// other than the default are keyed by series and padded season number (e.g.
+// "forensic-files-ii/01"). It returns an error if more than one episode has
+// the same series, season, and episode number.
+func ReadCatalog(r io.Reader) (*Catalog, error) {
+	episodes, err := ReadEpisodes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCatalog(episodes)
+}
+
+// LoadEpisodes returns the episodes in the episodes JSON file at the specified
+// path without building a catalog, so every entry is returned, including the
+// duplicates a catalog would reject.
+func LoadEpisodes(path string) ([]*Episode, error) {
+	jsonFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer jsonFile.Close()
+
+	return ReadEpisodes(jsonFile)
+}
+
+// ReadEpisodes returns the episodes read from the specified reader in the
+// same format as the episodes JSON file. The episodes are sorted by series,
+// season, and episode number, and entries with the same numbers are kept in
+// the order they appear in the file.
+func ReadEpisodes(r io.Reader) ([]*Episode, error) {
+	var result map[string][]*Episode
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(result))
+	for key := range result {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	episodes := make([]*Episode, 0)
+	for _, key := range keys {
+		episodes = append(episodes, result[key]...)
+	}
+
+	sortEpisodes(episodes)
+
+	return episodes, nil
+}
+
+// NewCatalog returns a new catalog containing the specified episodes. This is
+// useful for building a catalog from fixtures without touching the `/assets`
+// directory. The catalog doesn't belong to a workspace, so the assets of its
+// episodes can't be used until it's attached to one with SetWorkspace. It
+// returns an error if more than one episode has the same series, season, and
+// episode number.
+func NewCatalog(episodes []*Episode) (*Catalog, error) {
+	c := &Catalog{
+		seasons:  make(map[seasonKey]*Season),
+		episodes: make([]*Episode, 0, len(episodes)),
+	}
+
+	for _, ep := range episodes {
+		if err := c.add(ep); err != nil {
+			return nil, err
+		}
+	}
+
+	sortEpisodes(c.episodes)
+
+	return c, nil
+}
+
+// Add adds the specified episode to the catalog. It returns an error if the
+// catalog already has an episode with the same series, season, and episode
+// number. Call Save to write the changes to the episodes JSON file.
+func (c *Catalog) Add(ep *Episode) error {
+	if err := c.add(ep); err != nil {
+		return err
+	}
+
+	sortEpisodes(c.episodes)
+	return nil
+}
+
+func (c *Catalog) add(ep *Episode) error {
+	if existing := c.SeriesEpisode(ep.Series, ep.SeasonNumber, ep.EpisodeNumber); existing != nil {
+		return fmt.Errorf("catalog already has episode %s", existing.Name())
+	}
+
+	if ep.Series.IsDefault() {
+		ep.Series = ""
+	}
+
+	key := seasonKey{ep.Series, ep.SeasonNumber}
+	s, ok := c.seasons[key]
+	if !ok {
+		s = NewSeriesSeason(ep.Series, ep.SeasonNumber)
+		s.catalog = c
+		c.seasons[key] = s
+	}
+
+	ep.season = s
+	s.episodeMap[ep.EpisodeNumber] = ep
+	c.episodes = append(c.episodes, ep)
+	return nil
+}
+
+// Workspace returns the workspace the catalog belongs to or nil if it doesn't
+// belong to one.
+func (c *Catalog) Workspace() *Workspace {
+	return c.workspace
+}
+
+// SetWorkspace attaches the catalog to the specified workspace, so the assets
+// of its episodes are read from and written to the workspace.
+func (c *Catalog) SetWorkspace(ws *Workspace) {
+	c.workspace = ws
+}
+
+// Save writes the episodes in the catalog to the JSON file at the specified
+// path in the same format as the episodes JSON file. The contents are written
+// to a temporary file first, so the catalog is never left half-written.
+func (c *Catalog) Save(path string) error {
+	result := make(map[string][]*Episode)
+	for _, ep := range c.episodes {
+		key := crimeseen.PaddedNumberString(ep.SeasonNumber)
+		if !ep.Series.IsDefault() {
+			key = fmt.Sprintf("%s/%s", ep.Series, key)
+		}
+		result[key] = append(result[key], ep)
+	}
+
+	file, err := crimeseen.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	// HTML escaping is disabled, so the `&` in the YouTube URLs isn't written
+	// as `\u0026`.
+	enc := json.NewEncoder(file)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// Season returns the season of the default series associated with the
+// specified season number or nil if the season isn't in the catalog.
+func (c *Catalog) Season(seasonNumber int) *Season {
+	return c.SeriesSeason("", seasonNumber)
+}
+
+// SeriesSeason returns the season of the specified series associated with
+// the specified season number or nil if the season isn't in the catalog.
+func (c *Catalog) SeriesSeason(series Series, seasonNumber int) *Season {
+	if series.IsDefault() {
+		series = ""
+	}
+
+	return c.seasons[seasonKey{series, seasonNumber}]
+}
+
+// Seasons returns all of the seasons in the catalog sorted by series and
+// season number.
+func (c *Catalog) Seasons() []*Season {
+	seasons := make([]*Season, 0, len(c.seasons))
+	for _, s := range c.seasons {
+		seasons = append(seasons, s)
+	}
+
+	sort.Slice(seasons, func(i, j int) bool {
+		if seasons[i].Series != seasons[j].Series {
+			return seasons[i].Series.Before(seasons[j].Series)
+		}
+		return seasons[i].SeasonNumber < seasons[j].SeasonNumber
+	})
+
+	return seasons
+}
+
+// Series returns the series in the catalog with the default series first.
+func (c *Catalog) Series() []Series {
+	series := make([]Series, 0)
+	for _, s := range c.Seasons() {
+		if len(series) == 0 || series[len(series)-1] != s.Series.Key() {
+			series = append(series, s.Series.Key())
+		}
+	}
+
+	return series
+}
+
+// SeasonCount returns the count of seasons of the default series in the
+// catalog.
+func (c *Catalog) SeasonCount() int {
+	return c.SeriesSeasonCount("")
+}
+
+// SeriesSeasonCount returns the count of seasons of the specified series in
+// the catalog.
+func (c *Catalog) SeriesSeasonCount(series Series) int {
+	count := 0
+	for key := range c.seasons {
+		if key.series.Key() == series.Key() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Episode returns the episode of the default series associated with the
+// specified season and episode number or nil if the episode isn't in the
+// catalog.
+func (c *Catalog) Episode(seasonNumber int, episodeNumber int) *Episode {
+	return c.SeriesEpisode("", seasonNumber, episodeNumber)
+}
+
+// SeriesEpisode returns the episode of the specified series associated with
+// the specified season and episode number or nil if the episode isn't in the
+// catalog.
+func (c *Catalog) SeriesEpisode(
+	series Series,
+	seasonNumber int,
+	episodeNumber int,
+) *Episode {
+	s := c.SeriesSeason(series, seasonNumber)
+	if s == nil {
+		return nil
+	}
+
+	return s.Episode(episodeNumber)
+}
+
+// EpisodeByName returns the episode with the specified name (e.g.
+// "03-02-knot-for-everyone") or nil if no episode matches. The name can also
+// be a file path, in which case the directory and extension are ignored.
+func (c *Catalog) EpisodeByName(name string) *Episode {
+	base := filepath.Base(name)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	for _, ep := range c.episodes {
+		if ep.Name() == base {
+			return ep
+		}
+	}
+
+	return nil
+}
+
+// AssetOwner returns the episode that owns the asset file at the specified
+// path in the asset storage or nil if there isn't one. A file is owned if it's
+// in the season directory of an episode (which includes the series directory
+// for series other than the default) and its name (ignoring extensions) is the
+// episode's name.
+func (c *Catalog) AssetOwner(assetType AssetType, filePath string) *Episode {
+	name := path.Base(filePath)
+	if index := strings.Index(name, "."); index != -1 {
+		name = name[:index]
+	}
+
+	for _, ep := range c.episodes {
+		if ep.Name() != name {
+			continue
+		}
+
+		if path.Dir(filePath) == path.Join(assetType.DirName(), ep.season.DirName()) {
+			return ep
+		}
+	}
+
+	return nil
+}
+
+// FindByTitle returns the episodes with a title that contains the specified
+// value. The comparison is case-insensitive and treats hyphens and spaces
+// the same.
+func (c *Catalog) FindByTitle(value string) []*Episode {
+	search := strings.ToLower(strings.ReplaceAll(value, " ", "-"))
+
+	episodes := make([]*Episode, 0)
+	for _, ep := range c.episodes {
+		if strings.Contains(strings.ToLower(ep.Title), search) {
+			episodes = append(episodes, ep)
+		}
+	}
+
+	return episodes
+}
+
+// Episodes returns all of the episodes in the catalog sorted by series,
+// season, and episode number.
+func (c *Catalog) Episodes() []*Episode {
+	episodes := make([]*Episode, len(c.episodes))
+	copy(episodes, c.episodes)
+	return episodes
+}
+
+// EpisodeCount returns the count of episodes in the catalog.
+func (c *Catalog) EpisodeCount() int {
+	return len(c.episodes)
+}
+
+// Solve runs the specified function for every episode in the catalog that is
+// in the specified selection.
+func (c *Catalog) Solve(sel *Selection, onEpisode func(ep *Episode)) error {
+	episodes := c.Select(sel)
+	if len(episodes) == 0 {
+		return fmt.Errorf("no episodes found for selection %q", sel)
+	}
+
+	for _, ep := range episodes {
+		onEpisode(ep)
+	}
+
+	return nil
+}
+
+func sortEpisodes(episodes []*Episode) {
+	sort.SliceStable(episodes, func(i, j int) bool {
+		if episodes[i].Series != episodes[j].Series {
+			return episodes[i].Series.Before(episodes[j].Series)
+		}
+		if episodes[i].SeasonNumber != episodes[j].SeasonNumber {
+			return episodes[i].SeasonNumber < episodes[j].SeasonNumber
+		}
+		return episodes[i].EpisodeNumber < episodes[j].EpisodeNumber
+	})
+}
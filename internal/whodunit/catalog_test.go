@@ -0,0 +1,183 @@
+package whodunit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCatalogJSON = `{
+  "01": [
+    {
+      "season": 1,
+      "episode": 2,
+      "title": "the-magic-bullet",
+      "url": "https://www.youtube.com/watch?v=Bn8Oeae3j-c&list=PL&index=2"
+    },
+    {
+      "season": 1,
+      "episode": 1,
+      "title": "the-disappearance-of-helle-crafts",
+      "url": "https://www.youtube.com/watch?v=wV8pNYh8diI&list=PL&index=1"
+    }
+  ],
+  "03": [
+    {
+      "season": 3,
+      "episode": 2,
+      "title": "knot-for-everyone",
+      "url": "https://www.youtube.com/watch?v=abc",
+      "airDate": "1998-10-22",
+      "imdbId": "tt0000001",
+      "runtime": 30,
+      "rating": 8.1
+    }
+  ],
+  "forensic-files-ii/01": [
+    {
+      "series": "forensic-files-ii",
+      "season": 1,
+      "episode": 1,
+      "title": "the-cold-case",
+      "url": ""
+    }
+  ]
+}`
+
+func TestCatalogRoundTrip(t *testing.T) {
+	c, err := ReadCatalog(strings.NewReader(testCatalogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNames := []string{
+		"01-01-the-disappearance-of-helle-crafts",
+		"01-02-the-magic-bullet",
+		"03-02-knot-for-everyone",
+		"forensic-files-ii-01-01-the-cold-case",
+	}
+	if got := episodeNames(c.Episodes()); !reflect.DeepEqual(got, wantNames) {
+		t.Fatalf("got episodes %q, want %q", got, wantNames)
+	}
+
+	dir, err := ioutil.TempDir("", "whodunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "episodes.json")
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`"forensic-files-ii/01": [`, "&list=PL&index=2"} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("saved catalog doesn't contain %q:\n%s", want, contents)
+		}
+	}
+
+	saved, err := LoadCatalog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := episodeNames(saved.Episodes()); !reflect.DeepEqual(got, wantNames) {
+		t.Fatalf("got saved episodes %q, want %q", got, wantNames)
+	}
+
+	for i, ep := range saved.Episodes() {
+		want := *c.Episodes()[i]
+		got := *ep
+		want.season, got.season = nil, nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got saved episode %+v, want %+v", got, want)
+		}
+	}
+
+	if got := saved.Series(); !reflect.DeepEqual(got, []Series{SeriesForensicFiles, SeriesForensicFilesII}) {
+		t.Errorf("got series %q", got)
+	}
+
+	if ep := saved.SeriesEpisode(SeriesForensicFilesII, 1, 1); ep == nil || ep.Season().DirName() != "forensic-files-ii/season-1" {
+		t.Errorf("got episode %v in the wrong season", ep)
+	}
+
+	if ep := saved.EpisodeByName("03-02-knot-for-everyone"); ep == nil || ep.Runtime != 30 {
+		t.Errorf("got episode %v by name", ep)
+	}
+}
+
+func episodeNames(episodes []*Episode) []string {
+	names := make([]string, 0, len(episodes))
+	for _, ep := range episodes {
+		names = append(names, ep.Name())
+	}
+
+	return names
+}
+
+func TestReadCatalogDuplicates(t *testing.T) {
+	contents := `{
+  "03": [
+    {"season": 3, "episode": 2, "title": "knot-for-everyone", "url": ""},
+    {"season": 3, "episode": 2, "title": "knot-for-anyone", "url": ""}
+  ]
+}`
+
+	if _, err := ReadCatalog(strings.NewReader(contents)); err == nil ||
+		!strings.Contains(err.Error(), "03-02-knot-for-everyone") {
+		t.Errorf("got error %v, want duplicate of 03-02-knot-for-everyone", err)
+	}
+
+	episodes, err := ReadEpisodes(strings.NewReader(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"03-02-knot-for-everyone", "03-02-knot-for-anyone"}
+	if got := episodeNames(episodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("got episodes %q, want %q", got, want)
+	}
+
+	c, err := NewCatalog(episodes[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Add(episodes[1]); err == nil {
+		t.Error("expected an error adding a duplicate episode")
+	}
+
+	if len(c.Episodes()) != 1 || c.Season(3).Episode(2) != episodes[0] {
+		t.Errorf("got episodes %q after adding a duplicate", episodeNames(c.Episodes()))
+	}
+}
+
+func TestFindByTitle(t *testing.T) {
+	c, err := ReadCatalog(strings.NewReader(testCatalogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]string{
+		"the":          {"01-01-the-disappearance-of-helle-crafts", "01-02-the-magic-bullet", "forensic-files-ii-01-01-the-cold-case"},
+		"Magic Bullet": {"01-02-the-magic-bullet"},
+		"knot-for":     {"03-02-knot-for-everyone"},
+		"xylophone":    {},
+	}
+
+	for value, want := range tests {
+		if got := episodeNames(c.FindByTitle(value)); !reflect.DeepEqual(got, want) {
+			t.Errorf("FindByTitle(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package whodunit
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -12,11 +14,17 @@ import (
 // Episode is the high-level representation of a file in the `/assets` directory.
 // An Episode has an associated audio file, video file, recognition, etc.
 type Episode struct {
-	SeasonNumber  int    `json:"season"`
-	EpisodeNumber int    `json:"episode"`
-	Title         string `json:"title"`
-	URL           string `json:"url"`
-	assetStatus   AssetStatus
+	Series        Series                   `json:"series,omitempty"`
+	SeasonNumber  int                      `json:"season"`
+	EpisodeNumber int                      `json:"episode"`
+	Title         string                   `json:"title"`
+	URL           string                   `json:"url"`
+	AirDate       string                   `json:"airDate,omitempty"`
+	Plot          string                   `json:"plot,omitempty"`
+	IMDbID        string                   `json:"imdbId,omitempty"`
+	Runtime       int                      `json:"runtime,omitempty"`
+	Rating        float64                  `json:"rating,omitempty"`
+	Media         map[AssetType]*MediaInfo `json:"media,omitempty"`
 	season        *Season
 }
 
@@ -28,6 +36,7 @@ func newEpisode(
 	url string,
 ) *Episode {
 	return &Episode{
+		Series:        season.Series,
 		SeasonNumber:  season.SeasonNumber,
 		EpisodeNumber: episodeNumber,
 		Title:         title,
@@ -36,46 +45,47 @@ func newEpisode(
 	}
 }
 
+// episodeNameRegexp matches an episode name, which may be prefixed with the
+// key of the series. No part of a series key is only numbers, so the first
+// part that is ends the series and starts the season and episode numbers, even
+// if the title contains more numbers (e.g. "forensic-files-ii-01-02-a-3-4-b").
+var episodeNameRegexp = regexp.MustCompile(`^(?:(` + seriesKeyPattern + `)-)?(\d+)-(\d+)-(.*)$`)
+
 // NewEpisodeFromName returns a new instance of an Episode from parsing the
 // specified name.
 //
 // For example, calling NewEpisodeFromName("03-02-knot-for-everyone") would
 // return an Episode instance with season number 3, episode number 2, and a
-// title of "knot-for-everyone".
+// title of "knot-for-everyone". Calling it with
+// "forensic-files-ii-01-02-the-cold-case" would return an episode of the
+// Forensic Files II series.
 func NewEpisodeFromName(name string) (*Episode, error) {
-	// If the name is a file path, throw out the path.
+	// If the name is a file path, throw out the path and the extension.
 	base := filepath.Base(name)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	matches := episodeNameRegexp.FindStringSubmatch(base)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid episode name %q", base)
+	}
 
-	// Split the name up by hyphens. The first and second elements of the slice
-	// represent the season and episode respectively. The rest of the elements
-	// need to be combined back together to form the title.
-	values := strings.Split(base, "-")
-	seasonNumber, err := strconv.Atoi(values[0])
+	series, err := ParseSeries(matches[1])
 	if err != nil {
 		return nil, err
 	}
 
-	episodeNumber, err := strconv.Atoi(values[1])
+	seasonNumber, err := strconv.Atoi(matches[2])
 	if err != nil {
 		return nil, err
 	}
 
-	titles := make([]string, 0)
-	for i := 2; i < len(values); i++ {
-		titles = append(titles, values[i])
+	episodeNumber, err := strconv.Atoi(matches[3])
+	if err != nil {
+		return nil, err
 	}
-	title := strings.Join(titles, "-")
-
-	// Ensure the file extension (if any) is stripped from the title.
-	title = strings.Replace(title, filepath.Ext(title), "", -1)
 
-	return &Episode{
-		SeasonNumber:  seasonNumber,
-		EpisodeNumber: episodeNumber,
-		Title:         title,
-		URL:           "",
-		season:        NewSeason(seasonNumber),
-	}, nil
+	return newEpisode(NewSeriesSeason(series, seasonNumber),
+		episodeNumber, matches[4], ""), nil
 }
 
 // DisplayTitle returns the Title property separated by spaces with title case.
@@ -83,27 +93,17 @@ func (e *Episode) DisplayTitle() string {
 	return strings.Title(strings.ReplaceAll(e.Title, "-", " "))
 }
 
-// AssetExists returns true if the file associated with the specified asset
-// type exists in the `/assets` directory.
-func (e *Episode) AssetExists(assetType AssetType) bool {
-	path := e.AssetFilePath(assetType)
-	exists := crimeseen.FileExists(path)
-	if assetType != AssetTypeVideo {
-		return exists
-	}
-
-	if !exists {
-		mkvPath := strings.Replace(path, ".mp4", ".mkv", -1)
-		return crimeseen.FileExists(mkvPath)
-	}
-
-	return true
+// AssetStale returns true if the asset exists, but one of the upstream assets
+// it was produced from has changed since.
+func (e *Episode) AssetStale(assetType AssetType) bool {
+	return e.AssetStatus(assetType) == AssetStatusStale
 }
 
-// AssetFilePath returns the absolute path to the asset file for the episode.
-func (e *Episode) AssetFilePath(assetType AssetType) string {
-	return filepath.Join(assetType.DirPath(),
-		e.season.DirName(), e.AssetFileName(assetType))
+// AssetComplete returns true if the asset has been processed and isn't in
+// process, failed, stale, or missing. Use this to check if an asset is safe to
+// use as the input for the next stage.
+func (e *Episode) AssetComplete(assetType AssetType) bool {
+	return e.AssetStatus(assetType) == AssetStatusComplete
 }
 
 // AssetFileName returns the file name of the episode with the appropriate
@@ -112,37 +112,189 @@ func (e *Episode) AssetFileName(assetType AssetType) string {
 	return fmt.Sprintf("%s%s", e.Name(), assetType.FileExt())
 }
 
-// SetAssetStatus allows you to override the asset status extrapolated from
-// whether the file currently exists.
-func (e *Episode) SetAssetStatus(status AssetStatus) {
-	e.assetStatus = status
-}
-
 // AssetStatus returns the current status of the asset associated with the
-// episode.
+// episode. The status is resolved by the status resolver registered for the
+// asset type, which defaults to DefaultAssetStatus.
 func (e *Episode) AssetStatus(assetType AssetType) AssetStatus {
-	if e.assetStatus != 0 {
-		return e.assetStatus
-	}
-
 	if e.URL == "" {
 		return AssetStatusMissing
 	}
 
-	assetExists := e.AssetExists(assetType)
-	if assetExists {
+	if resolve := assetType.spec().ResolveStatus; resolve != nil {
+		return resolve(e, assetType)
+	}
+
+	return DefaultAssetStatus(e, assetType)
+}
+
+// DefaultAssetStatus returns the status of the asset associated with the
+// episode from the ledger if the asset has been processed before, otherwise
+// it's extrapolated from whether the file exists. Status resolvers can call
+// this to build on the default behavior.
+func DefaultAssetStatus(ep *Episode, assetType AssetType) AssetStatus {
+	if entry := ep.LedgerEntry(assetType); entry != nil {
+		switch entry.Status {
+		case AssetStatusInProcess, AssetStatusFailed:
+			return entry.Status
+
+		case AssetStatusPending:
+			return AssetStatusPending
+		}
+	}
+
+	if ep.AssetExists(assetType) {
+		if ep.isStale(assetType) {
+			return AssetStatusStale
+		}
 		return AssetStatusComplete
 	}
 
 	return AssetStatusPending
 }
 
+// RenameLedgerEntries moves the ledger entries recorded under the specified
+// old name of the episode to the episode's current name.
+func (e *Episode) RenameLedgerEntries(oldName string) error {
+	l, err := e.ledger()
+	if err != nil {
+		return err
+	}
+
+	return l.Rename(oldName, e)
+}
+
+// LedgerEntry returns the ledger entry for the asset associated with the
+// episode or nil if the asset has never been processed.
+func (e *Episode) LedgerEntry(assetType AssetType) *LedgerEntry {
+	l, err := e.ledger()
+	if err != nil {
+		return nil
+	}
+
+	return l.Entry(e, assetType)
+}
+
+// BeginAsset records the start of an attempt to produce the asset in the
+// ledger.
+func (e *Episode) BeginAsset(assetType AssetType) error {
+	l, err := e.ledger()
+	if err != nil {
+		return err
+	}
+
+	return l.Begin(e, assetType)
+}
+
+// CompleteAsset records that the asset was produced successfully in the
+// ledger and adds the asset file to the season manifest. If the asset file is
+// missing or can't be read, it's recorded as failed instead.
+func (e *Episode) CompleteAsset(assetType AssetType) error {
+	l, err := e.ledger()
+	if err != nil {
+		return err
+	}
+
+	entry, err := e.Fingerprint(context.Background(), assetType)
+	if err != nil {
+		err = fmt.Errorf("error checking %s: %w", e.AssetFileName(assetType), err)
+		if failErr := l.Fail(e, assetType, err); failErr != nil {
+			return failErr
+		}
+		return err
+	}
+
+	if err := e.RecordManifestEntry(entry); err != nil {
+		return err
+	}
+
+	return l.Complete(e, assetType, entry.SHA256, e.inputHashes(assetType))
+}
+
+// FailAsset records that the attempt to produce the asset failed with the
+// specified error in the ledger.
+func (e *Episode) FailAsset(assetType AssetType, cause error) error {
+	l, err := e.ledger()
+	if err != nil {
+		return err
+	}
+
+	return l.Fail(e, assetType, cause)
+}
+
+// ResetAsset sets the status of the asset back to pending in the ledger, so it
+// gets processed again. The specified reason is stored as the last error.
+func (e *Episode) ResetAsset(assetType AssetType, reason string) error {
+	l, err := e.ledger()
+	if err != nil {
+		return err
+	}
+
+	return l.Reset(e, assetType, reason)
+}
+
+// Track records an attempt to produce the asset in the ledger, calls the
+// specified function to produce it, and records whether it succeeded or
+// failed. The asset is locked the whole time, so another process can't write
+// it at the same time. The error returned from the function is returned as-is.
+func (e *Episode) Track(assetType AssetType, produce func() error) error {
+	unlock, err := e.LockAsset(assetType)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := e.BeginAsset(assetType); err != nil {
+		return err
+	}
+
+	if err := produce(); err != nil {
+		if ledgerErr := e.FailAsset(assetType, err); ledgerErr != nil {
+			return fmt.Errorf("%w (unable to update ledger: %v)", err, ledgerErr)
+		}
+		return err
+	}
+
+	return e.CompleteAsset(assetType)
+}
+
+// Workspace returns the workspace of the catalog the episode belongs to. It
+// returns ErrNoWorkspace if the episode doesn't belong to a catalog that was
+// attached to a workspace.
+func (e *Episode) Workspace() (*Workspace, error) {
+	if e.season == nil {
+		return nil, fmt.Errorf("episode %s: %w", e.Name(), ErrNoWorkspace)
+	}
+
+	return e.season.Workspace()
+}
+
+// ledger returns the ledger of the workspace the episode belongs to.
+func (e *Episode) ledger() (*Ledger, error) {
+	ws, err := e.Workspace()
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.Ledger()
+}
+
 // Name returns the name of the episode in the common format used throughout
 // the `/assets` directory: xx-yy-zz, where xx is the season, yy is the
-// episode number, and zz is the title.
+// episode number, and zz is the title. Episodes of a series other than the
+// default are prefixed with the series key (e.g. "forensic-files-ii-01-02-zz").
 func (e *Episode) Name() string {
-	return fmt.Sprintf("%s-%s-%s",
+	name := fmt.Sprintf("%s-%s-%s",
 		crimeseen.PaddedNumberString(e.SeasonNumber),
 		crimeseen.PaddedNumberString(e.EpisodeNumber),
 		e.Title)
+	if e.Series.IsDefault() {
+		return name
+	}
+
+	return fmt.Sprintf("%s-%s", e.Series, name)
+}
+
+// Season returns the season the episode belongs to.
+func (e *Episode) Season() *Season {
+	return e.season
 }
@@ -0,0 +1,114 @@
+package whodunit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewEpisodeFromName(t *testing.T) {
+	tests := []struct {
+		name    string
+		series  Series
+		season  int
+		episode int
+		title   string
+	}{
+		{"03-02-knot-for-everyone", SeriesForensicFiles, 3, 2, "knot-for-everyone"},
+		{"/assets/videos/season-03/03-02-knot-for-everyone.mp4", SeriesForensicFiles, 3, 2, "knot-for-everyone"},
+		{"13-10-4-on-the-floor", SeriesForensicFiles, 13, 10, "4-on-the-floor"},
+		{"forensic-files-ii-01-02-the-cold-case", SeriesForensicFilesII, 1, 2, "the-cold-case"},
+		{"forensic-files-ii-01-02-a-3-4-b", SeriesForensicFilesII, 1, 2, "a-3-4-b"},
+		{"forensic-files-ii-01-02-3-4-b", SeriesForensicFilesII, 1, 2, "3-4-b"},
+		{"forensic-files-2-1-3-title", SeriesForensicFiles, 2, 1, "3-title"},
+		{"forensic-files-2nd-1-3-title", Series("forensic-files-2nd"), 1, 3, "title"},
+	}
+
+	for _, test := range tests {
+		ep, err := NewEpisodeFromName(test.name)
+		if err != nil {
+			t.Errorf("NewEpisodeFromName(%q) returned error: %s", test.name, err)
+			continue
+		}
+
+		if ep.Series.Key() != test.series ||
+			ep.SeasonNumber != test.season ||
+			ep.EpisodeNumber != test.episode ||
+			ep.Title != test.title {
+			t.Errorf("NewEpisodeFromName(%q) = %s s%de%d %q, want %s s%de%d %q",
+				test.name, ep.Series, ep.SeasonNumber, ep.EpisodeNumber, ep.Title,
+				test.series, test.season, test.episode, test.title)
+		}
+	}
+
+	for _, name := range []string{"", "knot-for-everyone", "03-knot-for-everyone", "Forensic-03-02-x"} {
+		if _, err := NewEpisodeFromName(name); err == nil {
+			t.Errorf("NewEpisodeFromName(%q) didn't return an error", name)
+		}
+	}
+}
+
+func TestParseSeries(t *testing.T) {
+	tests := map[string]Series{
+		"":                   SeriesForensicFiles,
+		"Forensic-Files-II":  SeriesForensicFilesII,
+		"forensic-files-2nd": "forensic-files-2nd",
+		"files-b2":           "files-b2",
+	}
+
+	for key, want := range tests {
+		if got, err := ParseSeries(key); err != nil || got != want {
+			t.Errorf("ParseSeries(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+
+	for _, key := range []string{"forensic-files-2", "2-files", "forensic--files", "forensic_files", "files-"} {
+		if _, err := ParseSeries(key); err == nil {
+			t.Errorf("ParseSeries(%q) didn't return an error", key)
+		}
+	}
+}
+
+func TestEpisodeWithoutWorkspace(t *testing.T) {
+	c, err := ReadCatalog(strings.NewReader(testCatalogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detached := c.EpisodeByName("01-02-the-magic-bullet")
+	orphan := &Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone", URL: "x"}
+
+	for _, ep := range []*Episode{detached, orphan} {
+		if _, err := ep.Workspace(); !errors.Is(err, ErrNoWorkspace) {
+			t.Errorf("Workspace() of %s returned error %v, want ErrNoWorkspace", ep.Name(), err)
+		}
+
+		if _, err := ep.LockAsset(AssetTypeTranscript); !errors.Is(err, ErrNoWorkspace) {
+			t.Errorf("LockAsset() of %s returned error %v, want ErrNoWorkspace", ep.Name(), err)
+		}
+
+		if err := ep.BeginAsset(AssetTypeTranscript); !errors.Is(err, ErrNoWorkspace) {
+			t.Errorf("BeginAsset() of %s returned error %v, want ErrNoWorkspace", ep.Name(), err)
+		}
+
+		if err := ep.RecordManifestEntry(&ManifestEntry{Name: ep.Name()}); !errors.Is(err, ErrNoWorkspace) {
+			t.Errorf("RecordManifestEntry() of %s returned error %v, want ErrNoWorkspace", ep.Name(), err)
+		}
+
+		if got := ep.LedgerEntry(AssetTypeTranscript); got != nil {
+			t.Errorf("LedgerEntry() of %s = %+v, want nil", ep.Name(), got)
+		}
+	}
+
+	if _, err := detached.ReadAsset(AssetTypeTranscript); !errors.Is(err, ErrNoWorkspace) {
+		t.Errorf("ReadAsset() returned error %v, want ErrNoWorkspace", err)
+	}
+
+	if got := detached.AssetStatus(AssetTypeTranscript); got != AssetStatusPending {
+		t.Errorf("AssetStatus() = %s, want %s", got, AssetStatusPending)
+	}
+
+	if _, err := detached.Season().ManifestPath(); !errors.Is(err, ErrNoWorkspace) {
+		t.Errorf("ManifestPath() returned error %v, want ErrNoWorkspace", err)
+	}
+}
@@ -0,0 +1,279 @@
+package whodunit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// LedgerEntry is the persisted pipeline state of a single asset associated
+// with an episode.
+type LedgerEntry struct {
+	Name          string      `json:"name"`
+	Series        Series      `json:"series,omitempty"`
+	SeasonNumber  int         `json:"season"`
+	EpisodeNumber int         `json:"episode"`
+	AssetType     AssetType   `json:"assetType"`
+	Status        AssetStatus `json:"status"`
+	Attempts      int         `json:"attempts"`
+	StartedAt     time.Time   `json:"startedAt"`
+	UpdatedAt     time.Time   `json:"updatedAt"`
+	CompletedAt   time.Time   `json:"completedAt"`
+	LastError     string      `json:"lastError,omitempty"`
+	Hash          string      `json:"hash,omitempty"`
+
+	// Inputs contains the hashes of the upstream assets at the time the
+	// asset was produced, which is used to check if the asset is stale.
+	Inputs map[AssetType]string `json:"inputs,omitempty"`
+}
+
+// Ledger is a local store of the pipeline state for every asset of every
+// episode. It's persisted to a JSON file, so the status of an asset (along
+// with when and why it failed) is remembered after the process exits. Updates
+// hold a lock on a `.lock` file next to the ledger file, so the callback
+// server and the commands can update it at the same time.
+type Ledger struct {
+	path    string
+	mutex   sync.Mutex
+	entries map[string]*LedgerEntry
+}
+
+// ledgerFile is the layout of the ledger JSON file.
+type ledgerFile struct {
+	Entries []*LedgerEntry `json:"entries"`
+}
+
+// OpenLedger returns the ledger stored at the specified path. If the file
+// doesn't exist yet, the ledger starts out empty and the file is created the
+// first time an entry is updated.
+func OpenLedger(path string) (*Ledger, error) {
+	l := &Ledger{
+		path:    path,
+		entries: make(map[string]*LedgerEntry),
+	}
+
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Entry returns a copy of the ledger entry for the specified episode and asset
+// type or nil if the asset has never been processed.
+func (l *Ledger) Entry(ep *Episode, assetType AssetType) *LedgerEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry, ok := l.entries[ledgerKey(ep, assetType)]
+	if !ok {
+		return nil
+	}
+
+	entryCopy := *entry
+	return &entryCopy
+}
+
+// Entries returns a copy of every entry in the ledger sorted by episode name
+// and asset type.
+func (l *Ledger) Entries() []*LedgerEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.sortedEntries()
+}
+
+// Begin records the start of an attempt to produce the asset for the
+// specified episode.
+func (l *Ledger) Begin(ep *Episode, assetType AssetType) error {
+	return l.update(ep, assetType, func(entry *LedgerEntry) error {
+		entry.Status = AssetStatusInProcess
+		entry.Attempts++
+		entry.StartedAt = entry.UpdatedAt
+		return nil
+	})
+}
+
+// Complete records that the asset for the specified episode was produced
+// successfully along with the hash of the asset file and the hashes of the
+// upstream assets it was produced from.
+func (l *Ledger) Complete(
+	ep *Episode,
+	assetType AssetType,
+	hash string,
+	inputs map[AssetType]string,
+) error {
+	return l.update(ep, assetType, func(entry *LedgerEntry) error {
+		entry.Status = AssetStatusComplete
+		entry.CompletedAt = entry.UpdatedAt
+		entry.LastError = ""
+		entry.Hash = hash
+		entry.Inputs = inputs
+		return nil
+	})
+}
+
+// Fail records that the attempt to produce the asset for the specified episode
+// failed with the specified error.
+func (l *Ledger) Fail(ep *Episode, assetType AssetType, cause error) error {
+	return l.update(ep, assetType, func(entry *LedgerEntry) error {
+		entry.Status = AssetStatusFailed
+		entry.LastError = cause.Error()
+		return nil
+	})
+}
+
+// Reset sets the status of the asset for the specified episode back to
+// pending, so it gets processed again.
+func (l *Ledger) Reset(ep *Episode, assetType AssetType, reason string) error {
+	return l.update(ep, assetType, func(entry *LedgerEntry) error {
+		entry.Status = AssetStatusPending
+		entry.LastError = reason
+		entry.Hash = ""
+		return nil
+	})
+}
+
+// Rename moves the entries recorded under the specified old name of the
+// episode (e.g. before its title was corrected) to the episode's current name.
+// Existing entries under the current name are replaced.
+func (l *Ledger) Rename(oldName string, ep *Episode) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := l.load(); err != nil {
+		return err
+	}
+
+	for key, entry := range l.entries {
+		if entry.Name != oldName || entry.Series != ep.Series {
+			continue
+		}
+
+		delete(l.entries, key)
+		entry.Name = ep.Name()
+		l.entries[entry.key()] = entry
+	}
+
+	return l.save()
+}
+
+// update reloads the ledger file (in case another process changed it), calls
+// the specified function to change the entry for the episode and asset type,
+// and writes the results back to the file.
+func (l *Ledger) update(
+	ep *Episode,
+	assetType AssetType,
+	change func(entry *LedgerEntry) error,
+) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := l.load(); err != nil {
+		return err
+	}
+
+	key := ledgerKey(ep, assetType)
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &LedgerEntry{
+			Name:          ep.Name(),
+			Series:        ep.Series,
+			SeasonNumber:  ep.SeasonNumber,
+			EpisodeNumber: ep.EpisodeNumber,
+			AssetType:     assetType,
+		}
+		l.entries[key] = entry
+	}
+
+	entry.UpdatedAt = time.Now().UTC()
+	if err := change(entry); err != nil {
+		return err
+	}
+
+	return l.save()
+}
+
+func (l *Ledger) load() error {
+	contents, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var lf ledgerFile
+	if err := json.Unmarshal(contents, &lf); err != nil {
+		return fmt.Errorf("error parsing ledger %s: %w", l.path, err)
+	}
+
+	entries := make(map[string]*LedgerEntry, len(lf.Entries))
+	for _, entry := range lf.Entries {
+		entries[entry.key()] = entry
+	}
+	l.entries = entries
+
+	return nil
+}
+
+// lock blocks until no other process is updating the ledger file and returns
+// the function that releases the lock.
+func (l *Ledger) lock() (func(), error) {
+	fl, err := crimeseen.LockFile(l.path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("error locking ledger %s: %w", l.path, err)
+	}
+
+	return func() { fl.Unlock() }, nil
+}
+
+// save writes the ledger to a temporary file and renames it to the ledger
+// path, so a crash partway through doesn't leave a corrupt ledger behind.
+func (l *Ledger) save() error {
+	lf := &ledgerFile{Entries: l.sortedEntries()}
+	return crimeseen.WriteJSONFile(l.path, lf)
+}
+
+func (l *Ledger) sortedEntries() []*LedgerEntry {
+	entries := make([]*LedgerEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entryCopy := *entry
+		entries = append(entries, &entryCopy)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].AssetType < entries[j].AssetType
+	})
+
+	return entries
+}
+
+func (entry *LedgerEntry) key() string {
+	return fmt.Sprintf("%s/%s", entry.AssetType, entry.Name)
+}
+
+func ledgerKey(ep *Episode, assetType AssetType) string {
+	return fmt.Sprintf("%s/%s", assetType, ep.Name())
+}
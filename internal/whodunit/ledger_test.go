@@ -0,0 +1,199 @@
+package whodunit
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLedgerTransitions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whodunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ledger.json")
+	l, err := OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ep := &Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone"}
+	if entry := l.Entry(ep, AssetTypeAudio); entry != nil {
+		t.Fatalf("got entry %+v before the asset was processed", entry)
+	}
+
+	inputs := map[AssetType]string{AssetTypeVideo: "videohash"}
+	tests := []struct {
+		name      string
+		update    func() error
+		status    AssetStatus
+		attempts  int
+		lastError string
+		hash      string
+	}{
+		{
+			name:     "begin",
+			update:   func() error { return l.Begin(ep, AssetTypeAudio) },
+			status:   AssetStatusInProcess,
+			attempts: 1,
+		},
+		{
+			name:      "fail",
+			update:    func() error { return l.Fail(ep, AssetTypeAudio, errors.New("ffmpeg exited with status 1")) },
+			status:    AssetStatusFailed,
+			attempts:  1,
+			lastError: "ffmpeg exited with status 1",
+		},
+		{
+			name:      "retry",
+			update:    func() error { return l.Begin(ep, AssetTypeAudio) },
+			status:    AssetStatusInProcess,
+			attempts:  2,
+			lastError: "ffmpeg exited with status 1",
+		},
+		{
+			name:     "complete",
+			update:   func() error { return l.Complete(ep, AssetTypeAudio, "audiohash", inputs) },
+			status:   AssetStatusComplete,
+			attempts: 2,
+			hash:     "audiohash",
+		},
+		{
+			name:      "reset",
+			update:    func() error { return l.Reset(ep, AssetTypeAudio, "video changed") },
+			status:    AssetStatusPending,
+			attempts:  2,
+			lastError: "video changed",
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.update(); err != nil {
+			t.Fatalf("%s: got error %q", test.name, err)
+		}
+
+		entry := l.Entry(ep, AssetTypeAudio)
+		if entry.Status != test.status || entry.Attempts != test.attempts ||
+			entry.LastError != test.lastError || entry.Hash != test.hash {
+			t.Errorf("%s: got %s, %d attempt(s), %q, %q, want %s, %d attempt(s), %q, %q",
+				test.name, entry.Status, entry.Attempts, entry.LastError, entry.Hash,
+				test.status, test.attempts, test.lastError, test.hash)
+		}
+
+		if entry.Name != ep.Name() || entry.SeasonNumber != 3 || entry.EpisodeNumber != 2 ||
+			entry.UpdatedAt.IsZero() || entry.StartedAt.IsZero() {
+			t.Errorf("%s: got entry %+v", test.name, entry)
+		}
+	}
+
+	entry := l.Entry(ep, AssetTypeAudio)
+	if entry.CompletedAt.IsZero() || !reflect.DeepEqual(entry.Inputs, inputs) {
+		t.Errorf("got completed at %s with inputs %v after reset, want the completion kept",
+			entry.CompletedAt, entry.Inputs)
+	}
+
+	// Entries are copies, so changing one doesn't change the ledger.
+	entry.Status = AssetStatusComplete
+	if got := l.Entry(ep, AssetTypeAudio).Status; got != AssetStatusPending {
+		t.Errorf("changing a copy of the entry changed the status to %s", got)
+	}
+}
+
+func TestLedgerReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whodunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ledger.json")
+	first, err := OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second ledger stands in for another process with the same file
+	// open, like the callback server.
+	second, err := OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	knot := &Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone"}
+	coldCase := &Episode{Series: SeriesForensicFilesII, SeasonNumber: 1, EpisodeNumber: 1, Title: "the-cold-case"}
+
+	if err := first.Complete(knot, AssetTypeVideo, "videohash", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Begin(coldCase, AssetTypeRecognition); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Begin(knot, AssetTypeAudio); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantKeys := []string{
+		"audio/03-02-knot-for-everyone",
+		"video/03-02-knot-for-everyone",
+		"recognition/forensic-files-ii-01-01-the-cold-case",
+	}
+	for _, l := range []*Ledger{first, reopened} {
+		keys := make([]string, 0)
+		for _, entry := range l.Entries() {
+			keys = append(keys, entry.key())
+		}
+
+		if !reflect.DeepEqual(keys, wantKeys) {
+			t.Errorf("got entries %q, want %q", keys, wantKeys)
+		}
+	}
+
+	if entry := reopened.Entry(coldCase, AssetTypeRecognition); entry == nil ||
+		entry.Series != SeriesForensicFilesII || entry.Status != AssetStatusInProcess {
+		t.Errorf("got reloaded entry %+v", entry)
+	}
+
+	renamed := &Episode{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-anyone"}
+	if err := reopened.Rename(knot.Name(), renamed); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.Begin(renamed, AssetTypeTranscript); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Entry(knot, AssetTypeVideo) != nil {
+		t.Error("got an entry under the old name after renaming")
+	}
+	if entry := first.Entry(renamed, AssetTypeVideo); entry == nil || entry.Hash != "videohash" {
+		t.Errorf("got entry %+v under the new name, want the renamed entry", entry)
+	}
+}
+
+func TestOpenLedgerInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "whodunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ledger.json")
+	if err := ioutil.WriteFile(path, []byte(`{"entries": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenLedger(path); err == nil || !strings.Contains(err.Error(), "error parsing ledger") {
+		t.Errorf("got error %v, want a parsing error", err)
+	}
+}
@@ -0,0 +1,288 @@
+package whodunit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"github.com/mikerourke/forensic-files-api/internal/sharperimage"
+)
+
+// ManifestEntry contains the integrity details of a single asset file, so
+// the file can be checked for corruption later.
+type ManifestEntry struct {
+	Name       string     `json:"name"`
+	AssetType  AssetType  `json:"assetType"`
+	FileName   string     `json:"fileName"`
+	Size       int64      `json:"size"`
+	SHA256     string     `json:"sha256"`
+	Duration   float64    `json:"duration,omitempty"`
+	RecordedAt time.Time  `json:"recordedAt"`
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+}
+
+// Manifest contains the manifest entries for every asset in a season. Each
+// season has its own manifest file in the `/manifests` directory of the
+// investigations directory.
+type Manifest struct {
+	Series       Series
+	SeasonNumber int
+	path         string
+	entries      map[string]*ManifestEntry
+}
+
+// manifestFile is the layout of a manifest JSON file.
+type manifestFile struct {
+	Series  Series           `json:"series,omitempty"`
+	Season  int              `json:"season"`
+	Entries []*ManifestEntry `json:"entries"`
+}
+
+// manifestMutex prevents concurrent updates to the manifest files from
+// clobbering each other. Updates from other processes are kept out by a lock
+// on a `.lock` file next to the manifest file.
+var manifestMutex sync.Mutex
+
+// Manifest returns the manifest for the season.
+func (s *Season) Manifest() (*Manifest, error) {
+	manifestMutex.Lock()
+	defer manifestMutex.Unlock()
+
+	return s.loadManifest()
+}
+
+// ManifestPath returns the absolute path to the manifest file for the season.
+func (s *Season) ManifestPath() (string, error) {
+	ws, err := s.Workspace()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(ws.ManifestsDirPath(), s.DirName()+".json"), nil
+}
+
+func (s *Season) loadManifest() (*Manifest, error) {
+	path, err := s.ManifestPath()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Series:       s.Series,
+		SeasonNumber: s.SeasonNumber,
+		path:         path,
+		entries:      make(map[string]*ManifestEntry),
+	}
+
+	contents, err := ioutil.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+
+	var mf manifestFile
+	if err := json.Unmarshal(contents, &mf); err != nil {
+		return nil, fmt.Errorf("error parsing manifest %s: %w", m.path, err)
+	}
+
+	for _, entry := range mf.Entries {
+		m.entries[entry.key()] = entry
+	}
+
+	return m, nil
+}
+
+// updateManifest reloads the manifest for the season, calls the specified
+// function to change it, and writes the results back to the manifest file.
+func (s *Season) updateManifest(change func(m *Manifest)) error {
+	manifestMutex.Lock()
+	defer manifestMutex.Unlock()
+
+	path, err := s.ManifestPath()
+	if err != nil {
+		return err
+	}
+
+	fl, err := crimeseen.LockFile(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("error locking manifest for %s: %w", s.DirName(), err)
+	}
+	defer fl.Unlock()
+
+	m, err := s.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	change(m)
+
+	mf := &manifestFile{
+		Series:  m.Series,
+		Season:  m.SeasonNumber,
+		Entries: m.Entries(),
+	}
+	return crimeseen.WriteJSONFile(m.path, mf)
+}
+
+// Entry returns the manifest entry for the specified episode and asset type
+// or nil if the asset isn't in the manifest.
+func (m *Manifest) Entry(ep *Episode, assetType AssetType) *ManifestEntry {
+	return m.entries[manifestKey(ep.Name(), assetType)]
+}
+
+// Entries returns every entry in the manifest sorted by episode name and
+// asset type.
+func (m *Manifest) Entries() []*ManifestEntry {
+	entries := make([]*ManifestEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].AssetType < entries[j].AssetType
+	})
+
+	return entries
+}
+
+// Matches returns true if the size and hash of the specified entry are the
+// same as this entry.
+func (entry *ManifestEntry) Matches(other *ManifestEntry) bool {
+	return entry.Size == other.Size && entry.SHA256 == other.SHA256
+}
+
+func (entry *ManifestEntry) key() string {
+	return manifestKey(entry.Name, entry.AssetType)
+}
+
+func manifestKey(name string, assetType AssetType) string {
+	return fmt.Sprintf("%s/%s", assetType, name)
+}
+
+// ManifestEntry returns the manifest entry for the asset associated with the
+// episode or nil if the asset isn't in the manifest.
+func (e *Episode) ManifestEntry(assetType AssetType) *ManifestEntry {
+	if e.season == nil {
+		return nil
+	}
+
+	m, err := e.season.Manifest()
+	if err != nil {
+		return nil
+	}
+
+	return m.Entry(e, assetType)
+}
+
+// Fingerprint returns a new manifest entry built from the current contents
+// of the asset file. Video and audio files are probed with ffprobe to get the
+// duration, which returns an error if the file can't be read. If ffprobe isn't
+// installed, the duration is left empty.
+func (e *Episode) Fingerprint(
+	ctx context.Context,
+	assetType AssetType,
+) (*ManifestEntry, error) {
+	info, err := e.StatAsset(assetType)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := e.assetHash(assetType)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &ManifestEntry{
+		Name:       e.Name(),
+		AssetType:  assetType,
+		FileName:   info.Name(),
+		Size:       info.Size,
+		SHA256:     hash,
+		RecordedAt: time.Now().UTC(),
+	}
+
+	if assetType.IsMedia() && sharperimage.IsInstalled() {
+		ws, err := e.Workspace()
+		if err != nil {
+			return nil, err
+		}
+
+		localPath, release, err := e.FetchAsset(assetType)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		probe, err := sharperimage.ProbeFile(ctx, localPath, ws.Config().FFmpeg.Timeout)
+		if err != nil && !errors.Is(err, sharperimage.ErrNotInstalled) {
+			return nil, err
+		}
+
+		if probe != nil {
+			entry.Duration = probe.Duration.Seconds()
+		}
+	}
+
+	return entry, nil
+}
+
+// RecordManifestEntry adds the specified entry to the manifest of the
+// episode's season, replacing the existing entry for the asset (if any).
+func (e *Episode) RecordManifestEntry(entry *ManifestEntry) error {
+	if e.season == nil {
+		return fmt.Errorf("episode %s: %w", e.Name(), ErrNoWorkspace)
+	}
+
+	return e.season.updateManifest(func(m *Manifest) {
+		m.entries[entry.key()] = entry
+	})
+}
+
+// RenameManifestEntries moves the entries recorded under the specified old name
+// of the episode (e.g. before its title was corrected) to the episode's current
+// name in the manifest of the episode's season. The file names of the entries
+// are updated to match.
+func (e *Episode) RenameManifestEntries(oldName string) error {
+	if e.season == nil {
+		return fmt.Errorf("episode %s: %w", e.Name(), ErrNoWorkspace)
+	}
+
+	return e.season.updateManifest(func(m *Manifest) {
+		for key, entry := range m.entries {
+			if entry.Name != oldName {
+				continue
+			}
+
+			delete(m.entries, key)
+			entry.Name = e.Name()
+			entry.FileName = e.Name() + strings.TrimPrefix(entry.FileName, oldName)
+			m.entries[entry.key()] = entry
+		}
+	})
+}
+
+// RemoveManifestEntry removes the entry for the asset associated with the
+// episode from the manifest of the episode's season.
+func (e *Episode) RemoveManifestEntry(assetType AssetType) error {
+	if e.season == nil {
+		return fmt.Errorf("episode %s: %w", e.Name(), ErrNoWorkspace)
+	}
+
+	return e.season.updateManifest(func(m *Manifest) {
+		delete(m.entries, manifestKey(e.Name(), assetType))
+	})
+}
@@ -0,0 +1,218 @@
+package whodunit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// newTestCatalog returns the test catalog attached to a workspace in a
+// temporary directory along with the function that removes the directory.
+func newTestCatalog(t *testing.T) (*Catalog, func()) {
+	root, err := ioutil.TempDir("", "whodunit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(root) }
+
+	if err := os.MkdirAll(filepath.Join(root, "assets"), os.ModePerm); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	config := crimeseen.NewConfig()
+	config.InvestigationsPath = filepath.Join(root, "investigations")
+
+	ws, err := NewWorkspace(root, config)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(ws.CatalogPath(), []byte(testCatalogJSON), 0644); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	c, err := ws.Catalog()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return c, cleanup
+}
+
+func TestManifest(t *testing.T) {
+	c, cleanup := newTestCatalog(t)
+	defer cleanup()
+
+	ep := c.EpisodeByName("01-02-the-magic-bullet")
+	if err := ep.WriteAsset(AssetTypeTranscript, []byte("The magic bullet.")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ep.CompleteAsset(AssetTypeTranscript); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256([]byte("The magic bullet."))
+	want := &ManifestEntry{
+		Name:      "01-02-the-magic-bullet",
+		AssetType: AssetTypeTranscript,
+		FileName:  "01-02-the-magic-bullet.txt",
+		Size:      17,
+		SHA256:    hex.EncodeToString(hash[:]),
+	}
+
+	entry := ep.ManifestEntry(AssetTypeTranscript)
+	if entry == nil || entry.Name != want.Name || entry.FileName != want.FileName ||
+		!entry.Matches(want) || entry.RecordedAt.IsZero() {
+		t.Fatalf("got manifest entry %+v, want %+v", entry, want)
+	}
+
+	if ledgerEntry := ep.LedgerEntry(AssetTypeTranscript); ledgerEntry == nil || ledgerEntry.Hash != want.SHA256 {
+		t.Errorf("got ledger entry %+v, want hash %s", ledgerEntry, want.SHA256)
+	}
+
+	manifestPath, err := ep.Season().ManifestPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crimeseen.FileExists(manifestPath) {
+		t.Errorf("manifest %s wasn't written", manifestPath)
+	}
+
+	// The file changed since it was recorded, so the fingerprint doesn't
+	// match anymore.
+	if err := ep.WriteAsset(AssetTypeTranscript, []byte("The magic bullet!")); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint, err := ep.Fingerprint(context.Background(), AssetTypeTranscript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint.Matches(entry) {
+		t.Error("got a matching fingerprint after the file changed")
+	}
+
+	// Entries recorded before the title was corrected are moved to the
+	// current name.
+	err = ep.RecordManifestEntry(&ManifestEntry{
+		Name:      "01-02-the-magik-bullet",
+		AssetType: AssetTypeRecognition,
+		FileName:  "01-02-the-magik-bullet.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ep.RenameManifestEntries("01-02-the-magik-bullet"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ep.Season().Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renamed := m.Entry(ep, AssetTypeRecognition); renamed == nil || renamed.FileName != "01-02-the-magic-bullet.json" {
+		t.Errorf("got renamed entry %+v", renamed)
+	}
+	if len(m.Entries()) != 2 {
+		t.Errorf("got %d manifest entries after renaming, want 2", len(m.Entries()))
+	}
+
+	if err := ep.RemoveManifestEntry(AssetTypeTranscript); err != nil {
+		t.Fatal(err)
+	}
+	if entry := ep.ManifestEntry(AssetTypeTranscript); entry != nil {
+		t.Errorf("got manifest entry %+v after removing it", entry)
+	}
+}
+
+func TestAssetStaleFromHashes(t *testing.T) {
+	c, cleanup := newTestCatalog(t)
+	defer cleanup()
+
+	ep := c.EpisodeByName("01-02-the-magic-bullet")
+	produce := func(assetType AssetType, contents string) {
+		if err := ep.WriteAsset(assetType, []byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+		if err := ep.CompleteAsset(assetType); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	produce(AssetTypeRecognition, `{"results": []}`)
+	produce(AssetTypeTranscript, "")
+
+	if got := ep.AssetStatus(AssetTypeTranscript); got != AssetStatusComplete {
+		t.Errorf("got transcript %s, want %s", got, AssetStatusComplete)
+	}
+
+	// Producing the same recognition again doesn't change its hash.
+	produce(AssetTypeRecognition, `{"results": []}`)
+	if ep.AssetStale(AssetTypeTranscript) {
+		t.Error("got a stale transcript after the recognition was produced again unchanged")
+	}
+
+	produce(AssetTypeRecognition, `{"results": [{}]}`)
+	if got := ep.AssetStatus(AssetTypeTranscript); got != AssetStatusStale {
+		t.Errorf("got transcript %s after the recognition changed, want %s", got, AssetStatusStale)
+	}
+
+	produce(AssetTypeTranscript, "")
+	if got := ep.AssetStatus(AssetTypeTranscript); got != AssetStatusComplete {
+		t.Errorf("got transcript %s after it was produced again, want %s", got, AssetStatusComplete)
+	}
+}
+
+func TestAssetStaleFromModTimes(t *testing.T) {
+	c, cleanup := newTestCatalog(t)
+	defer cleanup()
+
+	// Neither asset was produced by the pipeline, so the modification times
+	// of the files are compared.
+	ep := c.EpisodeByName("01-01-the-disappearance-of-helle-crafts")
+	for _, assetType := range []AssetType{AssetTypeRecognition, AssetTypeTranscript} {
+		if err := ep.WriteAsset(assetType, []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storage, err := c.Workspace().Storage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	touch := func(assetType AssetType, modTime time.Time) {
+		path := storage.(coldstorage.LocalStorage).LocalPath(ep.AssetKey(assetType))
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	touch(AssetTypeRecognition, now.Add(-time.Hour))
+	touch(AssetTypeTranscript, now)
+	if got := ep.AssetStatus(AssetTypeTranscript); got != AssetStatusComplete {
+		t.Errorf("got transcript %s, want %s", got, AssetStatusComplete)
+	}
+
+	touch(AssetTypeRecognition, now.Add(time.Hour))
+	if got := ep.AssetStatus(AssetTypeTranscript); got != AssetStatusStale {
+		t.Errorf("got transcript %s after the recognition was modified, want %s",
+			got, AssetStatusStale)
+	}
+}
@@ -0,0 +1,316 @@
+package whodunit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// MatrixFormat is the format the status matrix is written in.
+type MatrixFormat string
+
+const (
+	// MatrixFormatTable writes the matrix as a colored table for the terminal.
+	MatrixFormatTable MatrixFormat = "table"
+
+	// MatrixFormatJSON writes the matrix as a JSON array with an object for
+	// each episode.
+	MatrixFormatJSON MatrixFormat = "json"
+
+	// MatrixFormatCSV writes the matrix as CSV with a header row.
+	MatrixFormatCSV MatrixFormat = "csv"
+
+	// MatrixFormatMarkdown writes the matrix as a Markdown table.
+	MatrixFormatMarkdown MatrixFormat = "markdown"
+)
+
+// MatrixFormats returns the keys of every format the status matrix can be
+// written in.
+func MatrixFormats() []string {
+	return []string{
+		string(MatrixFormatTable),
+		string(MatrixFormatJSON),
+		string(MatrixFormatCSV),
+		string(MatrixFormatMarkdown),
+	}
+}
+
+// StatusMatrix contains the status of each asset type for each episode, so
+// the progress of the whole pipeline can be seen at once.
+type StatusMatrix struct {
+	AssetTypes []AssetType
+	Rows       []*StatusMatrixRow
+}
+
+// StatusMatrixRow is the status of each asset of a single episode.
+type StatusMatrixRow struct {
+	Episode  *Episode
+	Statuses map[AssetType]AssetStatus
+}
+
+// StatusCondition limits the rows of the status matrix to episodes where the
+// asset has (or doesn't have, if negated) one of the statuses.
+type StatusCondition struct {
+	AssetType AssetType
+	Statuses  []AssetStatus
+	Negated   bool
+}
+
+// matrixRecord is the JSON representation of a row in the status matrix.
+type matrixRecord struct {
+	Series   string                    `json:"series"`
+	Season   int                       `json:"season"`
+	Episode  int                       `json:"episode"`
+	Title    string                    `json:"title"`
+	Name     string                    `json:"name"`
+	Statuses map[AssetType]AssetStatus `json:"statuses"`
+}
+
+// NewStatusMatrix returns the status of the specified asset types for each
+// episode in the catalog that is in the specified selection. If no asset types
+// are specified, every asset type is included in the order they're produced.
+func NewStatusMatrix(
+	c *Catalog,
+	sel *Selection,
+	assetTypes []AssetType,
+) *StatusMatrix {
+	if len(assetTypes) == 0 {
+		assetTypes = AllAssetTypes()
+	}
+
+	m := &StatusMatrix{
+		AssetTypes: assetTypes,
+		Rows:       make([]*StatusMatrixRow, 0),
+	}
+
+	for _, ep := range c.Select(sel) {
+		row := &StatusMatrixRow{
+			Episode:  ep,
+			Statuses: make(map[AssetType]AssetStatus, len(assetTypes)),
+		}
+		for _, assetType := range assetTypes {
+			row.Statuses[assetType] = ep.AssetStatus(assetType)
+		}
+		m.Rows = append(m.Rows, row)
+	}
+
+	return m
+}
+
+// ParseStatusCondition returns the condition parsed from the specified value
+// in the form "asset=status" or "asset!=status", where multiple statuses are
+// separated by a pipe (e.g. "audio=complete" and
+// "recognition!=complete|in-process").
+func ParseStatusCondition(value string) (*StatusCondition, error) {
+	separator, negated := "=", false
+	if strings.Contains(value, "!=") {
+		separator, negated = "!=", true
+	}
+
+	parts := strings.SplitN(value, separator, 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid condition %q, expected asset=status", value)
+	}
+
+	assetType, err := ParseAssetType(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	condition := &StatusCondition{AssetType: assetType, Negated: negated}
+	for _, key := range strings.Split(parts[1], "|") {
+		var status AssetStatus
+		if err := status.UnmarshalText([]byte(strings.TrimSpace(key))); err != nil {
+			return nil, err
+		}
+		condition.Statuses = append(condition.Statuses, status)
+	}
+
+	return condition, nil
+}
+
+// Matches returns true if the status of the asset in the specified row meets
+// the condition. If the asset type isn't shown in the matrix, the status is
+// looked up from the episode, so the matrix can be filtered by any asset.
+func (sc *StatusCondition) Matches(row *StatusMatrixRow) bool {
+	status, ok := row.Statuses[sc.AssetType]
+	if !ok {
+		status = row.Episode.AssetStatus(sc.AssetType)
+	}
+
+	for _, expected := range sc.Statuses {
+		if expected == AssetStatusAny || status == expected {
+			return !sc.Negated
+		}
+	}
+
+	return sc.Negated
+}
+
+// Where returns a copy of the matrix that only contains the rows that meet
+// every one of the specified conditions (e.g. episodes with audio, but no
+// recognition).
+func (m *StatusMatrix) Where(conditions ...*StatusCondition) *StatusMatrix {
+	filtered := &StatusMatrix{
+		AssetTypes: m.AssetTypes,
+		Rows:       make([]*StatusMatrixRow, 0),
+	}
+
+	for _, row := range m.Rows {
+		matches := true
+		for _, condition := range conditions {
+			if !condition.Matches(row) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			filtered.Rows = append(filtered.Rows, row)
+		}
+	}
+
+	return filtered
+}
+
+// Write writes the matrix to the specified writer in the specified format.
+func (m *StatusMatrix) Write(w io.Writer, format MatrixFormat) error {
+	switch format {
+	case MatrixFormatTable:
+		m.writeTable(w)
+		return nil
+
+	case MatrixFormatJSON:
+		return m.writeJSON(w)
+
+	case MatrixFormatCSV:
+		return m.writeCSV(w)
+
+	case MatrixFormatMarkdown:
+		return m.writeMarkdown(w)
+	}
+
+	return fmt.Errorf("unknown matrix format %q", format)
+}
+
+func (m *StatusMatrix) writeTable(w io.Writer) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+
+	header := []string{"Season", "Episode", "Title"}
+	for _, assetType := range m.AssetTypes {
+		header = append(header, assetType.DisplayName())
+	}
+	table.SetHeader(header)
+
+	for _, row := range m.Rows {
+		cells := []string{
+			seasonDisplay(row.Episode),
+			strconv.Itoa(row.Episode.EpisodeNumber),
+			row.Episode.DisplayTitle(),
+		}
+		colors := []tablewriter.Colors{{}, {}, {}}
+		for _, assetType := range m.AssetTypes {
+			status := row.Statuses[assetType]
+			cells = append(cells, status.DisplayName())
+			colors = append(colors, statusColors(status))
+		}
+		table.Rich(cells, colors)
+	}
+
+	footer := make([]string, len(header))
+	footer[1], footer[2] = "Total", strconv.Itoa(len(m.Rows))
+	table.SetFooter(footer)
+	table.Render()
+}
+
+func (m *StatusMatrix) writeJSON(w io.Writer) error {
+	records := make([]*matrixRecord, 0, len(m.Rows))
+	for _, row := range m.Rows {
+		records = append(records, &matrixRecord{
+			Series:   row.Episode.Series.String(),
+			Season:   row.Episode.SeasonNumber,
+			Episode:  row.Episode.EpisodeNumber,
+			Title:    row.Episode.Title,
+			Name:     row.Episode.Name(),
+			Statuses: row.Statuses,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func (m *StatusMatrix) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"series", "season", "episode", "title"}
+	for _, assetType := range m.AssetTypes {
+		header = append(header, assetType.String())
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range m.Rows {
+		record := []string{
+			row.Episode.Series.String(),
+			strconv.Itoa(row.Episode.SeasonNumber),
+			strconv.Itoa(row.Episode.EpisodeNumber),
+			row.Episode.Title,
+		}
+		for _, assetType := range m.AssetTypes {
+			record = append(record, row.Statuses[assetType].String())
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (m *StatusMatrix) writeMarkdown(w io.Writer) error {
+	header := []string{"Season", "Episode", "Title"}
+	divider := []string{"---:", "---:", "---"}
+	for _, assetType := range m.AssetTypes {
+		header = append(header, assetType.DisplayName())
+		divider = append(divider, "---")
+	}
+
+	lines := []string{markdownRow(header), markdownRow(divider)}
+	for _, row := range m.Rows {
+		cells := []string{
+			seasonDisplay(row.Episode),
+			strconv.Itoa(row.Episode.EpisodeNumber),
+			row.Episode.DisplayTitle(),
+		}
+		for _, assetType := range m.AssetTypes {
+			cells = append(cells, row.Statuses[assetType].DisplayName())
+		}
+		lines = append(lines, markdownRow(cells))
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// markdownRow returns the specified cells as a row in a Markdown table with
+// any pipes in the cells escaped.
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = strings.ReplaceAll(cell, "|", `\|`)
+	}
+
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
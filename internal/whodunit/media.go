@@ -0,0 +1,113 @@
+package whodunit
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mikerourke/forensic-files-api/internal/sharperimage"
+)
+
+// MediaInfo contains the details ffprobe reported for a video or audio asset.
+// It's stored in the catalog, so the runtime and format of an episode are
+// known without probing the file again.
+type MediaInfo struct {
+	// Path is the actual path to the file in the asset storage, which may
+	// have a different extension than the asset type (e.g. `.mkv` videos).
+	Path       string    `json:"path"`
+	Container  string    `json:"container"`
+	Duration   float64   `json:"duration"`
+	VideoCodec string    `json:"videoCodec,omitempty"`
+	AudioCodec string    `json:"audioCodec,omitempty"`
+	SampleRate int       `json:"sampleRate,omitempty"`
+	BitRate    int64     `json:"bitRate,omitempty"`
+	Size       int64     `json:"size"`
+	ProbedAt   time.Time `json:"probedAt"`
+}
+
+// Length returns the duration of the media as a time.Duration.
+func (mi *MediaInfo) Length() time.Duration {
+	return time.Duration(mi.Duration * float64(time.Second))
+}
+
+// MediaInfo returns the media details recorded in the catalog for the asset
+// or nil if the asset hasn't been probed.
+func (e *Episode) MediaInfo(assetType AssetType) *MediaInfo {
+	if e.Media == nil {
+		return nil
+	}
+
+	return e.Media[assetType]
+}
+
+// SetMediaInfo records the media details for the asset. The catalog needs to
+// be saved to persist them.
+func (e *Episode) SetMediaInfo(assetType AssetType, info *MediaInfo) {
+	if e.Media == nil {
+		e.Media = make(map[AssetType]*MediaInfo)
+	}
+
+	e.Media[assetType] = info
+}
+
+// ProbeMedia runs ffprobe on the asset file and returns the media details.
+func (e *Episode) ProbeMedia(
+	ctx context.Context,
+	assetType AssetType,
+) (*MediaInfo, error) {
+	ws, err := e.Workspace()
+	if err != nil {
+		return nil, err
+	}
+
+	key := e.AssetKey(assetType)
+	localPath, release, err := e.FetchAsset(assetType)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	probe, err := sharperimage.ProbeFile(ctx, localPath, ws.Config().FFmpeg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MediaInfo{
+		Path:       key,
+		Container:  probe.FormatName,
+		Duration:   probe.Duration.Seconds(),
+		VideoCodec: probe.VideoCodec,
+		AudioCodec: probe.AudioCodec,
+		SampleRate: probe.SampleRate,
+		BitRate:    probe.BitRate,
+		Size:       probe.Size,
+		ProbedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// findMediaAsset returns the path to the media file for the asset in the asset
+// storage regardless of its extension (e.g. youtube-dl sometimes writes `.mkv`
+// or `.webm` files) or an empty string if there isn't one. Partial downloads
+// and quarantined files (e.g. `.mp4.part`) are ignored.
+func (e *Episode) findMediaAsset(assetType AssetType) string {
+	prefix := path.Join(assetType.DirName(), e.season.DirName(), e.Name()) + "."
+	s, err := e.storage()
+	if err != nil {
+		return ""
+	}
+
+	files, err := s.List(prefix)
+	if err != nil {
+		return ""
+	}
+
+	for _, file := range files {
+		ext := strings.TrimPrefix(file.Path, prefix)
+		if ext != "" && !strings.Contains(ext, ".") {
+			return file.Path
+		}
+	}
+
+	return ""
+}
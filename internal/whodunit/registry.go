@@ -0,0 +1,284 @@
+package whodunit
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AssetTypeSpec describes where an asset type is stored and how it fits into
+// the pipeline. Packages that produce a new kind of asset register a spec with
+// RegisterAssetType, so the status table, selections, and CLI flags pick it up
+// without any changes.
+type AssetTypeSpec struct {
+	// Key is the unique key used in CLI flags, the ledger, and the manifests
+	// (e.g. "recognition").
+	Key string
+
+	// Aliases are alternate keys accepted when parsing the asset type (e.g.
+	// "recog").
+	Aliases []string
+
+	// DisplayName is the name shown in the terminal (e.g. "Recognition").
+	DisplayName string
+
+	// DirName is the name of the directory that contains the asset files in
+	// the investigations directory and the asset storage.
+	DirName string
+
+	// FileExt is the extension of the asset files, including the dot.
+	FileExt string
+
+	// Inputs are the upstream asset types the asset is produced from.
+	Inputs []AssetType
+
+	// IsMedia indicates that the asset files are video or audio files that
+	// can be probed with ffprobe.
+	IsMedia bool
+
+	// ResolveStatus returns the status of the asset for an episode. If nil,
+	// DefaultAssetStatus is used.
+	ResolveStatus StatusResolver
+}
+
+// StatusResolver returns the status of the asset associated with the
+// specified episode and asset type.
+type StatusResolver func(ep *Episode, assetType AssetType) AssetStatus
+
+var registry = struct {
+	sync.RWMutex
+	specs map[AssetType]*AssetTypeSpec
+	order []AssetType
+}{
+	specs: make(map[AssetType]*AssetTypeSpec),
+}
+
+// The built-in asset types are registered in the order they're produced in
+// the pipeline.
+func init() {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registerAssetType(AssetTypeVideo, AssetTypeSpec{
+		Key:         "video",
+		DisplayName: "Video",
+		DirName:     "videos",
+		FileExt:     ".mp4",
+		IsMedia:     true,
+	})
+
+	registerAssetType(AssetTypeAudio, AssetTypeSpec{
+		Key:         "audio",
+		DisplayName: "Audio",
+		DirName:     "audio",
+		FileExt:     ".mp3",
+		Inputs:      []AssetType{AssetTypeVideo},
+		IsMedia:     true,
+	})
+
+	registerAssetType(AssetTypeRecognition, AssetTypeSpec{
+		Key:         "recognition",
+		Aliases:     []string{"recog"},
+		DisplayName: "Recognition",
+		DirName:     "recognitions",
+		FileExt:     ".json",
+		Inputs:      []AssetType{AssetTypeAudio},
+	})
+
+	registerAssetType(AssetTypeTranscript, AssetTypeSpec{
+		Key:         "transcript",
+		Aliases:     []string{"trans"},
+		DisplayName: "Transcript",
+		DirName:     "transcripts",
+		FileExt:     ".txt",
+		Inputs:      []AssetType{AssetTypeRecognition},
+	})
+
+	registerAssetType(AssetTypeGCPAnalysis, AssetTypeSpec{
+		Key:         "gcp-analysis",
+		DisplayName: "GCP Analysis",
+		DirName:     "gcp-analyses",
+		FileExt:     ".json",
+		Inputs:      []AssetType{AssetTypeTranscript},
+	})
+
+	registerAssetType(AssetTypeIBMAnalysis, AssetTypeSpec{
+		Key:         "ibm-analysis",
+		DisplayName: "IBM Analysis",
+		DirName:     "ibm-analyses",
+		FileExt:     ".json",
+		Inputs:      []AssetType{AssetTypeTranscript},
+	})
+}
+
+// RegisterAssetType adds a new asset type to the registry and returns it. It
+// should be called when initializing the package that produces the asset,
+// e.g. `var AssetTypeSubtitles = whodunit.RegisterAssetType(...)`. It panics
+// if the spec is invalid or the key is already registered, since that's a
+// programming error.
+func RegisterAssetType(spec AssetTypeSpec) AssetType {
+	registry.Lock()
+	defer registry.Unlock()
+
+	// The lock is held until the asset type is added, so two packages
+	// registering at the same time can't get the same value.
+	next := AssetType(0)
+	for _, assetType := range registry.order {
+		if assetType >= next {
+			next = assetType + 1
+		}
+	}
+
+	registerAssetType(next, spec)
+	return next
+}
+
+// registerAssetType adds the spec to the registry as the specified asset type.
+// The registry must be locked by the caller.
+func registerAssetType(assetType AssetType, spec AssetTypeSpec) {
+	if spec.Key == "" || spec.DirName == "" || spec.FileExt == "" {
+		panic("whodunit: asset type key, directory, and extension are required")
+	}
+
+	for _, key := range append([]string{spec.Key}, spec.Aliases...) {
+		if existing, ok := lookupAssetType(key); ok {
+			panic(fmt.Sprintf("whodunit: asset type key %q already registered for %s",
+				key, registry.specs[existing].Key))
+		}
+	}
+
+	for _, input := range spec.Inputs {
+		if _, ok := registry.specs[input]; !ok {
+			panic(fmt.Sprintf("whodunit: input of asset type %q isn't registered", spec.Key))
+		}
+	}
+
+	if spec.DisplayName == "" {
+		spec.DisplayName = spec.Key
+	}
+
+	registry.specs[assetType] = &spec
+	registry.order = append(registry.order, assetType)
+}
+
+// lookupAssetType returns the asset type associated with the specified key or
+// alias. The registry must be locked by the caller.
+func lookupAssetType(key string) (AssetType, bool) {
+	for _, assetType := range registry.order {
+		spec := registry.specs[assetType]
+		if spec.Key == key {
+			return assetType, true
+		}
+
+		for _, alias := range spec.Aliases {
+			if alias == key {
+				return assetType, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// spec returns the registered spec for the asset type or an empty spec if the
+// asset type isn't registered.
+func (at AssetType) spec() *AssetTypeSpec {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	if spec, ok := registry.specs[at]; ok {
+		return spec
+	}
+
+	return &AssetTypeSpec{}
+}
+
+// AllAssetTypes returns every registered asset type in the order they were
+// registered, which is the order they're produced in the pipeline.
+func AllAssetTypes() []AssetType {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	assetTypes := make([]AssetType, len(registry.order))
+	copy(assetTypes, registry.order)
+	return assetTypes
+}
+
+// AssetTypeKeys returns the keys of every registered asset type. If
+// withAliases is true, the aliases are included after each key.
+func AssetTypeKeys(withAliases bool) []string {
+	keys := make([]string, 0)
+	for _, assetType := range AllAssetTypes() {
+		spec := assetType.spec()
+		keys = append(keys, spec.Key)
+		if withAliases {
+			keys = append(keys, spec.Aliases...)
+		}
+	}
+
+	return keys
+}
+
+// ParseAssetType returns the asset type associated with the specified key or
+// alias (e.g. "recognition" or "recog").
+func ParseAssetType(key string) (AssetType, error) {
+	var at AssetType
+	err := at.UnmarshalText([]byte(key))
+	return at, err
+}
+
+// DisplayName returns the name of the asset type shown in the terminal.
+func (at AssetType) DisplayName() string {
+	return at.spec().DisplayName
+}
+
+// DirName returns the name of the directory associated with the asset type,
+// which is the same in the investigations directory and the asset storage.
+func (at AssetType) DirName() string {
+	return at.spec().DirName
+}
+
+// Inputs returns the upstream asset types that are used to produce the asset
+// type (e.g. the audio is extracted from the video).
+func (at AssetType) Inputs() []AssetType {
+	inputs := at.spec().Inputs
+	if inputs == nil {
+		return []AssetType{}
+	}
+
+	return inputs
+}
+
+// IsMedia returns true if the asset type is a video or audio file.
+func (at AssetType) IsMedia() bool {
+	return at.spec().IsMedia
+}
+
+// FileExt returns the file extension associated with the asset type.
+func (at AssetType) FileExt() string {
+	return at.spec().FileExt
+}
+
+// String returns the key associated with the asset type (e.g. "recognition").
+func (at AssetType) String() string {
+	return at.spec().Key
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, so the asset
+// type is stored as a readable key in JSON files.
+func (at AssetType) MarshalText() ([]byte, error) {
+	return []byte(at.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (at *AssetType) UnmarshalText(text []byte) error {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	assetType, ok := lookupAssetType(string(text))
+	if !ok {
+		return fmt.Errorf("unknown asset type %q", text)
+	}
+
+	*at = assetType
+	return nil
+}
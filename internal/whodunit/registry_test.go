@@ -0,0 +1,57 @@
+package whodunit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRegisterAssetTypeConcurrently(t *testing.T) {
+	const count = 20
+
+	assetTypes := make([]AssetType, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assetTypes[i] = RegisterAssetType(AssetTypeSpec{
+				Key:     fmt.Sprintf("test-asset-%d", i),
+				DirName: fmt.Sprintf("test-assets-%d", i),
+				FileExt: ".txt",
+				Inputs:  []AssetType{AssetTypeTranscript},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	// The test asset types are removed, so they don't show up in other tests.
+	defer func() {
+		registry.Lock()
+		defer registry.Unlock()
+
+		for _, assetType := range assetTypes {
+			delete(registry.specs, assetType)
+		}
+
+		order := make([]AssetType, 0, len(registry.order))
+		for _, assetType := range registry.order {
+			if _, ok := registry.specs[assetType]; ok {
+				order = append(order, assetType)
+			}
+		}
+		registry.order = order
+	}()
+
+	seen := make(map[AssetType]bool)
+	for i, assetType := range assetTypes {
+		if seen[assetType] {
+			t.Errorf("asset type %d was returned more than once", assetType)
+		}
+		seen[assetType] = true
+
+		if want := fmt.Sprintf("test-asset-%d", i); assetType.String() != want {
+			t.Errorf("got asset type %s, want %s", assetType, want)
+		}
+	}
+}
@@ -1,60 +1,37 @@
 package whodunit
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
-	"sort"
-
-	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+	"path"
 )
 
 // Season represents a season directory in the assets directory along with
 // associated episodes.
 type Season struct {
+	Series       Series
 	SeasonNumber int
 	episodeMap   map[int]*Episode
+	catalog      *Catalog
 }
 
-// SeasonCount is the count of seasons in Forensic Files.
-const SeasonCount = 14
-
-// NewSeason returns a new instance of a Season with an empty episode map.
+// NewSeason returns a new instance of a Season of the default series with an
+// empty episode map.
 func NewSeason(seasonNumber int) *Season {
-	return &Season{
-		SeasonNumber: seasonNumber,
-		episodeMap:   make(map[int]*Episode, 0),
-	}
+	return NewSeriesSeason("", seasonNumber)
 }
 
-// PopulateEpisodes populates the season's episode map from the contents of the
-// episodes JSON file in the `/assets` directory.
-func (s *Season) PopulateEpisodes() error {
-	jsonFile, err := os.Open(filepath.Join(AssetsDirPath, "episodes.json"))
-	if err != nil {
-		return err
-	}
-
-	defer jsonFile.Close()
-	byteValue, err := ioutil.ReadAll(jsonFile)
-	if err != nil {
-		return err
-	}
-
-	var result map[string][]Episode
-	if err := json.Unmarshal(byteValue, &result); err != nil {
-		return err
+// NewSeriesSeason returns a new instance of a Season of the specified series
+// with an empty episode map.
+func NewSeriesSeason(series Series, seasonNumber int) *Season {
+	if series.IsDefault() {
+		series = ""
 	}
 
-	seasonName := crimeseen.PaddedNumberString(s.SeasonNumber)
-	for _, ep := range result[seasonName] {
-		s.episodeMap[ep.EpisodeNumber] = newEpisode(s,
-			ep.EpisodeNumber, ep.Title, ep.URL)
+	return &Season{
+		Series:       series,
+		SeasonNumber: seasonNumber,
+		episodeMap:   make(map[int]*Episode, 0),
 	}
-
-	return nil
 }
 
 // EpisodeCount returns the count of episodes in the season.
@@ -69,10 +46,7 @@ func (s *Season) AllEpisodes() []*Episode {
 		episodes = append(episodes, ep)
 	}
 
-	// Sort by episode number.
-	sort.Slice(episodes, func(i, j int) bool {
-		return episodes[i].EpisodeNumber < episodes[j].EpisodeNumber
-	})
+	sortEpisodes(episodes)
 
 	return episodes
 }
@@ -83,19 +57,26 @@ func (s *Season) Episode(episodeNumber int) *Episode {
 	return s.episodeMap[episodeNumber]
 }
 
-// EnsureDir ensures that the season directory exists in the assets sub-directory
-// associated with the specified asset type.
-func (s *Season) EnsureDir(assetType AssetType) error {
-	return crimeseen.Mkdirp(s.AssetDirPath(assetType))
-}
+// Workspace returns the workspace of the catalog the season belongs to. It
+// returns ErrNoWorkspace if the season doesn't belong to a catalog that was
+// attached to a workspace.
+func (s *Season) Workspace() (*Workspace, error) {
+	if s.catalog == nil || s.catalog.workspace == nil {
+		return nil, fmt.Errorf("season %s: %w", s.DirName(), ErrNoWorkspace)
+	}
 
-// AssetDirPath returns the absolute path to the season directory for the
-// associated asset.
-func (s *Season) AssetDirPath(assetType AssetType) string {
-	return filepath.Join(assetType.DirPath(), s.DirName())
+	return s.catalog.workspace, nil
 }
 
-// DirName returns the name of the directory for the associated season number.
+// DirName returns the path of the directory for the associated season number
+// relative to the asset type directory. Seasons of a series other than the
+// default are nested in a directory named after the series (e.g.
+// "forensic-files-ii/season-1"), so the original asset paths don't change.
 func (s *Season) DirName() string {
-	return fmt.Sprintf("season-%d", s.SeasonNumber)
+	dirName := fmt.Sprintf("season-%d", s.SeasonNumber)
+	if s.Series.IsDefault() {
+		return dirName
+	}
+
+	return path.Join(string(s.Series), dirName)
 }
@@ -0,0 +1,355 @@
+package whodunit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Selection represents the set of episodes to process. It is parsed from an
+// expression made up of comma-separated terms, where each term is a season
+// (`s3`), an episode (`s3e2`), or a range of either (`s3e2-s4e10`, `s9-s11`).
+// Terms prefixed with `!` are excluded from the selection, so `!s14` selects
+// every episode except the ones in season 14.
+//
+// Terms apply to the default series unless they're prefixed with the key of
+// a series and a colon (e.g. `forensic-files-ii:s1e2`). A series key followed
+// by a colon and nothing else (`forensic-files-ii:`) selects every episode in
+// the series.
+type Selection struct {
+	expr            string
+	series          Series
+	includes        []episodeRange
+	excludes        []episodeRange
+	filterAssetType AssetType
+	filterStatus    AssetStatus
+}
+
+// episodeKey is a season and episode number pair used for comparing
+// positions within the catalog.
+type episodeKey struct {
+	season  int
+	episode int
+}
+
+// episodeRange is an inclusive range of episodes in a series. If the series
+// is empty, the range applies to the series of the selection.
+type episodeRange struct {
+	series Series
+	from   episodeKey
+	to     episodeKey
+}
+
+// lastEpisode is the episode number used to indicate the end of a season.
+const lastEpisode = math.MaxInt32
+
+// AllEpisodes returns a selection that contains every episode.
+func AllEpisodes() *Selection {
+	return &Selection{}
+}
+
+// NewSelection returns a selection for the specified season and episode
+// number. If the season number is 0, the selection contains every episode.
+// If the episode number is 0, the selection contains every episode in the
+// season.
+func NewSelection(seasonNumber int, episodeNumber int) (*Selection, error) {
+	if seasonNumber == 0 {
+		// How do we know which season to process if it isn't specified?
+		if episodeNumber != 0 {
+			return nil, errors.New("you must specify a season number for an episode")
+		}
+		return AllEpisodes(), nil
+	}
+
+	expr := fmt.Sprintf("s%d", seasonNumber)
+	if episodeNumber != 0 {
+		expr = fmt.Sprintf("s%de%d", seasonNumber, episodeNumber)
+	}
+
+	return ParseSelection(expr)
+}
+
+// EpisodeSelection returns a selection that only contains the specified
+// episode.
+func EpisodeSelection(ep *Episode) (*Selection, error) {
+	expr := fmt.Sprintf("s%de%d", ep.SeasonNumber, ep.EpisodeNumber)
+	if !ep.Series.IsDefault() {
+		expr = fmt.Sprintf("%s:%s", ep.Series, expr)
+	}
+
+	return ParseSelection(expr)
+}
+
+// ParseSelection returns a selection parsed from the specified expression
+// (e.g. "s1,s5e7,s9-s11,!s10e3,forensic-files-ii:s1"). An empty expression
+// selects every episode of every series.
+func ParseSelection(expr string) (*Selection, error) {
+	sel := &Selection{expr: strings.TrimSpace(expr)}
+	if sel.expr == "" {
+		return sel, nil
+	}
+
+	for _, term := range strings.Split(sel.expr, ",") {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term == "" {
+			return nil, fmt.Errorf("invalid selection %q: empty term", expr)
+		}
+
+		isExcluded := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+
+		r, err := parseEpisodeRange(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q: %v", expr, err)
+		}
+
+		if isExcluded {
+			sel.excludes = append(sel.excludes, r)
+		} else {
+			sel.includes = append(sel.includes, r)
+		}
+	}
+
+	return sel, nil
+}
+
+// WithSeries returns a copy of the selection that only contains episodes of
+// the specified series. Terms that don't specify a series apply to the
+// specified series instead of the default series.
+func (s *Selection) WithSeries(series Series) *Selection {
+	filtered := *s
+	filtered.series = series.Key()
+	return &filtered
+}
+
+// WithStatus returns a copy of the selection that only contains episodes
+// where the asset associated with the specified asset type has the specified
+// status (e.g. only episodes with a stale transcript).
+func (s *Selection) WithStatus(
+	assetType AssetType,
+	status AssetStatus,
+) *Selection {
+	filtered := *s
+	filtered.filterAssetType = assetType
+	filtered.filterStatus = status
+	return &filtered
+}
+
+// Contains returns true if the specified episode is in the selection.
+func (s *Selection) Contains(ep *Episode) bool {
+	if !s.inRange(ep) {
+		return false
+	}
+
+	if s.filterStatus == AssetStatusAny {
+		return true
+	}
+
+	return ep.AssetStatus(s.filterAssetType) == s.filterStatus
+}
+
+func (s *Selection) inRange(ep *Episode) bool {
+	if s.series != "" && ep.Series.Key() != s.series {
+		return false
+	}
+
+	key := episodeKey{ep.SeasonNumber, ep.EpisodeNumber}
+
+	for _, r := range s.excludes {
+		if s.rangeSeries(r) == ep.Series.Key() && r.contains(key) {
+			return false
+		}
+	}
+
+	// If only exclusions were specified, everything else is included.
+	if len(s.includes) == 0 {
+		return true
+	}
+
+	for _, r := range s.includes {
+		if s.rangeSeries(r) == ep.Series.Key() && r.contains(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rangeSeries returns the series the specified range applies to.
+func (s *Selection) rangeSeries(r episodeRange) Series {
+	if r.series != "" {
+		return r.series
+	}
+
+	return s.series.Key()
+}
+
+// IsEpisode returns true if the selection is made up of a single episode.
+func (s *Selection) IsEpisode() bool {
+	return len(s.includes) == 1 &&
+		len(s.excludes) == 0 &&
+		s.includes[0].from == s.includes[0].to
+}
+
+// String returns the expression the selection was parsed from along with
+// the status filter (if any).
+func (s *Selection) String() string {
+	expr := s.expr
+	if expr == "" {
+		expr = "all"
+	}
+
+	if s.series != "" {
+		expr = fmt.Sprintf("%s:%s", s.series, expr)
+	}
+
+	if s.filterStatus != AssetStatusAny {
+		expr = fmt.Sprintf("%s (%s %s)", expr, s.filterStatus, s.filterAssetType)
+	}
+
+	return expr
+}
+
+// Select returns the episodes in the catalog that are in the specified
+// selection sorted by series, season, and episode number.
+func (c *Catalog) Select(sel *Selection) []*Episode {
+	episodes := make([]*Episode, 0)
+	for _, ep := range c.episodes {
+		if sel.Contains(ep) {
+			episodes = append(episodes, ep)
+		}
+	}
+
+	return episodes
+}
+
+func (r episodeRange) contains(key episodeKey) bool {
+	return !key.before(r.from) && !r.to.before(key)
+}
+
+func (k episodeKey) before(other episodeKey) bool {
+	if k.season != other.season {
+		return k.season < other.season
+	}
+	return k.episode < other.episode
+}
+
+// parseEpisodeRange parses a single term of a selection expression, which
+// can be prefixed with a series (e.g. "forensic-files-ii:s1"). The end of a
+// range can omit the season if it's the same as the start (e.g. "s3e2-e10").
+func parseEpisodeRange(term string) (episodeRange, error) {
+	var series Series
+	if index := strings.Index(term, ":"); index != -1 {
+		parsed, err := ParseSeries(term[:index])
+		if err != nil || index == 0 {
+			return episodeRange{}, fmt.Errorf("invalid series in %q", term)
+		}
+
+		series = parsed
+		term = term[index+1:]
+		if term == "" {
+			return episodeRange{
+				series: series,
+				from:   episodeKey{0, 0},
+				to:     episodeKey{math.MaxInt32, lastEpisode},
+			}, nil
+		}
+	}
+
+	r, err := parseSeasonRange(term)
+	r.series = series
+	return r, err
+}
+
+// parseSeasonRange parses a range of seasons or episodes within a series.
+func parseSeasonRange(term string) (episodeRange, error) {
+	bounds := strings.Split(term, "-")
+	if len(bounds) > 2 {
+		return episodeRange{}, fmt.Errorf("too many bounds in %q", term)
+	}
+
+	fromSeason, fromEpisode, err := parseEpisodePoint(bounds[0])
+	if err != nil {
+		return episodeRange{}, err
+	}
+
+	r := episodeRange{
+		from: episodeKey{fromSeason, fromEpisode},
+		to:   episodeKey{fromSeason, fromEpisode},
+	}
+	if fromEpisode == 0 {
+		r.to.episode = lastEpisode
+	}
+
+	if len(bounds) == 1 {
+		return r, nil
+	}
+
+	toBound := bounds[1]
+	if strings.HasPrefix(toBound, "e") {
+		if fromEpisode == 0 {
+			return episodeRange{}, fmt.Errorf(
+				"range %q needs an episode to start from", term)
+		}
+		toBound = fmt.Sprintf("s%d%s", fromSeason, toBound)
+	}
+
+	toSeason, toEpisode, err := parseEpisodePoint(toBound)
+	if err != nil {
+		return episodeRange{}, err
+	}
+
+	r.to = episodeKey{toSeason, toEpisode}
+	if toEpisode == 0 {
+		r.to.episode = lastEpisode
+	}
+
+	if r.to.before(r.from) {
+		return episodeRange{}, fmt.Errorf("range %q ends before it starts", term)
+	}
+
+	return r, nil
+}
+
+// parseEpisodePoint parses a value in the form "s3" or "s3e2" and returns the
+// season and episode numbers. The episode number is 0 if it was omitted.
+func parseEpisodePoint(value string) (int, int, error) {
+	if !strings.HasPrefix(value, "s") {
+		return 0, 0, fmt.Errorf("%q must start with a season (e.g. s3)", value)
+	}
+
+	values := strings.SplitN(strings.TrimPrefix(value, "s"), "e", 2)
+	seasonNumber, err := parsePointNumber(values[0])
+	if err != nil || seasonNumber < 1 {
+		return 0, 0, fmt.Errorf("invalid season in %q", value)
+	}
+
+	if len(values) == 1 {
+		return seasonNumber, 0, nil
+	}
+
+	episodeNumber, err := parsePointNumber(values[1])
+	if err != nil || episodeNumber < 1 {
+		return 0, 0, fmt.Errorf("invalid episode in %q", value)
+	}
+
+	return seasonNumber, episodeNumber, nil
+}
+
+// parsePointNumber parses a season or episode number. Unlike strconv.Atoi, it
+// only accepts digits, so values like "s+3" aren't valid.
+func parsePointNumber(value string) (int, error) {
+	if value == "" {
+		return 0, errors.New("number is required")
+	}
+
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid number %q", value)
+		}
+	}
+
+	return strconv.Atoi(value)
+}
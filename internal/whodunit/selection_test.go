@@ -0,0 +1,122 @@
+package whodunit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	names := []string{
+		"01-01-the-disappearance-of-helle-crafts",
+		"01-02-the-magic-bullet",
+		"02-01-southern-rapist",
+		"03-02-knot-for-everyone",
+		"03-05-bitter-pills",
+		"04-01-the-list",
+		"forensic-files-ii-01-01-the-cold-case",
+		"forensic-files-ii-01-02-a-3-4-b",
+	}
+
+	episodes := make([]*Episode, 0, len(names))
+	for _, name := range names {
+		ep, err := NewEpisodeFromName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		episodes = append(episodes, ep)
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", names},
+		{"s1", names[0:2]},
+		{"S1E2", names[1:2]},
+		{"s1e2,s4", []string{names[1], names[5]}},
+		{"s1e2-s3e2", names[1:4]},
+		{"s3e2-e5", names[3:5]},
+		{"s2-s3", names[2:5]},
+		{" s1 , s2 ", names[0:3]},
+		{"!s3", []string{names[0], names[1], names[2], names[5], names[6], names[7]}},
+		{"s1-s4,!s3e5", []string{names[0], names[1], names[2], names[3], names[5]}},
+		{"forensic-files-ii:", names[6:8]},
+		{"forensic-files-ii:s1e2", names[7:8]},
+		{"forensic-files-ii:s1,s1e1", []string{names[0], names[6], names[7]}},
+		{"forensic-files:s1e1", names[0:1]},
+	}
+
+	for _, test := range tests {
+		sel, err := ParseSelection(test.expr)
+		if err != nil {
+			t.Errorf("ParseSelection(%q) returned error: %s", test.expr, err)
+			continue
+		}
+
+		got := make([]string, 0)
+		for _, ep := range episodes {
+			if sel.Contains(ep) {
+				got = append(got, ep.Name())
+			}
+		}
+
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("ParseSelection(%q) selected %q, want %q", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseSelectionErrors(t *testing.T) {
+	tests := []string{
+		"1",
+		"s",
+		"s0",
+		"s1e0",
+		"s+3",
+		"s-3",
+		"s3e+2",
+		"s 3",
+		"s3e",
+		"s3x",
+		"s1,,s2",
+		"s3-s2",
+		"s3e5-e2",
+		"s3-e2",
+		"s1-s2-s3",
+		":s1",
+		"Forensic Files:s1",
+	}
+
+	for _, expr := range tests {
+		if sel, err := ParseSelection(expr); err == nil {
+			t.Errorf("ParseSelection(%q) = %s, want error", expr, sel)
+		}
+	}
+}
+
+func TestSelectionIsEpisode(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"s3e2", true},
+		{"forensic-files-ii:s1e2", true},
+		{"s3e2-e2", true},
+		{"s3", false},
+		{"s3e2-e3", false},
+		{"s3e2,s3e3", false},
+		{"s3e2,!s3e3", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		sel, err := ParseSelection(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := sel.IsEpisode(); got != test.want {
+			t.Errorf("ParseSelection(%q).IsEpisode() = %t, want %t", test.expr, got, test.want)
+		}
+	}
+}
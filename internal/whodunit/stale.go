@@ -0,0 +1,61 @@
+package whodunit
+
+import "time"
+
+// isStale returns true if any of the upstream assets the asset was produced
+// from changed since. If the hashes of the upstream assets were recorded in
+// the ledger when the asset was produced, they're compared to the current
+// hashes. Otherwise, the asset is stale if an upstream file was modified
+// after the asset file.
+func (e *Episode) isStale(assetType AssetType) bool {
+	entry := e.LedgerEntry(assetType)
+	for _, input := range assetType.Inputs() {
+		if !e.AssetExists(input) {
+			continue
+		}
+
+		inputEntry := e.LedgerEntry(input)
+		if entry != nil && entry.Inputs[input] != "" &&
+			inputEntry != nil && inputEntry.Hash != "" {
+			if entry.Inputs[input] != inputEntry.Hash {
+				return true
+			}
+			continue
+		}
+
+		if e.assetModTime(input).After(e.assetModTime(assetType)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inputHashes returns the current hashes of the upstream assets used to
+// produce the asset. The hash is taken from the ledger if the upstream asset
+// was produced by the pipeline, otherwise the file is hashed.
+func (e *Episode) inputHashes(assetType AssetType) map[AssetType]string {
+	hashes := make(map[AssetType]string)
+	for _, input := range assetType.Inputs() {
+		if inputEntry := e.LedgerEntry(input); inputEntry != nil && inputEntry.Hash != "" {
+			hashes[input] = inputEntry.Hash
+			continue
+		}
+
+		hash, err := e.assetHash(input)
+		if err == nil {
+			hashes[input] = hash
+		}
+	}
+
+	return hashes
+}
+
+func (e *Episode) assetModTime(assetType AssetType) time.Time {
+	info, err := e.StatAsset(assetType)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime
+}
@@ -0,0 +1,201 @@
+package whodunit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// AssetKey returns the path to the asset file for the episode in the asset
+// storage. Video and audio files don't always have the extension of the asset
+// type, so the path recorded when the asset was probed is used if the file
+// still exists, otherwise the storage is searched for the file.
+func (e *Episode) AssetKey(assetType AssetType) string {
+	key := e.assetKey(assetType)
+	if !assetType.IsMedia() {
+		return key
+	}
+
+	// If the storage can't be configured, the error is returned as soon as
+	// the file is accessed.
+	s, err := e.storage()
+	if err != nil {
+		return key
+	}
+
+	if info := e.MediaInfo(assetType); info != nil && info.Path != key {
+		if coldstorage.Exists(s, info.Path) {
+			return info.Path
+		}
+	}
+
+	if coldstorage.Exists(s, key) {
+		return key
+	}
+
+	if found := e.findMediaAsset(assetType); found != "" {
+		return found
+	}
+
+	return key
+}
+
+// storage returns the asset storage of the workspace the episode belongs to.
+func (e *Episode) storage() (coldstorage.Storage, error) {
+	ws, err := e.Workspace()
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.Storage()
+}
+
+// assetKey returns the path the asset file is written to in the asset storage.
+func (e *Episode) assetKey(assetType AssetType) string {
+	return path.Join(assetType.DirName(), e.season.DirName(),
+		e.AssetFileName(assetType))
+}
+
+// AssetExists returns true if the file associated with the specified asset
+// type exists in the asset storage. It returns false if the storage can't be
+// configured.
+func (e *Episode) AssetExists(assetType AssetType) bool {
+	s, err := e.storage()
+	if err != nil {
+		return false
+	}
+
+	return coldstorage.Exists(s, e.AssetKey(assetType))
+}
+
+// StatAsset returns the details of the asset file in the asset storage.
+func (e *Episode) StatAsset(assetType AssetType) (*coldstorage.FileInfo, error) {
+	s, err := e.storage()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Stat(e.AssetKey(assetType))
+}
+
+// OpenAsset opens the asset file for reading.
+func (e *Episode) OpenAsset(assetType AssetType) (io.ReadCloser, error) {
+	s, err := e.storage()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Open(e.AssetKey(assetType))
+}
+
+// assetHash returns the hex-encoded SHA-256 hash of the contents of the asset
+// file.
+func (e *Episode) assetHash(assetType AssetType) (string, error) {
+	r, err := e.OpenAsset(assetType)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ReadAsset returns the contents of the asset file.
+func (e *Episode) ReadAsset(assetType AssetType) ([]byte, error) {
+	s, err := e.storage()
+	if err != nil {
+		return nil, err
+	}
+
+	return coldstorage.ReadFile(s, e.AssetKey(assetType))
+}
+
+// WriteAsset writes the specified contents to the asset file.
+func (e *Episode) WriteAsset(assetType AssetType, contents []byte) error {
+	s, err := e.storage()
+	if err != nil {
+		return err
+	}
+
+	return coldstorage.WriteFile(s, e.assetKey(assetType), contents)
+}
+
+// WriteAssetJSON writes the specified contents as JSON to the asset file.
+func (e *Episode) WriteAssetJSON(assetType AssetType, contents interface{}) error {
+	b, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return e.WriteAsset(assetType, b)
+}
+
+// LockAsset blocks until no other process (or goroutine) holds the lock on
+// the asset and returns the function that releases it. The lock is advisory,
+// so it only keeps out writers that lock the asset too.
+func (e *Episode) LockAsset(assetType AssetType) (func(), error) {
+	ws, err := e.Workspace()
+	if err != nil {
+		return nil, err
+	}
+
+	lockPath := filepath.Join(ws.LocksDirPath(), assetType.DirName(),
+		e.season.DirName(), e.Name()+".lock")
+	fl, err := crimeseen.LockFile(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("error locking %s: %w", e.AssetFileName(assetType), err)
+	}
+
+	return func() { fl.Unlock() }, nil
+}
+
+// FetchAsset returns a path on the local filesystem that contains the asset
+// file, so it can be passed to external tools like ffmpeg. The release function
+// must be called once the file is no longer needed.
+func (e *Episode) FetchAsset(assetType AssetType) (string, func(), error) {
+	s, err := e.storage()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return coldstorage.Fetch(s, e.AssetKey(assetType))
+}
+
+// ProduceAsset calls the specified function with a path on the local
+// filesystem that an external tool (like youtube-dl) can write the asset file
+// to. The file is stored in the asset storage once the function returns.
+func (e *Episode) ProduceAsset(
+	assetType AssetType,
+	produce func(localPath string) error,
+) error {
+	s, err := e.storage()
+	if err != nil {
+		return err
+	}
+
+	return coldstorage.Produce(s, e.assetKey(assetType), produce)
+}
+
+// QuarantineAsset moves the asset file aside by appending the specified
+// suffix to its name, so it's no longer picked up as the asset.
+func (e *Episode) QuarantineAsset(assetType AssetType, suffix string) error {
+	s, err := e.storage()
+	if err != nil {
+		return err
+	}
+
+	key := e.AssetKey(assetType)
+	return coldstorage.Rename(s, key, key+suffix)
+}
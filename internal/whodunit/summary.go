@@ -0,0 +1,173 @@
+package whodunit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Verdict is the result of running a function for a single episode.
+type Verdict struct {
+	Episode  *Episode
+	Err      error
+	Duration time.Duration
+	Canceled bool
+}
+
+// Summary contains the verdicts for every episode processed by SolveContext,
+// in the same order as the catalog.
+type Summary struct {
+	Verdicts []*Verdict
+}
+
+// SolveContext runs the specified function for every episode in the catalog
+// that is in the specified selection using a pool of workers limited to the
+// specified concurrency. Errors returned from the function don't stop the
+// remaining episodes from being processed, they're collected in the returned
+// summary instead. If the context is canceled, episodes that haven't started
+// yet are marked as canceled and the function returns once the episodes that
+// are in process finish.
+func (c *Catalog) SolveContext(
+	ctx context.Context,
+	sel *Selection,
+	concurrency int,
+	onEpisode func(ctx context.Context, ep *Episode) error,
+) (*Summary, error) {
+	episodes := c.Select(sel)
+	if len(episodes) == 0 {
+		// Having nothing to do isn't an error if the selection is filtered
+		// by status (e.g. no stale assets).
+		if sel.filterStatus != AssetStatusAny {
+			return &Summary{Verdicts: []*Verdict{}}, nil
+		}
+		return nil, fmt.Errorf("no episodes found for selection %q", sel)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	summary := &Summary{Verdicts: make([]*Verdict, len(episodes))}
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				summary.Verdicts[index] = solveEpisode(ctx, episodes[index], onEpisode)
+			}
+		}()
+	}
+
+	for index, ep := range episodes {
+		if ctx.Err() != nil {
+			summary.Verdicts[index] = &Verdict{
+				Episode:  ep,
+				Err:      ctx.Err(),
+				Canceled: true,
+			}
+			continue
+		}
+
+		select {
+		case indices <- index:
+		case <-ctx.Done():
+			summary.Verdicts[index] = &Verdict{
+				Episode:  ep,
+				Err:      ctx.Err(),
+				Canceled: true,
+			}
+		}
+	}
+
+	close(indices)
+	wg.Wait()
+
+	return summary, nil
+}
+
+func solveEpisode(
+	ctx context.Context,
+	ep *Episode,
+	onEpisode func(ctx context.Context, ep *Episode) error,
+) *Verdict {
+	start := time.Now()
+	err := onEpisode(ctx, ep)
+	return &Verdict{
+		Episode:  ep,
+		Err:      err,
+		Duration: time.Since(start),
+		Canceled: err != nil && ctx.Err() != nil,
+	}
+}
+
+// Succeeded returns the verdicts for episodes that were processed without
+// an error.
+func (s *Summary) Succeeded() []*Verdict {
+	return s.filter(func(v *Verdict) bool {
+		return v.Err == nil
+	})
+}
+
+// Failed returns the verdicts for episodes that returned an error.
+func (s *Summary) Failed() []*Verdict {
+	return s.filter(func(v *Verdict) bool {
+		return v.Err != nil && !v.Canceled
+	})
+}
+
+// Canceled returns the verdicts for episodes that were interrupted or never
+// started because the context was canceled.
+func (s *Summary) Canceled() []*Verdict {
+	return s.filter(func(v *Verdict) bool {
+		return v.Canceled
+	})
+}
+
+// Err returns an error describing the failed and canceled episodes or nil if
+// every episode succeeded.
+func (s *Summary) Err() error {
+	failedCount := len(s.Failed())
+	canceledCount := len(s.Canceled())
+	if failedCount == 0 && canceledCount == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d of %d episode(s) failed, %d canceled",
+		failedCount, len(s.Verdicts), canceledCount)
+}
+
+// Log logs each failed episode and the totals using the specified logger.
+func (s *Summary) Log(log logrus.FieldLogger) {
+	for _, v := range s.Failed() {
+		log.WithFields(logrus.Fields{
+			"series":  v.Episode.Series.String(),
+			"season":  v.Episode.SeasonNumber,
+			"episode": v.Episode.EpisodeNumber,
+			"title":   v.Episode.Title,
+			"error":   v.Err,
+		}).Errorln("Episode failed")
+	}
+
+	log.WithFields(logrus.Fields{
+		"succeeded": len(s.Succeeded()),
+		"failed":    len(s.Failed()),
+		"canceled":  len(s.Canceled()),
+	}).Infoln("Finished processing episode(s)")
+}
+
+func (s *Summary) filter(include func(v *Verdict) bool) []*Verdict {
+	verdicts := make([]*Verdict, 0)
+	for _, v := range s.Verdicts {
+		if include(v) {
+			verdicts = append(verdicts, v)
+		}
+	}
+
+	return verdicts
+}
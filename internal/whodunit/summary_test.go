@@ -0,0 +1,156 @@
+package whodunit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSolveContext(t *testing.T) {
+	c, err := ReadCatalog(strings.NewReader(testCatalogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errKnot := errors.New("knot came undone")
+
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	summary, err := c.SolveContext(context.Background(), AllEpisodes(), 2,
+		func(ctx context.Context, ep *Episode) error {
+			mutex.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+
+			if ep.Title == "knot-for-everyone" {
+				return errKnot
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if maxRunning < 1 || maxRunning > 2 {
+		t.Errorf("got %d episode(s) running at the same time, want at most 2", maxRunning)
+	}
+
+	names := make([]string, 0)
+	for _, v := range summary.Verdicts {
+		names = append(names, v.Episode.Name())
+		if v.Duration <= 0 {
+			t.Errorf("got duration %s for %s", v.Duration, v.Episode.Name())
+		}
+	}
+
+	if want := episodeNames(c.Episodes()); !reflect.DeepEqual(names, want) {
+		t.Errorf("got verdicts for %q, want %q", names, want)
+	}
+
+	failed := summary.Failed()
+	if len(failed) != 1 || failed[0].Err != errKnot || failed[0].Episode.Title != "knot-for-everyone" {
+		t.Errorf("got failed verdicts %+v", failed)
+	}
+
+	if len(summary.Succeeded()) != 3 || len(summary.Canceled()) != 0 {
+		t.Errorf("got %d succeeded and %d canceled, want 3 and 0",
+			len(summary.Succeeded()), len(summary.Canceled()))
+	}
+
+	if err := summary.Err(); err == nil || err.Error() != "1 of 4 episode(s) failed, 0 canceled" {
+		t.Errorf("got summary error %v", err)
+	}
+}
+
+func TestSolveContextCanceled(t *testing.T) {
+	c, err := ReadCatalog(strings.NewReader(testCatalogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first episode is interrupted, the rest never start.
+	calls := 0
+	summary, err := c.SolveContext(ctx, AllEpisodes(), 1,
+		func(ctx context.Context, ep *Episode) error {
+			calls++
+			cancel()
+			return ctx.Err()
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Errorf("got %d call(s) after canceling, want 1", calls)
+	}
+
+	if len(summary.Verdicts) != 4 || len(summary.Canceled()) != 4 || len(summary.Failed()) != 0 {
+		t.Errorf("got %d verdict(s) with %d canceled and %d failed, want 4, 4, and 0",
+			len(summary.Verdicts), len(summary.Canceled()), len(summary.Failed()))
+	}
+
+	for _, v := range summary.Canceled() {
+		if v.Err != context.Canceled {
+			t.Errorf("got error %v for %s, want %v", v.Err, v.Episode.Name(), context.Canceled)
+		}
+	}
+
+	if err := summary.Err(); err == nil || err.Error() != "0 of 4 episode(s) failed, 4 canceled" {
+		t.Errorf("got summary error %v", err)
+	}
+}
+
+func TestSolveContextEmpty(t *testing.T) {
+	c, err := ReadCatalog(strings.NewReader(testCatalogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onEpisode := func(ctx context.Context, ep *Episode) error {
+		t.Errorf("got call for %s", ep.Name())
+		return nil
+	}
+
+	sel, err := ParseSelection("s9")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.SolveContext(context.Background(), sel, 1, onEpisode); err == nil ||
+		!strings.Contains(err.Error(), "no episodes found") {
+		t.Errorf("got error %v, want no episodes found", err)
+	}
+
+	// The episode doesn't have a URL, so its video is missing rather than
+	// complete and the status filter leaves nothing to do.
+	sel, err = ParseSelection("forensic-files-ii:s1e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := c.SolveContext(context.Background(),
+		sel.WithStatus(AssetTypeVideo, AssetStatusComplete), 1, onEpisode)
+	if err != nil {
+		t.Fatalf("got error %q with a status filter", err)
+	}
+
+	if len(summary.Verdicts) != 0 || summary.Err() != nil {
+		t.Errorf("got %d verdict(s) and error %v, want none", len(summary.Verdicts), summary.Err())
+	}
+}
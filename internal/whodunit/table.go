@@ -1,6 +1,7 @@
 package whodunit
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -17,10 +18,6 @@ type StatusTable struct {
 
 // NewStatusTable returns a new instance of a status table.
 func NewStatusTable(assetType AssetType, status AssetStatus) *StatusTable {
-	if assetType != AssetTypeRecognition && status == AssetStatusInProcess {
-		panic("You can only specify the in-process filter for recognitions")
-	}
-
 	return &StatusTable{
 		Table:        tablewriter.NewWriter(os.Stdout),
 		assetType:    assetType,
@@ -28,20 +25,13 @@ func NewStatusTable(assetType AssetType, status AssetStatus) *StatusTable {
 	}
 }
 
-// Log is a convenience method for looping through the episodes in all seasons
-// and logging their status in the terminal.
-func (st *StatusTable) Log() {
+// LogCatalog loops through the episodes in the specified catalog and logs
+// their status in the terminal.
+func (st *StatusTable) LogCatalog(c *Catalog) {
 	totalCount := 0
-	for season := 1; season <= SeasonCount; season++ {
-		s := NewSeason(season)
-		if err := s.PopulateEpisodes(); err != nil {
-			panic("Could not get season episodes")
-		}
-
-		for _, ep := range s.AllEpisodes() {
-			if st.AddRow(ep) {
-				totalCount++
-			}
+	for _, ep := range c.Episodes() {
+		if st.AddRow(ep) {
+			totalCount++
 		}
 	}
 
@@ -52,14 +42,26 @@ func (st *StatusTable) Log() {
 func (st *StatusTable) RenderTable(totalCount int) {
 	st.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
 	st.SetAlignment(tablewriter.ALIGN_LEFT)
-	st.SetHeader([]string{"Season", "Episode", "Title", "Status"})
-	st.SetFooter([]string{"", "", "Total", strconv.Itoa(totalCount)})
+	st.SetAutoWrapText(false)
+	st.SetHeader([]string{
+		"Season", "Episode", "Title", st.assetType.DisplayName() + " Status",
+		"Attempts", "Updated", "Last Error",
+	})
+	st.SetFooter([]string{
+		"", "", "Total", strconv.Itoa(totalCount), "", "", "",
+	})
 	st.Render()
 }
 
 // AddRow adds a new row to the table associated with the episode.
 func (st *StatusTable) AddRow(ep *Episode) bool {
-	status := ep.AssetStatus(st.assetType)
+	return st.AddRowWithStatus(ep, ep.AssetStatus(st.assetType))
+}
+
+// AddRowWithStatus adds a new row to the table associated with the episode
+// that shows the specified status instead of the status of the asset (e.g.
+// the status of a job that's still running).
+func (st *StatusTable) AddRowWithStatus(ep *Episode, status AssetStatus) bool {
 	if st.statusFilter != status && st.statusFilter != AssetStatusAny {
 		return false
 	}
@@ -69,47 +71,60 @@ func (st *StatusTable) AddRow(ep *Episode) bool {
 		title = "The Disappearance Of Helle..."
 	}
 
-	statusDisplay := st.statusDisplay(status)
+	statusDisplay := status.DisplayName()
+	attempts, updated, lastError := "", "", ""
+	if entry := ep.LedgerEntry(st.assetType); entry != nil {
+		attempts = strconv.Itoa(entry.Attempts)
+		updated = entry.UpdatedAt.Local().Format("2006-01-02 15:04")
+		lastError = entry.LastError
+		if len(lastError) > 60 {
+			lastError = lastError[:57] + "..."
+		}
+	}
+
 	row := []string{
-		strconv.Itoa(ep.SeasonNumber),
+		seasonDisplay(ep),
 		strconv.Itoa(ep.EpisodeNumber),
 		title,
 		statusDisplay,
+		attempts,
+		updated,
+		lastError,
 	}
 
-	fgStyle := tablewriter.Normal
-	var fgColor int
-
-	if status == AssetStatusComplete {
-		fgColor = tablewriter.FgGreenColor
-	} else if status == AssetStatusPending {
-		fgColor = tablewriter.FgYellowColor
-	} else if status == AssetStatusInProcess {
-		fgColor = tablewriter.FgCyanColor
-	} else {
-		fgStyle = tablewriter.Bold
-		fgColor = tablewriter.FgRedColor
+	colors := make([]tablewriter.Colors, len(row))
+	for i := range colors {
+		colors[i] = statusColors(status)
 	}
 
-	st.Rich(row, []tablewriter.Colors{
-		{fgStyle, fgColor},
-		{fgStyle, fgColor},
-		{fgStyle, fgColor},
-		{fgStyle, fgColor},
-	})
+	st.Rich(row, colors)
 	return true
 }
 
-func (st *StatusTable) statusDisplay(status AssetStatus) string {
+// seasonDisplay returns the season number of the episode shown in a table,
+// which is prefixed with the series for series other than the default.
+func seasonDisplay(ep *Episode) string {
+	season := strconv.Itoa(ep.SeasonNumber)
+	if ep.Series.IsDefault() {
+		return season
+	}
+
+	return fmt.Sprintf("%s:%s", ep.Series, season)
+}
+
+// statusColors returns the colors used to show the specified status in a
+// table in the terminal.
+func statusColors(status AssetStatus) tablewriter.Colors {
 	switch status {
+	case AssetStatusComplete:
+		return tablewriter.Colors{tablewriter.Normal, tablewriter.FgGreenColor}
 	case AssetStatusPending:
-		return "Pending"
+		return tablewriter.Colors{tablewriter.Normal, tablewriter.FgYellowColor}
 	case AssetStatusInProcess:
-		return "In Process"
-	case AssetStatusMissing:
-		return "Missing"
-	case AssetStatusComplete:
-		return "Complete"
+		return tablewriter.Colors{tablewriter.Normal, tablewriter.FgCyanColor}
+	case AssetStatusStale:
+		return tablewriter.Colors{tablewriter.Normal, tablewriter.FgMagentaColor}
 	}
-	return "Unknown"
+
+	return tablewriter.Colors{tablewriter.Bold, tablewriter.FgRedColor}
 }
@@ -0,0 +1,325 @@
+package whodunit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MinTitleScore is the lowest score a title can have to be considered a match
+// for a search. Scores range from 0 (nothing in common) to 1 (exact match).
+const MinTitleScore = 0.6
+
+// TitleMargin is the amount the best match needs to score higher than the
+// next best match to be picked without asking which episode was meant.
+const TitleMargin = 0.1
+
+// ErrNoTitleMatch is returned when no episode title matches a search.
+var ErrNoTitleMatch = errors.New("no episode title matches")
+
+// TitleMatch is an episode that matched a title search along with how well it
+// matched.
+type TitleMatch struct {
+	Episode *Episode
+	Score   float64
+}
+
+// AmbiguousTitleError is returned when more than one episode matches a title
+// search equally well.
+type AmbiguousTitleError struct {
+	Query   string
+	Matches []*TitleMatch
+}
+
+func (e *AmbiguousTitleError) Error() string {
+	names := make([]string, 0, len(e.Matches))
+	for _, match := range e.Matches {
+		names = append(names, match.Episode.Name())
+	}
+
+	return fmt.Sprintf("title %q matches %d episodes: %s",
+		e.Query, len(e.Matches), strings.Join(names, ", "))
+}
+
+// DisplayTitles contains the original titles of the episodes as they were
+// shown on YouTube (e.g. "The Disappearance of Helle Crafts") keyed by episode
+// name. The titles in the catalog have the punctuation stripped out, so these
+// are searched too.
+type DisplayTitles map[string]string
+
+// youTubeLink is a single video in the YouTube links JSON file.
+type youTubeLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// youTubeLinkNameRegexp matches the name of a video in the YouTube links JSON
+// file, which is in the form "Season 1 | Episode 2 | The Magic Bullet".
+var youTubeLinkNameRegexp = regexp.MustCompile(
+	`^Season (\d+) \| Episode (\d+) \| (.+)$`)
+
+// LoadDisplayTitles returns the display titles of the episodes in the
+// specified catalog from the YouTube links JSON file at the specified path.
+// Videos are matched to episodes by season and episode number or by URL if
+// the name can't be parsed. If the file doesn't exist, there are no display
+// titles.
+func LoadDisplayTitles(c *Catalog, path string) (DisplayTitles, error) {
+	titles := make(DisplayTitles)
+
+	jsonFile, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return titles, nil
+		}
+		return nil, err
+	}
+	defer jsonFile.Close()
+
+	var result map[string][]*youTubeLink
+	if err := json.NewDecoder(jsonFile).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	episodesByURL := make(map[string]*Episode)
+	for _, ep := range c.Episodes() {
+		if ep.URL != "" {
+			episodesByURL[ep.URL] = ep
+		}
+	}
+
+	for _, links := range result {
+		for _, link := range links {
+			ep, title := episodesByURL[link.URL], link.Name
+			if matches := youTubeLinkNameRegexp.FindStringSubmatch(link.Name); matches != nil {
+				seasonNumber, _ := strconv.Atoi(matches[1])
+				episodeNumber, _ := strconv.Atoi(matches[2])
+				if found := c.Episode(seasonNumber, episodeNumber); found != nil {
+					ep = found
+				}
+				title = matches[3]
+			}
+
+			if ep != nil {
+				titles[ep.Name()] = strings.TrimSpace(title)
+			}
+		}
+	}
+
+	return titles, nil
+}
+
+// SearchTitle returns the episodes with a title (or display title) that
+// matches the specified query sorted from the best match to the worst. The
+// search ignores case and punctuation and tolerates typos, so "knot for
+// everone" still finds "knot-for-everyone". Episodes that score lower than
+// MinTitleScore aren't included.
+func (c *Catalog) SearchTitle(query string, displayTitles DisplayTitles) []*TitleMatch {
+	queryTokens := titleTokens(query)
+	matches := make([]*TitleMatch, 0)
+	if len(queryTokens) == 0 {
+		return matches
+	}
+
+	for _, ep := range c.episodes {
+		score := titleScore(queryTokens, ep.Title)
+		if displayTitle, ok := displayTitles[ep.Name()]; ok {
+			score = maxFloat(score, titleScore(queryTokens, displayTitle))
+		}
+
+		if score >= MinTitleScore {
+			matches = append(matches, &TitleMatch{Episode: ep, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	return matches
+}
+
+// LookupTitle returns the episode with the title that best matches the
+// specified query. It returns ErrNoTitleMatch if no episodes match and an
+// AmbiguousTitleError with the closest matches if the best match doesn't
+// score at least TitleMargin higher than the others.
+func (c *Catalog) LookupTitle(query string, displayTitles DisplayTitles) (*Episode, error) {
+	return BestTitleMatch(query, c.SearchTitle(query, displayTitles))
+}
+
+// BestTitleMatch returns the episode of the best match from the specified
+// results of a title search for the specified query. This is useful if the
+// results were filtered after searching (e.g. to a single series). The
+// errors are the same as LookupTitle.
+func BestTitleMatch(query string, matches []*TitleMatch) (*Episode, error) {
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrNoTitleMatch, query)
+	}
+
+	best := matches[0]
+	closest := []*TitleMatch{best}
+	for _, match := range matches[1:] {
+		if best.Score-match.Score < TitleMargin {
+			closest = append(closest, match)
+		}
+	}
+
+	if len(closest) > 1 {
+		return nil, &AmbiguousTitleError{Query: query, Matches: closest}
+	}
+
+	return best.Episode, nil
+}
+
+// NormalizeTitle returns the specified title in the form used in the catalog,
+// which is lowercase with the punctuation stripped out and the words separated
+// by hyphens (e.g. "Knot for Everyone" returns "knot-for-everyone").
+func NormalizeTitle(title string) string {
+	return strings.Join(titleTokens(title), "-")
+}
+
+// titleStopWords are words that are ignored when comparing titles, unless the
+// query is made up of nothing else.
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "in": true, "of": true, "the": true,
+}
+
+// titleTokens returns the lowercase words in the specified title with the
+// punctuation stripped out. Hyphens separate words, so the catalog titles
+// are split up the same way as the display titles.
+func titleTokens(title string) []string {
+	title = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case r == '\'' || r == '’':
+			return -1
+		default:
+			return ' '
+		}
+	}, title)
+
+	return strings.Fields(title)
+}
+
+// titleScore returns how well the query tokens match the specified title. The
+// score is mostly based on how closely each word in the query matches a word
+// in the title, with the rest based on how much of the title the query
+// covers, so "knot for everyone" scores higher than "knot" for the same
+// episode. A query that spells out the whole title scores 1.
+func titleScore(queryTokens []string, title string) float64 {
+	candidateTokens := titleTokens(title)
+	if len(candidateTokens) == 0 {
+		return 0
+	}
+
+	if strings.Join(queryTokens, " ") == strings.Join(candidateTokens, " ") {
+		return 1
+	}
+
+	queryWords := withoutStopWords(queryTokens)
+	candidateWords := withoutStopWords(candidateTokens)
+
+	total := 0.0
+	matched := make(map[int]bool)
+	for _, queryWord := range queryWords {
+		best, bestIndex := 0.0, -1
+		for i, candidateWord := range candidateWords {
+			if similarity := wordSimilarity(queryWord, candidateWord); similarity > best {
+				best, bestIndex = similarity, i
+			}
+		}
+
+		total += best
+		if best >= 0.8 {
+			matched[bestIndex] = true
+		}
+	}
+
+	wordScore := total / float64(len(queryWords))
+	coverage := float64(len(matched)) / float64(len(candidateWords))
+
+	// Scores can't reach 1 unless the query is the exact title.
+	return 0.99 * (0.8*wordScore + 0.2*coverage)
+}
+
+// withoutStopWords returns the tokens that aren't stop words or all of the
+// tokens if every one of them is a stop word.
+func withoutStopWords(tokens []string) []string {
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !titleStopWords[token] {
+			words = append(words, token)
+		}
+	}
+
+	if len(words) == 0 {
+		return tokens
+	}
+
+	return words
+}
+
+// wordSimilarity returns how similar the specified words are from 0 to 1 based
+// on the edit distance between them. A query word that is the start of a
+// title word (e.g. "hel" for "helle") is treated as a close match.
+func wordSimilarity(queryWord string, word string) float64 {
+	if queryWord == word {
+		return 1
+	}
+
+	if len(queryWord) >= 3 && strings.HasPrefix(word, queryWord) {
+		return 0.9
+	}
+
+	a, b := []rune(queryWord), []rune(word)
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance between the specified words.
+func editDistance(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
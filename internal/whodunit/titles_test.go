@@ -0,0 +1,112 @@
+package whodunit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSearchTitle(t *testing.T) {
+	c, err := ReadCatalog(strings.NewReader(testCatalogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	displayTitles := DisplayTitles{
+		"01-01-the-disappearance-of-helle-crafts": "The Disappearance of Helle Crafts",
+		"03-02-knot-for-everyone":                 "Knot for Everyone",
+	}
+
+	tests := []struct {
+		query    string
+		want     string
+		minScore float64
+		maxScore float64
+	}{
+		{"knot-for-everyone", "03-02-knot-for-everyone", 1, 1},
+		{"Knot For Everyone!", "03-02-knot-for-everyone", 1, 1},
+		{"knot for everone", "03-02-knot-for-everyone", MinTitleScore, 0.99},
+		{"knot", "03-02-knot-for-everyone", MinTitleScore, 0.99},
+		{"magic bullet", "01-02-the-magic-bullet", MinTitleScore, 0.99},
+		{"helle crafts", "01-01-the-disappearance-of-helle-crafts", MinTitleScore, 0.99},
+		{"cold case", "forensic-files-ii-01-01-the-cold-case", MinTitleScore, 0.99},
+	}
+
+	for _, test := range tests {
+		matches := c.SearchTitle(test.query, displayTitles)
+		if len(matches) == 0 {
+			t.Errorf("SearchTitle(%q) found nothing, want %s", test.query, test.want)
+			continue
+		}
+
+		best := matches[0]
+		if best.Episode.Name() != test.want {
+			t.Errorf("SearchTitle(%q) best match is %s, want %s",
+				test.query, best.Episode.Name(), test.want)
+		}
+
+		if best.Score < test.minScore || best.Score > test.maxScore {
+			t.Errorf("SearchTitle(%q) scored %.2f, want %.2f to %.2f",
+				test.query, best.Score, test.minScore, test.maxScore)
+		}
+
+		for i := 1; i < len(matches); i++ {
+			if matches[i].Score > matches[i-1].Score {
+				t.Errorf("SearchTitle(%q) matches aren't sorted by score", test.query)
+			}
+			if matches[i].Score < MinTitleScore {
+				t.Errorf("SearchTitle(%q) included %s with score %.2f",
+					test.query, matches[i].Episode.Name(), matches[i].Score)
+			}
+		}
+	}
+
+	for _, query := range []string{"", "!!!", "xylophone"} {
+		if matches := c.SearchTitle(query, displayTitles); len(matches) != 0 {
+			t.Errorf("SearchTitle(%q) found %d matches, want none", query, len(matches))
+		}
+	}
+}
+
+func TestLookupTitle(t *testing.T) {
+	c, err := NewCatalog([]*Episode{
+		{SeasonNumber: 1, EpisodeNumber: 1, Title: "the-list"},
+		{SeasonNumber: 2, EpisodeNumber: 1, Title: "the-list"},
+		{SeasonNumber: 3, EpisodeNumber: 2, Title: "knot-for-everyone"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ep, err := c.LookupTitle("knot for everyone", nil)
+	if err != nil || ep.Name() != "03-02-knot-for-everyone" {
+		t.Errorf("got %v, %v", ep, err)
+	}
+
+	_, err = c.LookupTitle("the list", nil)
+	var ambiguous *AmbiguousTitleError
+	if !errors.As(err, &ambiguous) || len(ambiguous.Matches) != 2 {
+		t.Errorf("got error %v, want ambiguous with 2 matches", err)
+	}
+
+	if _, err := c.LookupTitle("xylophone", nil); !errors.Is(err, ErrNoTitleMatch) {
+		t.Errorf("got error %v, want ErrNoTitleMatch", err)
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"Knot for Everyone":   "knot-for-everyone",
+		"  Bitter   Pills! ":  "bitter-pills",
+		"Nature's Course":     "natures-course",
+		"x/../y":              "x-y",
+		"knot-for-everyone":   "knot-for-everyone",
+		"4 On the Floor (II)": "4-on-the-floor-ii",
+	}
+
+	for title, want := range tests {
+		if got := NormalizeTitle(title); got != want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
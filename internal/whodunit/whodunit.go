@@ -4,14 +4,7 @@
 // and metadata for the associated assets.
 package whodunit
 
-import (
-	"errors"
-	"log"
-	"os"
-	"path/filepath"
-
-	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
-)
+import "fmt"
 
 // AssetStatus is an enum that represents the status of the asset.
 type AssetStatus int
@@ -33,9 +26,29 @@ const (
 
 	// AssetStatusMissing indicates that the asset is missing.
 	AssetStatusMissing
+
+	// AssetStatusFailed indicates that the last attempt to process the asset
+	// failed.
+	AssetStatusFailed
+
+	// AssetStatusStale indicates that the asset exists, but one of the assets
+	// it was produced from changed since, so it needs to be rebuilt.
+	AssetStatusStale
 )
 
+var assetStatusKeys = map[AssetStatus]string{
+	AssetStatusAny:       "any",
+	AssetStatusPending:   "pending",
+	AssetStatusInProcess: "in-process",
+	AssetStatusComplete:  "complete",
+	AssetStatusMissing:   "missing",
+	AssetStatusFailed:    "failed",
+	AssetStatusStale:     "stale",
+}
+
 // AssetType represents which type of asset the episode is associated with.
+// The built-in asset types are declared below, other packages can add more
+// with RegisterAssetType.
 type AssetType int
 
 const (
@@ -59,97 +72,45 @@ const (
 	AssetTypeVideo
 )
 
-// AssetsDirPath is the absolute path to the `/assets` directory.
-var AssetsDirPath = assetsDirPath()
-
-var env = crimeseen.NewEnv()
-
-// DirPath returns the absolute path to the directory associated with the
-// asset type.
-func (at AssetType) DirPath() string {
-	invPath := env.InvestigationsPath()
-	switch at {
-	case AssetTypeGCPAnalysis:
-		return filepath.Join(invPath, "gcp-analyses")
-	case AssetTypeIBMAnalysis:
-		return filepath.Join(invPath, "ibm-analyses")
-	case AssetTypeAudio:
-		return filepath.Join(invPath, "audio")
-	case AssetTypeRecognition:
-		return filepath.Join(invPath, "recognitions")
-	case AssetTypeTranscript:
-		return filepath.Join(invPath, "transcripts")
-	case AssetTypeVideo:
-		return filepath.Join(invPath, "videos")
-	default:
-		return ""
-	}
+// String returns the key associated with the asset status (e.g. "in-process").
+func (as AssetStatus) String() string {
+	return assetStatusKeys[as]
 }
 
-// FileExt returns the file extension associated with the asset type.
-func (at AssetType) FileExt() string {
-	switch at {
-	case AssetTypeAudio:
-		return ".mp3"
-	case AssetTypeTranscript:
-		return ".txt"
-	case AssetTypeVideo:
-		return ".mp4"
-	default:
-		return ".json"
+// DisplayName returns the name of the asset status shown in the terminal (e.g.
+// "In Process").
+func (as AssetStatus) DisplayName() string {
+	switch as {
+	case AssetStatusPending:
+		return "Pending"
+	case AssetStatusInProcess:
+		return "In Process"
+	case AssetStatusMissing:
+		return "Missing"
+	case AssetStatusComplete:
+		return "Complete"
+	case AssetStatusFailed:
+		return "Failed"
+	case AssetStatusStale:
+		return "Stale"
 	}
+	return "Unknown"
 }
 
-// Solve runs the specified function per episode based on the values passed
-// in for the season and episode.
-func Solve(
-	seasonNumber int,
-	episodeNumber int,
-	onEpisode func(ep *Episode),
-) error {
-	// Performs the specified action for all of the episodes in a season.
-	onSeason := func(s *Season) error {
-		if err := s.PopulateEpisodes(); err != nil {
-			return err
-		}
-
-		if episodeNumber != 0 {
-			onEpisode(s.Episode(episodeNumber))
-		} else {
-			for _, ep := range s.AllEpisodes() {
-				onEpisode(ep)
-			}
-		}
-
-		return nil
-	}
-
-	// If the season specified is 0 (not specified), the onEpisode action needs
-	// to run on every episode in every season.
-	if seasonNumber == 0 {
-		// How do we know which season to process if it isn't specified?
-		if episodeNumber != 0 {
-			return errors.New("you must specify a season number for an episode")
-		}
-
-		for season := 1; season <= SeasonCount; season++ {
-			s := NewSeason(season)
-			if err := onSeason(s); err != nil {
-				return err
-			}
-		}
-		return nil
-	}
-
-	s := NewSeason(seasonNumber)
-	return onSeason(s)
+// MarshalText implements the encoding.TextMarshaler interface, so the status
+// is stored as a readable key in JSON files.
+func (as AssetStatus) MarshalText() ([]byte, error) {
+	return []byte(as.String()), nil
 }
 
-func assetsDirPath() string {
-	pwd, err := os.Getwd()
-	if err != nil {
-		log.Fatal("Error getting pwd")
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (as *AssetStatus) UnmarshalText(text []byte) error {
+	for status, key := range assetStatusKeys {
+		if key == string(text) {
+			*as = status
+			return nil
+		}
 	}
 
-	return filepath.Join(pwd, "assets")
+	return fmt.Errorf("unknown asset status %q", text)
 }
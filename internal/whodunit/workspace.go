@@ -0,0 +1,201 @@
+package whodunit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mikerourke/forensic-files-api/internal/coldstorage"
+	"github.com/mikerourke/forensic-files-api/internal/crimeseen"
+)
+
+// ErrNoWorkspace is returned when the assets of an episode or season are used
+// before its catalog is attached to a workspace, since none of its assets can
+// be found without one.
+var ErrNoWorkspace = errors.New("catalog doesn't belong to a workspace")
+
+// Workspace contains the paths and settings used to work with the assets of a
+// single checkout of the repo: the `/assets` directory with the catalog, the
+// investigations directory with the asset files, and the config. Everything
+// that reads or writes assets gets the workspace passed in (or gets it from
+// the catalog an episode belongs to), so several workspaces can be used side
+// by side in the same process.
+type Workspace struct {
+	assetsPath string
+	config     *crimeseen.Config
+
+	storage      coldstorage.Storage
+	storageMutex sync.Mutex
+
+	catalog     *Catalog
+	catalogErr  error
+	catalogOnce sync.Once
+
+	ledger     *Ledger
+	ledgerErr  error
+	ledgerOnce sync.Once
+}
+
+// NewWorkspace returns a workspace that uses the `/assets` directory in the
+// specified root directory (the root of the repo) and the specified config.
+// Nothing is loaded until it's needed, so the config is only validated when
+// the asset storage is first used.
+func NewWorkspace(root string, config *crimeseen.Config) (*Workspace, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving workspace %s: %w", root, err)
+	}
+
+	return &Workspace{
+		assetsPath: filepath.Join(root, "assets"),
+		config:     config,
+	}, nil
+}
+
+// AssetsPath returns the absolute path to the `/assets` directory.
+func (ws *Workspace) AssetsPath() string {
+	return ws.assetsPath
+}
+
+// InvestigationsPath returns the path to the investigations directory, which
+// contains the ledger and manifests along with the local asset files.
+func (ws *Workspace) InvestigationsPath() string {
+	return ws.config.InvestigationsPath
+}
+
+// Config returns the config of the workspace.
+func (ws *Workspace) Config() *crimeseen.Config {
+	return ws.config
+}
+
+// CatalogPath returns the absolute path to the episodes JSON file in the
+// `/assets` directory.
+func (ws *Workspace) CatalogPath() string {
+	return filepath.Join(ws.assetsPath, "episodes.json")
+}
+
+// DisplayTitlesPath returns the absolute path to the YouTube links JSON file in
+// the `/assets` directory, which contains the original display titles.
+func (ws *Workspace) DisplayTitlesPath() string {
+	return filepath.Join(ws.assetsPath, "youtube-links.json")
+}
+
+// LedgerPath returns the path to the ledger file in the investigations
+// directory.
+func (ws *Workspace) LedgerPath() string {
+	return filepath.Join(ws.InvestigationsPath(), "ledger.json")
+}
+
+// ManifestsDirPath returns the path to the directory that contains the season
+// manifests.
+func (ws *Workspace) ManifestsDirPath() string {
+	return filepath.Join(ws.InvestigationsPath(), "manifests")
+}
+
+// LocksDirPath returns the path to the directory in the investigations
+// directory that contains the asset lock files. The lock files are kept out of
+// the asset storage, so they work with every storage backend.
+func (ws *Workspace) LocksDirPath() string {
+	return filepath.Join(ws.InvestigationsPath(), ".locks")
+}
+
+// Catalog returns the catalog loaded from the episodes JSON file in the
+// `/assets` directory. The file is only read the first time this is called.
+func (ws *Workspace) Catalog() (*Catalog, error) {
+	ws.catalogOnce.Do(func() {
+		ws.catalog, ws.catalogErr = LoadCatalog(ws.CatalogPath())
+		if ws.catalogErr == nil {
+			ws.catalog.SetWorkspace(ws)
+		}
+	})
+
+	return ws.catalog, ws.catalogErr
+}
+
+// SaveCatalog writes the catalog back to the episodes JSON file.
+func (ws *Workspace) SaveCatalog() error {
+	c, err := ws.Catalog()
+	if err != nil {
+		return err
+	}
+
+	return c.Save(ws.CatalogPath())
+}
+
+// SolveContext runs the specified function for every episode in the catalog of
+// the workspace that is in the specified selection. See Catalog.SolveContext
+// for more details.
+func (ws *Workspace) SolveContext(
+	ctx context.Context,
+	sel *Selection,
+	concurrency int,
+	onEpisode func(ctx context.Context, ep *Episode) error,
+) (*Summary, error) {
+	c, err := ws.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.SolveContext(ctx, sel, concurrency, onEpisode)
+}
+
+// Ledger returns the ledger stored in the `ledger.json` file in the
+// investigations directory.
+func (ws *Workspace) Ledger() (*Ledger, error) {
+	ws.ledgerOnce.Do(func() {
+		ws.ledger, ws.ledgerErr = OpenLedger(ws.LedgerPath())
+	})
+
+	return ws.ledger, ws.ledgerErr
+}
+
+// Storage returns the storage backend that contains the asset files, which is
+// configured with the `assetStorage` setting. It returns an error if the
+// storage settings are invalid.
+func (ws *Workspace) Storage() (coldstorage.Storage, error) {
+	ws.storageMutex.Lock()
+	defer ws.storageMutex.Unlock()
+
+	if ws.storage == nil {
+		s, err := ws.newStorage()
+		if err != nil {
+			return nil, fmt.Errorf("error configuring asset storage: %w", err)
+		}
+		ws.storage = s
+	}
+
+	return ws.storage, nil
+}
+
+// SetStorage overrides the storage backend returned from Storage.
+func (ws *Workspace) SetStorage(s coldstorage.Storage) {
+	ws.storageMutex.Lock()
+	defer ws.storageMutex.Unlock()
+
+	ws.storage = s
+}
+
+func (ws *Workspace) newStorage() (coldstorage.Storage, error) {
+	config := ws.config
+	switch strings.ToLower(config.AssetStorage) {
+	case "", "local":
+		return coldstorage.NewLocal(config.InvestigationsPath), nil
+
+	case "s3":
+		return coldstorage.NewS3(coldstorage.S3Config{
+			Endpoint:        config.S3.Endpoint,
+			Region:          config.S3.Region,
+			Bucket:          config.S3.Bucket,
+			Prefix:          config.S3.Prefix,
+			AccessKeyID:     config.S3.AccessKeyID,
+			SecretAccessKey: config.S3.SecretAccessKey,
+			Timeout:         config.S3.Timeout,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown asset storage %q", config.AssetStorage)
+	}
+}